@@ -0,0 +1,45 @@
+// Package openapi serves the OpenAPI 3 document of the REST API and a Swagger
+// UI page rendering it.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Spec is openapi.json, keep it in sync with the routes of cmd/main.go.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// swaggerUIVersion pins the swagger-ui-dist assets loaded by the UI page.
+const swaggerUIVersion = "5.17.14"
+
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Wallets API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// SpecHandler serves Spec.
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(Spec)
+}
+
+// UIHandler serves the Swagger UI page for SpecHandler at /openapi.json.
+func UIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(uiPage))
+}
@@ -0,0 +1,125 @@
+package openapi
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+// mainFile registers the routes of the service.
+const mainFile = "../../cmd/main.go"
+
+type OpenAPITestSuite struct {
+	suite.Suite
+	doc *openapi3.T
+}
+
+func TestOpenAPISuite(t *testing.T) {
+	suite.RunSuite(t, new(OpenAPITestSuite))
+}
+
+func (s *OpenAPITestSuite) BeforeEach(t provider.T) {
+	doc, err := openapi3.NewLoader().LoadFromData(Spec)
+	t.Require().NoError(err)
+
+	s.doc = doc
+}
+
+// registeredRoutes returns the patterns passed to Handle and HandleFunc in
+// mainFile, such as "GET /api/v1/wallets".
+func registeredRoutes(t provider.T) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), mainFile, nil, 0)
+	t.Require().NoError(err)
+
+	var routes []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || (sel.Sel.Name != "Handle" && sel.Sel.Name != "HandleFunc") {
+			return true
+		}
+
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		pattern, err := strconv.Unquote(lit.Value)
+		t.Require().NoError(err)
+		routes = append(routes, pattern)
+
+		return true
+	})
+
+	return routes
+}
+
+func (s *OpenAPITestSuite) TestValid(t provider.T) {
+	t.Require().NoError(s.doc.Validate(context.Background()))
+}
+
+func (s *OpenAPITestSuite) TestEveryRouteDocumented(t provider.T) {
+	routes := registeredRoutes(t)
+	t.Require().NotEmpty(routes)
+
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		method, path, ok := strings.Cut(route, " ")
+		t.Require().True(ok, "route %q has no method", route)
+		registered[route] = true
+
+		var op *openapi3.Operation
+		if item := s.doc.Paths.Find(path); item != nil {
+			op = item.GetOperation(method)
+		}
+		t.Assert().NotNil(op, "route %q is not documented", route)
+	}
+
+	for path, item := range s.doc.Paths.Map() {
+		for method := range item.Operations() {
+			t.Assert().True(registered[method+" "+path], "%s %s is documented but not routed", method, path)
+		}
+	}
+}
+
+func (s *OpenAPITestSuite) TestPathParametersDeclared(t provider.T) {
+	for path, item := range s.doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			for _, segment := range strings.Split(path, "/") {
+				name, ok := strings.CutPrefix(segment, "{")
+				if !ok {
+					continue
+				}
+				name = strings.TrimSuffix(name, "}")
+
+				p := op.Parameters.GetByInAndName(openapi3.ParameterInPath, name)
+				t.Assert().NotNil(p, "%s %s doesn't declare %s", method, path, name)
+			}
+		}
+	}
+}
+
+func (s *OpenAPITestSuite) TestProtectedRoutesDeclareSession(t provider.T) {
+	for path, item := range s.doc.Paths.Map() {
+		for method, op := range item.Operations() {
+			if op.Responses.Value(strconv.Itoa(http.StatusUnauthorized)) == nil {
+				continue
+			}
+
+			t.Assert().NotNil(op.Security, "%s %s answers 401 without a security requirement", method, path)
+		}
+	}
+}
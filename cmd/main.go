@@ -1,48 +1,312 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"github.com/Davmie/javaCode/api/openapi"
 	"github.com/Davmie/javaCode/cmd/server"
+	holdDel "github.com/Davmie/javaCode/internal/hold/delivery"
+	holdRep "github.com/Davmie/javaCode/internal/hold/repository"
+	holdCache "github.com/Davmie/javaCode/internal/hold/repository/cache"
+	memHold "github.com/Davmie/javaCode/internal/hold/repository/memory"
+	pgHold "github.com/Davmie/javaCode/internal/hold/repository/postgres"
+	holdUseCase "github.com/Davmie/javaCode/internal/hold/usecase"
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox"
+	outboxRep "github.com/Davmie/javaCode/internal/outbox/repository"
+	memOutbox "github.com/Davmie/javaCode/internal/outbox/repository/memory"
+	pgOutbox "github.com/Davmie/javaCode/internal/outbox/repository/postgres"
+	outboxUseCase "github.com/Davmie/javaCode/internal/outbox/usecase"
+	"github.com/Davmie/javaCode/internal/stream"
+	streamDel "github.com/Davmie/javaCode/internal/stream/delivery"
+	transactionDel "github.com/Davmie/javaCode/internal/transaction/delivery"
+	transactionRep "github.com/Davmie/javaCode/internal/transaction/repository"
+	transactionCache "github.com/Davmie/javaCode/internal/transaction/repository/cache"
+	memTransaction "github.com/Davmie/javaCode/internal/transaction/repository/memory"
+	pgTransaction "github.com/Davmie/javaCode/internal/transaction/repository/postgres"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
 	walletDel "github.com/Davmie/javaCode/internal/wallet/delivery"
+	walletRpc "github.com/Davmie/javaCode/internal/wallet/delivery/rpc"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	walletCache "github.com/Davmie/javaCode/internal/wallet/repository/cache"
+	memWallet "github.com/Davmie/javaCode/internal/wallet/repository/memory"
 	pgWallet "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
 	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	webhookDel "github.com/Davmie/javaCode/internal/webhook/delivery"
+	webhookRep "github.com/Davmie/javaCode/internal/webhook/repository"
+	memWebhook "github.com/Davmie/javaCode/internal/webhook/repository/memory"
+	pgWebhook "github.com/Davmie/javaCode/internal/webhook/repository/postgres"
+	webhookUseCase "github.com/Davmie/javaCode/internal/webhook/usecase"
+	"github.com/Davmie/javaCode/pkg/cache"
+	"github.com/Davmie/javaCode/pkg/config"
+	ctxManager "github.com/Davmie/javaCode/pkg/context"
+	"github.com/Davmie/javaCode/pkg/database"
 	"github.com/Davmie/javaCode/pkg/middleware"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/Davmie/javaCode/pkg/walletpb"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"google.golang.org/grpc"
 )
 
-var prodCfgPg = postgres.Config{DSN: "host=postgres user=program password=test dbname=wallets port=5432"}
+const holdSweepInterval = 30 * time.Second
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flag.StringVar(&cfg.Storage, "storage", cfg.Storage, "where wallets are kept: postgres, sqlite or memory")
+	flag.Parse()
+
 	zapLogger := zap.Must(zap.NewDevelopment())
 	logger := zapLogger.Sugar()
 
-	db, err := gorm.Open(postgres.New(prodCfgPg), &gorm.Config{})
+	var (
+		walletRepo      walletRep.WalletRepositoryI
+		replicaRepo     walletRep.WalletRepositoryI
+		transactionRepo transactionRep.TransactionRepositoryI
+		holdRepo        holdRep.HoldRepositoryI
+		outboxRepo      outboxRep.OutboxRepositoryI
+		webhookRepo     webhookRep.WebhookRepositoryI
+	)
+
+	if cfg.Storage == config.StorageMemory {
+		// Nothing survives a restart, meant for local development only.
+		store := memstore.New()
+
+		walletRepo = memWallet.New(logger, store)
+		transactionRepo = memTransaction.New(logger, store)
+		holdRepo = memHold.New(logger, store)
+		outboxRepo = memOutbox.New(logger, store)
+		webhookRepo = memWebhook.New(logger, store)
+	} else {
+		// The gorm repositories work on top of both Postgres and SQLite.
+		db, err := database.Open(cfg, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		walletRepo = pgWallet.New(logger, db)
+		if cfg.BatchChanges {
+			walletRepo = pgWallet.NewBatched(logger, db, cfg.BatchSize)
+		}
+		transactionRepo = pgTransaction.New(logger, db)
+		holdRepo = pgHold.New(logger, db)
+		outboxRepo = pgOutbox.New(logger, db)
+		webhookRepo = pgWebhook.New(logger, db)
+
+		if cfg.Storage == config.StoragePostgres && len(cfg.ReplicaDSNs) > 0 {
+			replicas, err := database.OpenReplicas(context.Background(), logger, cfg, db)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			// Replica reads lag behind anyway, they are not cached.
+			replicaRepo = pgWallet.New(logger, replicas)
+		}
+	}
+
+	hub := stream.NewHub()
+	changed, flushed := hub.Notify, hub.NotifyAll
+
+	cacheBackend, err := cache.Open(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if cacheBackend != nil {
+		wallets := walletCache.NewWalletCache(logger, cacheBackend)
+
+		walletRepo = walletCache.New(logger, walletRepo, wallets)
+		transactionRepo = transactionCache.New(logger, transactionRepo, wallets)
+		holdRepo = holdCache.New(logger, holdRepo, wallets)
+
+		// A shared Redis cache is evicted by the writer itself, only local caches
+		// need to learn about the changes made by other instances. The cache is
+		// evicted before the streams read the wallets again.
+		if cfg.Cache == config.CacheLRU {
+			changed = func(uids ...string) {
+				wallets.Invalidate(uids...)
+				hub.Notify(uids...)
+			}
+			flushed = func() {
+				wallets.Flush()
+				hub.NotifyAll()
+			}
+		}
+	}
+
+	if cfg.Storage == config.StoragePostgres {
+		go pgWallet.ListenChanges(context.Background(), cfg.PostgresDSN, logger, changed, flushed)
+	}
 
 	walletHandler := walletDel.WalletHandler{
-		WalletUseCase: walletUseCase.New(pgWallet.New(logger, db)),
-		Logger:        logger,
+		WalletUseCase:  walletUseCase.New(walletRepo),
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+	if replicaRepo != nil {
+		walletHandler.ReplicaUseCase = walletUseCase.New(replicaRepo)
+	}
+
+	authManager := middleware.AuthManager{
+		SessionManager: session.JWTSessionsManager{},
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+
+	transactionUC := transactionUseCase.New(transactionRepo)
+	transactionHandler := transactionDel.TransactionHandler{
+		TransactionUseCase: transactionUC,
+		Logger:             logger,
+	}
+
+	streamHandler := streamDel.StreamHandler{
+		WalletUseCase:      walletHandler.WalletUseCase,
+		TransactionUseCase: transactionUC,
+		Hub:                hub,
+		Logger:             logger,
+		ContextManager:     ctxManager.Manager{},
+		Heartbeat:          cfg.StreamHeartbeat,
+	}
+
+	holdUC := holdUseCase.New(holdRepo)
+	holdHandler := holdDel.HoldHandler{
+		HoldUseCase: holdUC,
+		Logger:      logger,
+	}
+
+	go func() {
+		for range time.Tick(holdSweepInterval) {
+			expired, err := holdUC.ExpireHolds()
+			if err != nil {
+				logger.Errorw("can`t expire holds", "err:", err.Error())
+				continue
+			}
+			if expired > 0 {
+				logger.Infow("holds expired", "count", expired)
+			}
+		}
+	}()
+
+	publisher, err := outbox.Open(context.Background(), cfg, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer publisher.Close()
+
+	webhookUC := webhookUseCase.New(webhookRepo, webhookUseCase.NewClient(cfg.WebhookTimeout), cfg.WebhookMaxAttempts, cfg.WebhookBackoff)
+	webhookHandler := webhookDel.WebhookHandler{
+		WebhookUseCase: webhookUC,
+		WalletUseCase:  walletHandler.WalletUseCase,
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+
+	go func() {
+		for range time.Tick(cfg.WebhookInterval) {
+			if _, err := webhookUC.Dispatch(context.Background()); err != nil {
+				logger.Errorw("can`t dispatch webhooks", "err:", err.Error())
+			}
+		}
+	}()
+
+	// Webhook deliveries are queued as the events are relayed, so a failing
+	// webhook doesn't hold back the publisher.
+	// The hub also hears about the changes of this instance from the relay,
+	// without Postgres that is the only way.
+	outboxUC := outboxUseCase.New(outboxRepo, outbox.Multi(publisher, outbox.PublisherFunc(webhookUC.Enqueue), hub), cfg.OutboxBatch)
+	go func() {
+		for range time.Tick(cfg.OutboxInterval) {
+			if _, err := outboxUC.Relay(context.Background()); err != nil {
+				logger.Errorw("can`t relay outbox events", "err:", err.Error())
+			}
+		}
+	}()
+
+	if cfg.GRPCAddr != "" {
+		// Every wallet method is open to anonymous callers, as in the REST API,
+		// the methods check the role themselves where it matters.
+		grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+			middleware.UnaryPanic(logger),
+			middleware.UnaryAccessLog(logger),
+			middleware.UnaryMetrics(),
+			authManager.UnaryAuth(nil),
+		))
+		walletpb.RegisterWalletServiceServer(grpcServer, &walletRpc.WalletServer{
+			WalletUseCase:      walletHandler.WalletUseCase,
+			TransactionUseCase: transactionUC,
+			Logger:             logger,
+			ContextManager:     ctxManager.Manager{},
+		})
+
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			log.Println("Start gRPC server on " + cfg.GRPCAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Errorw("gRPC server stopped", "err:", err.Error())
+			}
+		}()
 	}
 
 	r := http.NewServeMux()
 
-	r.Handle("POST /api/v1/wallets", http.HandlerFunc(walletHandler.Create))
+	r.Handle("GET /metrics", promhttp.Handler())
+	r.Handle("GET /openapi.json", http.HandlerFunc(openapi.SpecHandler))
+	r.Handle("GET /docs", http.HandlerFunc(openapi.UIHandler))
+
+	r.Handle("POST /api/v1/wallets", authManager.OptionalAuth(http.HandlerFunc(walletHandler.Create)))
 	//r.Handle("GET /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.Get))
 	r.Handle("PATCH /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.Update))
 	r.Handle("DELETE /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.Delete))
-	r.Handle("GET /api/v1/wallets", http.HandlerFunc(walletHandler.GetAll))
+	r.Handle("POST /api/v1/wallets/{walletId}/restore", authManager.Auth(http.HandlerFunc(walletHandler.Restore), session.AdminRole))
+	r.Handle("GET /api/v1/wallets", authManager.OptionalAuth(http.HandlerFunc(walletHandler.GetAll)))
 	r.Handle("POST /api/v1/wallet", http.HandlerFunc(walletHandler.ChangeAmount))
-	r.Handle("GET /api/v1/wallets/{WALLET_UUID}", http.HandlerFunc(walletHandler.GetByUID))
+	r.Handle("POST /api/v1/wallets/{WALLET_UUID}/adjustments", authManager.Auth(http.HandlerFunc(walletHandler.Adjust), session.AdminRole))
+	r.Handle("POST /api/v1/wallets/{WALLET_UUID}/status", authManager.Auth(http.HandlerFunc(walletHandler.ChangeStatus), session.AdminRole))
+	r.Handle("PUT /api/v1/wallets/{WALLET_UUID}/shards", authManager.Auth(http.HandlerFunc(walletHandler.SetShards), session.AdminRole))
+	r.Handle("GET /api/v1/wallets/{WALLET_UUID}/status-changes", authManager.Auth(http.HandlerFunc(walletHandler.GetStatusChanges), session.AdminRole))
+	r.Handle("GET /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.GetByUID))
+	r.Handle("GET /api/v1/wallets/{WALLET_UUID}/transactions", http.HandlerFunc(transactionHandler.GetByWalletUID))
+	r.Handle("GET /api/v1/wallets/{WALLET_UUID}/events", authManager.Auth(http.HandlerFunc(streamHandler.WalletEvents)))
+	r.Handle("GET /api/v1/stream", authManager.Auth(http.HandlerFunc(streamHandler.Subscriptions)))
+	r.Handle("GET /api/v1/transactions/{TRANSACTION_UUID}", http.HandlerFunc(transactionHandler.GetByUID))
+	r.Handle("POST /api/v1/transactions/{TRANSACTION_UUID}/reversal", http.HandlerFunc(transactionHandler.Reverse))
+
+	r.Handle("POST /api/v1/wallets/{WALLET_UUID}/holds", http.HandlerFunc(holdHandler.Create))
+	r.Handle("GET /api/v1/holds/{HOLD_UUID}", http.HandlerFunc(holdHandler.GetByUID))
+	r.Handle("POST /api/v1/holds/{HOLD_UUID}/capture", http.HandlerFunc(holdHandler.Capture))
+	r.Handle("POST /api/v1/holds/{HOLD_UUID}/void", http.HandlerFunc(holdHandler.Void))
+
+	r.Handle("POST /api/v1/webhooks", authManager.Auth(http.HandlerFunc(webhookHandler.Create)))
+	r.Handle("GET /api/v1/webhooks", authManager.Auth(http.HandlerFunc(webhookHandler.GetAll)))
+	r.Handle("GET /api/v1/webhooks/{WEBHOOK_UUID}", authManager.Auth(http.HandlerFunc(webhookHandler.GetByUID)))
+	r.Handle("DELETE /api/v1/webhooks/{WEBHOOK_UUID}", authManager.Auth(http.HandlerFunc(webhookHandler.Delete)))
+	r.Handle("GET /api/v1/webhooks/{WEBHOOK_UUID}/deliveries", authManager.Auth(http.HandlerFunc(webhookHandler.GetDeliveries)))
+	r.Handle("POST /api/v1/webhooks/{WEBHOOK_UUID}/deliveries/{DELIVERY_UUID}/replay", authManager.Auth(http.HandlerFunc(webhookHandler.Replay)))
+
+	idempotencyStore, err := cache.OpenIdempotency(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	router := middleware.AccessLog(logger, r)
+	// Responses are kept as the handlers wrote them, a replay is rendered as
+	// problem details for the clients asking for them.
+	router := middleware.Idempotency(logger, idempotencyStore, r)
+	router = middleware.Problems(router)
+	router = middleware.ReadYourWrites(ctxManager.Manager{}, cfg.ReadYourWrites, router)
+	router = middleware.AccessLog(logger, router)
 	router = middleware.Panic(logger, router)
 
 	s := server.NewServer(router)
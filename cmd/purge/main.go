@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	pgWallet "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/database"
+	"go.uber.org/zap"
+)
+
+// purge permanently removes wallets that were soft deleted longer than the
+// retention period ago. It is meant to be run periodically, e.g. from cron.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	retention := flag.Duration("retention", cfg.WalletRetention, "how long deleted wallets are kept")
+	flag.StringVar(&cfg.Storage, "storage", cfg.Storage, "where wallets are kept: postgres or sqlite")
+	flag.Parse()
+
+	zapLogger := zap.Must(zap.NewDevelopment())
+	logger := zapLogger.Sugar()
+
+	db, err := database.Open(cfg, logger)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	purged, err := walletUseCase.New(pgWallet.New(logger, db)).Purge(*retention)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Infow("wallets purged", "count", purged, "retention", retention.String())
+
+	err = zapLogger.Sync()
+	if err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/reconcile"
+	transactionPg "github.com/Davmie/javaCode/internal/transaction/repository/postgres"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	walletDel "github.com/Davmie/javaCode/internal/wallet/delivery"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/asaskevich/govalidator"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errUsage is returned for bad arguments, the usage of the command is printed
+// by its flag set.
+var errUsage = errors.New("usage")
+
+// command is a subcommand of walletctl. Its flag set is created by run, args
+// are the arguments after the name of the command.
+type command struct {
+	synopsis string
+	run      func(ctl *walletctl, args []string) error
+}
+
+var commands = map[string]command{
+	"list":      {"list wallets", list},
+	"show":      {"show a wallet", show},
+	"create":    {"create a wallet", create},
+	"freeze":    {"freeze a wallet", freeze},
+	"unfreeze":  {"unfreeze a wallet", unfreeze},
+	"adjust":    {"adjust the balance of a wallet", adjust},
+	"tail":      {"print the latest ledger entries of a wallet", tail},
+	"migrate":   {"create or update the tables", migrate},
+	"reconcile": {"check balances against the ledger and the holds", reconcileWallets},
+}
+
+// walletctl is the admin tool of the operators. It works on the database of the
+// service directly, through the same repositories, so its changes are audited
+// and published as events like the ones made over the API.
+type walletctl struct {
+	cfg    *config.Config
+	logger *zap.SugaredLogger
+	db     *gorm.DB
+	out    *printer
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+
+	zapLogger := zap.Must(zap.NewDevelopment())
+	defer func() {
+		_ = zapLogger.Sync()
+	}()
+
+	ctl := &walletctl{cfg: cfg, logger: zapLogger.Sugar()}
+	err = cmd.run(ctl, flag.Args()[1:])
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: walletctl <command> [flags] [args]\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-10s %s\n", name, commands[name].synopsis)
+	}
+
+	fmt.Fprintf(out, "\nRun walletctl <command> -h for the flags of a command.\n"+
+		"The database is configured with the WALLETS_* variables of the service.\n")
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "walletctl:", err)
+	os.Exit(1)
+}
+
+// flags returns the flag set of a command with the flags every command has.
+// Usage describes its arguments.
+func (ctl *walletctl) flags(name string, usage string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: walletctl %s [flags] %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&ctl.cfg.Storage, "storage", ctl.cfg.Storage, "where wallets are kept: postgres or sqlite")
+	format := fs.String("o", formatTable, "output format: table, json or csv")
+
+	return fs, format
+}
+
+// parse parses the flags, expects nargs arguments after them and opens the
+// database.
+func (ctl *walletctl) parse(fs *flag.FlagSet, format *string, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		return err
+	}
+	ctl.out = out
+
+	if ctl.cfg.Storage == config.StorageMemory {
+		return errors.New("the memory storage lives in the service only, use postgres or sqlite")
+	}
+
+	ctl.db, err = database.Open(ctl.cfg, ctl.logger)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ctl *walletctl) wallets() walletUseCase.WalletUseCaseI {
+	return walletUseCase.New(walletPg.New(ctl.logger, ctl.db))
+}
+
+func (ctl *walletctl) transactions() transactionUseCase.TransactionUseCaseI {
+	return transactionUseCase.New(transactionPg.New(ctl.logger, ctl.db))
+}
+
+func list(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("list", "")
+	deleted := fs.Bool("deleted", false, "include soft deleted wallets")
+	if err := ctl.parse(fs, format, args, 0); err != nil {
+		return err
+	}
+
+	wallets, err := ctl.wallets().GetAll(*deleted)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.wallets(wallets)
+}
+
+func show(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("show", "<wallet uid>")
+	if err := ctl.parse(fs, format, args, 1); err != nil {
+		return err
+	}
+
+	wallet, err := ctl.wallets().GetByUID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.wallets([]*models.Wallet{wallet})
+}
+
+func create(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("create", "")
+	uid := fs.String("uid", "", "uid of the wallet, generated when empty")
+	name := fs.String("name", "", "name of the wallet")
+	owner := fs.Int("owner", 0, "id of the user owning the wallet, 0 for none")
+	if err := ctl.parse(fs, format, args, 0); err != nil {
+		return err
+	}
+
+	if *uid == "" {
+		*uid = uuid.NewString()
+	}
+
+	wallet := &models.Wallet{UID: *uid, Name: *name, OwnerID: *owner}
+	if err := ctl.wallets().Create(wallet); err != nil {
+		return err
+	}
+
+	return ctl.out.wallets([]*models.Wallet{wallet})
+}
+
+func freeze(ctl *walletctl, args []string) error {
+	return changeStatus(ctl, "freeze", models.WalletStatusFrozen, args)
+}
+
+func unfreeze(ctl *walletctl, args []string) error {
+	return changeStatus(ctl, "unfreeze", models.WalletStatusActive, args)
+}
+
+func changeStatus(ctl *walletctl, name string, status string, args []string) error {
+	fs, format := ctl.flags(name, "<wallet uid>")
+	reason := fs.String("reason", "", "why the status is changed, required")
+	actor := fs.Int("actor", 0, "id of the user changing the status")
+	if err := ctl.parse(fs, format, args, 1); err != nil {
+		return err
+	}
+
+	_, err := govalidator.ValidateStruct(walletDel.ChangeStatusRequest{Status: status, Reason: *reason})
+	if err != nil {
+		return errors.Wrap(err, "bad flags")
+	}
+
+	wallet, err := ctl.wallets().ChangeStatus(fs.Arg(0), status, *reason, *actor)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.wallets([]*models.Wallet{wallet})
+}
+
+func adjust(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("adjust", "<wallet uid>")
+	amount := fs.Int("amount", 0, "signed change of the balance, required")
+	reasonCode := fs.String("reason", "", "reason code: "+strings.Join(adjustmentReasons, ", "))
+	comment := fs.String("comment", "", "why the balance is adjusted, required")
+	actor := fs.Int("actor", 0, "id of the user adjusting the balance")
+	if err := ctl.parse(fs, format, args, 1); err != nil {
+		return err
+	}
+
+	_, err := govalidator.ValidateStruct(walletDel.AdjustRequest{Amount: *amount, ReasonCode: *reasonCode, Comment: *comment})
+	if err == nil && *amount == 0 {
+		err = errors.New("amount: zero amount")
+	}
+	if err != nil {
+		return errors.Wrap(err, "bad flags")
+	}
+
+	transaction, err := ctl.wallets().Adjust(fs.Arg(0), *amount, *reasonCode, *comment, *actor)
+	if err != nil {
+		return err
+	}
+
+	return ctl.out.transactions([]*models.Transaction{transaction})
+}
+
+var adjustmentReasons = []string{
+	models.AdjustmentReasonCorrection,
+	models.AdjustmentReasonChargeback,
+	models.AdjustmentReasonFee,
+	models.AdjustmentReasonGoodwill,
+	models.AdjustmentReasonMigration,
+}
+
+func tail(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("tail", "<wallet uid>")
+	n := fs.Int("n", 10, "how many latest entries are printed")
+	follow := fs.Bool("f", false, "keep printing new entries")
+	interval := fs.Duration("interval", time.Second, "how often new entries are looked for with -f")
+	if err := ctl.parse(fs, format, args, 1); err != nil {
+		return err
+	}
+
+	uid := fs.Arg(0)
+	if _, err := ctl.wallets().GetByUID(uid); err != nil {
+		return err
+	}
+
+	transactions, err := ctl.transactions().GetByWalletUID(uid)
+	if err != nil {
+		return err
+	}
+	lastID := 0
+	if len(transactions) > 0 {
+		lastID = transactions[len(transactions)-1].ID
+	}
+	if len(transactions) > *n {
+		transactions = transactions[len(transactions)-max(*n, 0):]
+	}
+
+	ctl.out.stream = *follow
+	if err = ctl.out.transactions(transactions); err != nil {
+		return err
+	}
+	if !*follow {
+		return nil
+	}
+
+	for range time.Tick(*interval) {
+		transactions, err = ctl.transactions().GetByWalletUIDAfter(uid, lastID, 100)
+		if err != nil {
+			return err
+		}
+		if len(transactions) == 0 {
+			continue
+		}
+
+		if err = ctl.out.transactions(transactions); err != nil {
+			return err
+		}
+		lastID = transactions[len(transactions)-1].ID
+	}
+
+	return nil
+}
+
+func migrate(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("migrate", "")
+	// The database is migrated when it is opened.
+	if err := ctl.parse(fs, format, args, 0); err != nil {
+		return err
+	}
+
+	ctl.logger.Infow("database migrated", "storage", ctl.cfg.Storage)
+
+	return nil
+}
+
+func reconcileWallets(ctl *walletctl, args []string) error {
+	fs, format := ctl.flags("reconcile", "")
+	all := fs.Bool("all", false, "print consistent wallets too")
+	if err := ctl.parse(fs, format, args, 0); err != nil {
+		return err
+	}
+
+	reports, err := reconcile.Run(ctl.db)
+	if err != nil {
+		return err
+	}
+
+	inconsistent := make([]*reconcile.Report, 0)
+	for _, r := range reports {
+		if !r.Consistent() {
+			inconsistent = append(inconsistent, r)
+		}
+	}
+
+	printed := inconsistent
+	if *all {
+		printed = reports
+	}
+	if err = ctl.out.reports(printed); err != nil {
+		return err
+	}
+
+	if len(inconsistent) > 0 {
+		return errors.Errorf("%d of %d wallets are inconsistent", len(inconsistent), len(reports))
+	}
+
+	return nil
+}
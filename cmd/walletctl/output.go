@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/reconcile"
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// printer writes records as an aligned table, CSV or JSON. A printer used
+// more than once, like by tail -f, prints the header only the first time and
+// writes JSON one record per line.
+type printer struct {
+	w      io.Writer
+	format string
+	stream bool
+	headed bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, errors.Errorf("unknown output format %q, expected table, json or csv", format)
+	}
+}
+
+// print writes records, rows holds the cells of each of them under header.
+func (p *printer) print(header []string, rows [][]string, records interface{}) error {
+	switch p.format {
+	case formatJSON:
+		return p.printJSON(records)
+	case formatCSV:
+		cw := csv.NewWriter(p.w)
+		if !p.headed {
+			_ = cw.Write(header)
+		}
+		_ = cw.WriteAll(rows)
+		p.headed = true
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+		if !p.headed {
+			fmt.Fprintln(tw, strings.Join(header, "\t"))
+		}
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		p.headed = true
+		return tw.Flush()
+	}
+}
+
+func (p *printer) printJSON(records interface{}) error {
+	enc := json.NewEncoder(p.w)
+	if !p.stream {
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err = json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err = enc.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var walletHeader = []string{"ID", "UID", "NAME", "STATUS", "AMOUNT", "HELD", "AVAILABLE", "SHARDS", "OWNER", "VERSION", "DELETED"}
+
+func (p *printer) wallets(wallets []*models.Wallet) error {
+	rows := make([][]string, 0, len(wallets))
+	for _, w := range wallets {
+		deleted := ""
+		if w.DeletedAt.Valid {
+			deleted = w.DeletedAt.Time.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(w.ID), w.UID, w.Name, w.Status,
+			strconv.Itoa(w.Amount), strconv.Itoa(w.Held), strconv.Itoa(w.Available()),
+			strconv.Itoa(w.Shards), strconv.Itoa(w.OwnerID), strconv.Itoa(w.Version), deleted,
+		})
+	}
+
+	return p.print(walletHeader, rows, wallets)
+}
+
+var transactionHeader = []string{"ID", "UID", "WALLET", "TYPE", "AMOUNT", "REVERSED", "REVERSAL OF", "REASON", "COMMENT", "CREATED"}
+
+func (p *printer) transactions(transactions []*models.Transaction) error {
+	rows := make([][]string, 0, len(transactions))
+	for _, t := range transactions {
+		reversalOf := ""
+		if t.ReversalOf != nil {
+			reversalOf = *t.ReversalOf
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(t.ID), t.UID, t.WalletUID, t.Type,
+			strconv.Itoa(t.Amount), strconv.Itoa(t.Reversed), reversalOf,
+			t.ReasonCode, t.Comment, t.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return p.print(transactionHeader, rows, transactions)
+}
+
+var reportHeader = []string{"WALLET", "STATUS", "AMOUNT", "LEDGER", "HELD", "HOLDS", "PROBLEMS"}
+
+func (p *printer) reports(reports []*reconcile.Report) error {
+	rows := make([][]string, 0, len(reports))
+	for _, r := range reports {
+		rows = append(rows, []string{
+			r.WalletUID, r.Status,
+			strconv.Itoa(r.Amount), strconv.Itoa(r.Ledger), strconv.Itoa(r.Held), strconv.Itoa(r.Holds),
+			strings.Join(r.Problems, "; "),
+		})
+	}
+
+	return p.print(reportHeader, rows, reports)
+}
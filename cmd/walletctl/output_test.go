@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/reconcile"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/gorm"
+)
+
+type OutputTestSuite struct {
+	suite.Suite
+	out     bytes.Buffer
+	wallets []*models.Wallet
+}
+
+func TestOutputSuite(t *testing.T) {
+	suite.RunSuite(t, new(OutputTestSuite))
+}
+
+func (s *OutputTestSuite) BeforeEach(t provider.T) {
+	s.out.Reset()
+	s.wallets = []*models.Wallet{
+		{ID: 1, UID: "first", Name: "savings", Status: models.WalletStatusActive, Amount: 100, Held: 30, OwnerID: 7, Version: 2},
+		{
+			ID: 2, UID: "second", Name: "old, closed", Status: models.WalletStatusClosed,
+			DeletedAt: gorm.DeletedAt{Time: time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC), Valid: true},
+		},
+	}
+}
+
+func (s *OutputTestSuite) printer(t provider.T, format string) *printer {
+	p, err := newPrinter(&s.out, format)
+	t.Require().NoError(err)
+
+	return p
+}
+
+func (s *OutputTestSuite) TestUnknownFormat(t provider.T) {
+	_, err := newPrinter(&s.out, "yaml")
+
+	t.Require().Error(err)
+	t.Assert().Contains(err.Error(), `unknown output format "yaml"`)
+}
+
+func (s *OutputTestSuite) TestTable(t provider.T) {
+	t.Require().NoError(s.printer(t, formatTable).wallets(s.wallets))
+
+	t.Assert().Equal(""+
+		"ID  UID     NAME         STATUS  AMOUNT  HELD  AVAILABLE  SHARDS  OWNER  VERSION  DELETED\n"+
+		"1   first   savings      ACTIVE  100     30    70         0       7      2        \n"+
+		"2   second  old, closed  CLOSED  0       0     0          0       0      0        2024-11-09T12:00:00Z\n",
+		s.out.String())
+}
+
+func (s *OutputTestSuite) TestJSON(t provider.T) {
+	t.Require().NoError(s.printer(t, formatJSON).wallets(s.wallets))
+
+	t.Assert().True(strings.HasPrefix(s.out.String(), "[\n  {\n"), "indented array")
+
+	var wallets []*models.Wallet
+	t.Require().NoError(json.Unmarshal(s.out.Bytes(), &wallets))
+	t.Require().Len(wallets, 2)
+	t.Assert().Equal("first", wallets[0].UID)
+	t.Assert().Equal(100, wallets[0].Amount)
+	t.Assert().Equal("old, closed", wallets[1].Name)
+}
+
+func (s *OutputTestSuite) TestCSV(t provider.T) {
+	reversalOf := "deposit"
+	transactions := []*models.Transaction{{
+		ID:         3,
+		UID:        "reversal",
+		WalletUID:  "first",
+		Type:       models.TransactionTypeReversal,
+		Amount:     -40,
+		ReversalOf: &reversalOf,
+		Comment:    `said "oops"`,
+		CreatedAt:  time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC),
+	}}
+
+	t.Require().NoError(s.printer(t, formatCSV).transactions(transactions))
+
+	t.Assert().Equal(""+
+		"ID,UID,WALLET,TYPE,AMOUNT,REVERSED,REVERSAL OF,REASON,COMMENT,CREATED\n"+
+		`3,reversal,first,REVERSAL,-40,0,deposit,,"said ""oops""",2024-11-09T12:00:00Z`+"\n",
+		s.out.String())
+}
+
+func (s *OutputTestSuite) TestReports(t provider.T) {
+	reports := []*reconcile.Report{{
+		WalletUID: "first",
+		Status:    models.WalletStatusActive,
+		Amount:    120,
+		Ledger:    100,
+		Held:      30,
+		Problems:  []string{"amount differs from ledger by 20", "held differs from active holds by 30"},
+	}}
+
+	t.Require().NoError(s.printer(t, formatCSV).reports(reports))
+
+	t.Assert().Equal(""+
+		"WALLET,STATUS,AMOUNT,LEDGER,HELD,HOLDS,PROBLEMS\n"+
+		"first,ACTIVE,120,100,30,0,amount differs from ledger by 20; held differs from active holds by 30\n",
+		s.out.String())
+}
+
+func (s *OutputTestSuite) TestStreamTable(t provider.T) {
+	p := s.printer(t, formatTable)
+	p.stream = true
+
+	t.Require().NoError(p.wallets(s.wallets[:1]))
+	t.Require().NoError(p.wallets(s.wallets[1:]))
+
+	lines := strings.Split(strings.TrimSuffix(s.out.String(), "\n"), "\n")
+	t.Require().Len(lines, 3)
+	t.Assert().True(strings.HasPrefix(lines[0], "ID"))
+	t.Assert().True(strings.HasPrefix(lines[1], "1"))
+	t.Assert().True(strings.HasPrefix(lines[2], "2"))
+}
+
+func (s *OutputTestSuite) TestStreamCSV(t provider.T) {
+	p := s.printer(t, formatCSV)
+	p.stream = true
+
+	t.Require().NoError(p.wallets(s.wallets[:1]))
+	t.Require().NoError(p.wallets(s.wallets[1:]))
+
+	lines := strings.Split(strings.TrimSuffix(s.out.String(), "\n"), "\n")
+	t.Require().Len(lines, 3)
+	t.Assert().True(strings.HasPrefix(lines[0], "ID,UID"))
+	t.Assert().True(strings.HasPrefix(lines[1], "1,first"))
+	t.Assert().True(strings.HasPrefix(lines[2], `2,second,"old, closed"`))
+}
+
+func (s *OutputTestSuite) TestStreamJSON(t provider.T) {
+	p := s.printer(t, formatJSON)
+	p.stream = true
+
+	t.Require().NoError(p.wallets(s.wallets[:1]))
+	t.Require().NoError(p.wallets(s.wallets[1:]))
+
+	lines := strings.Split(strings.TrimSuffix(s.out.String(), "\n"), "\n")
+	t.Require().Len(lines, 2)
+	for i, line := range lines {
+		wallet := &models.Wallet{}
+		t.Require().NoError(json.Unmarshal([]byte(line), wallet))
+		t.Assert().Equal(s.wallets[i].UID, wallet.UID)
+	}
+}
+
+func (s *OutputTestSuite) TestStreamJSONWithoutRecords(t provider.T) {
+	p := s.printer(t, formatJSON)
+	p.stream = true
+
+	t.Require().NoError(p.wallets(nil))
+
+	t.Assert().Empty(s.out.String())
+}
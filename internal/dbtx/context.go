@@ -0,0 +1,24 @@
+package dbtx
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// WithTx returns ctx carrying tx. Repositories given the context join the
+// transaction instead of using a connection of their own.
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// From returns the transaction carried by ctx, or db without one.
+func From(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+
+	return db
+}
@@ -0,0 +1,72 @@
+package dbtx
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// MaxAttempts is how many times a transaction is run before its serialization
+// failure or deadlock is returned to the caller.
+const MaxAttempts = 5
+
+// BaseBackoff is the upper bound of the first pause between attempts, every
+// next attempt doubles it. The actual pause is a random share of the bound, so
+// the transactions that collided don't collide again.
+var BaseBackoff = 10 * time.Millisecond
+
+var (
+	retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_transaction_retries_total",
+		Help: "Transactions run again after a serialization failure or a deadlock.",
+	}, []string{"operation", "sqlstate"})
+	exhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_transaction_retries_exhausted_total",
+		Help: "Transactions that kept failing after MaxAttempts attempts.",
+	}, []string{"operation"})
+)
+
+// Run runs fn in a transaction of db and runs it again while Postgres rejects
+// it with a serialization failure or a deadlock. fn must not keep state between
+// attempts. operation names the transaction in the metrics.
+func Run(db *gorm.DB, operation string, fn func(tx *gorm.DB) error) error {
+	backoff := BaseBackoff
+
+	for attempt := 1; ; attempt++ {
+		err := db.Transaction(fn)
+
+		code, ok := retryable(err)
+		if !ok {
+			return err
+		}
+
+		if attempt == MaxAttempts {
+			exhausted.WithLabelValues(operation).Inc()
+			return err
+		}
+
+		retries.WithLabelValues(operation, code).Inc()
+		time.Sleep(rand.N(backoff) + 1)
+		backoff *= 2
+	}
+}
+
+// retryable returns the SQLSTATE of err if the transaction can be run again.
+func retryable(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+
+	return pgErr.Code, pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
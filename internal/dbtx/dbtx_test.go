@@ -0,0 +1,119 @@
+package dbtx
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Davmie/javaCode/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type DBTxTestSuite struct {
+	suite.Suite
+	db     *sql.DB
+	gormDB *gorm.DB
+	mock   sqlmock.Sqlmock
+}
+
+func TestDBTxSuite(t *testing.T) {
+	suite.RunSuite(t, new(DBTxTestSuite))
+}
+
+func (s *DBTxTestSuite) BeforeEach(t provider.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("error while creating sql mock")
+	}
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal("error gorm open")
+	}
+
+	s.db = db
+	s.gormDB = gormDB
+	s.mock = mock
+	BaseBackoff = time.Microsecond
+}
+
+func (s *DBTxTestSuite) AfterEach(t provider.T) {
+	err := s.mock.ExpectationsWereMet()
+	t.Assert().NoError(err)
+	s.db.Close()
+}
+
+func (s *DBTxTestSuite) TestRetrySerializationFailure(t provider.T) {
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+	s.mock.ExpectBegin()
+	s.mock.ExpectCommit()
+
+	codes := []string{serializationFailure, deadlockDetected}
+	attempts := 0
+	err := Run(s.gormDB, "test", func(tx *gorm.DB) error {
+		attempts++
+		if attempts <= len(codes) {
+			return errors.Wrap(&pgconn.PgError{Code: codes[attempts-1]}, "conflict")
+		}
+		return nil
+	})
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(3, attempts)
+}
+
+func (s *DBTxTestSuite) TestRetryLimit(t provider.T) {
+	for i := 0; i < MaxAttempts; i++ {
+		s.mock.ExpectBegin()
+		s.mock.ExpectRollback()
+	}
+
+	attempts := 0
+	err := Run(s.gormDB, "test", func(tx *gorm.DB) error {
+		attempts++
+		return &pgconn.PgError{Code: serializationFailure}
+	})
+
+	var pgErr *pgconn.PgError
+	t.Require().ErrorAs(err, &pgErr)
+	t.Assert().Equal(serializationFailure, pgErr.Code)
+	t.Assert().Equal(MaxAttempts, attempts)
+}
+
+func (s *DBTxTestSuite) TestNoRetryOnOtherErrors(t provider.T) {
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+	s.mock.ExpectBegin()
+	s.mock.ExpectRollback()
+
+	attempts := 0
+	err := Run(s.gormDB, "test", func(tx *gorm.DB) error {
+		attempts++
+		return models.ErrInsufficientFunds
+	})
+
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+
+	err = Run(s.gormDB, "test", func(tx *gorm.DB) error {
+		attempts++
+		return &pgconn.PgError{Code: "23505"}
+	})
+
+	t.Assert().Error(err)
+	t.Assert().Equal(2, attempts)
+}
@@ -0,0 +1,202 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	holdUseCase "github.com/Davmie/javaCode/internal/hold/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/asaskevich/govalidator"
+	"github.com/pkg/errors"
+)
+
+type HoldHandler struct {
+	HoldUseCase holdUseCase.HoldUseCaseI
+	Logger      logger.Logger
+}
+
+type CreateHoldRequest struct {
+	Amount     int `json:"amount"`
+	TTLSeconds int `json:"ttlSeconds"`
+}
+
+type CaptureHoldRequest struct {
+	Amount int `json:"amount"`
+}
+
+func (hh *HoldHandler) Create(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		hh.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	if !govalidator.IsUUID(walletUID) {
+		hh.Logger.Infow("can`t create hold",
+			"err", "walletId must be uuid")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	createReq := CreateHoldRequest{}
+	if !hh.readJSON(w, r, &createReq) {
+		return
+	}
+
+	if createReq.Amount <= 0 || createReq.TTLSeconds < 0 {
+		hh.Logger.Infow("can`t create hold",
+			"err", "amount must be positive")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := hh.HoldUseCase.Create(walletUID, createReq.Amount, time.Duration(createReq.TTLSeconds)*time.Second)
+	if err != nil {
+		hh.Logger.Infow("can`t create hold",
+			"err:", err.Error())
+		http.Error(w, "can`t create hold", errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/holds/%s", hold.UID))
+	hh.writeJSON(w, http.StatusCreated, hold)
+}
+
+func (hh *HoldHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
+	holdUID := r.PathValue("HOLD_UUID")
+	if holdUID == "" {
+		hh.Logger.Errorw("no HOLD_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	hold, err := hh.HoldUseCase.GetByUID(holdUID)
+	if err != nil {
+		hh.Logger.Infow("can`t get hold",
+			"err:", err.Error())
+		http.Error(w, "can`t get hold", errorStatus(err))
+		return
+	}
+
+	hh.writeJSON(w, http.StatusOK, hold)
+}
+
+func (hh *HoldHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	holdUID := r.PathValue("HOLD_UUID")
+	if holdUID == "" {
+		hh.Logger.Errorw("no HOLD_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	captureReq := CaptureHoldRequest{}
+	if !hh.readJSON(w, r, &captureReq) {
+		return
+	}
+
+	if captureReq.Amount < 0 {
+		hh.Logger.Infow("can`t capture hold",
+			"err", "amount must not be negative")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	hold, err := hh.HoldUseCase.Capture(holdUID, captureReq.Amount)
+	if err != nil {
+		hh.Logger.Infow("can`t capture hold",
+			"err:", err.Error())
+		http.Error(w, "can`t capture hold", errorStatus(err))
+		return
+	}
+
+	hh.writeJSON(w, http.StatusOK, hold)
+}
+
+func (hh *HoldHandler) Void(w http.ResponseWriter, r *http.Request) {
+	holdUID := r.PathValue("HOLD_UUID")
+	if holdUID == "" {
+		hh.Logger.Errorw("no HOLD_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	hold, err := hh.HoldUseCase.Void(holdUID)
+	if err != nil {
+		hh.Logger.Infow("can`t void hold",
+			"err:", err.Error())
+		http.Error(w, "can`t void hold", errorStatus(err))
+		return
+	}
+
+	hh.writeJSON(w, http.StatusOK, hold)
+}
+
+func (hh *HoldHandler) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		hh.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return false
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		hh.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return false
+	}
+
+	if len(body) == 0 {
+		return true
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		hh.Logger.Infow("can`t unmarshal form",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func (hh *HoldHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		hh.Logger.Errorw("can`t marshal hold",
+			"err:", err.Error())
+		http.Error(w, "can`t make hold", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		hh.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInsufficientFunds),
+		errors.Is(err, models.ErrWalletFrozen),
+		errors.Is(err, models.ErrWalletClosed),
+		errors.Is(err, models.ErrHoldNotActive),
+		errors.Is(err, models.ErrCaptureExceedsHold):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
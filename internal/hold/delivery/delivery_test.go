@@ -0,0 +1,63 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	holdMocks "github.com/Davmie/javaCode/internal/hold/repository/mocks"
+	holdUseCase "github.com/Davmie/javaCode/internal/hold/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+)
+
+type HoldHandlerTestSuite struct {
+	suite.Suite
+	holdRepoMock *holdMocks.HoldRepositoryI
+	mux          *http.ServeMux
+}
+
+func TestHoldHandlerSuite(t *testing.T) {
+	suite.RunSuite(t, new(HoldHandlerTestSuite))
+}
+
+func (s *HoldHandlerTestSuite) BeforeEach(t provider.T) {
+	s.holdRepoMock = holdMocks.NewHoldRepositoryI(t)
+
+	handler := &HoldHandler{
+		HoldUseCase: holdUseCase.New(s.holdRepoMock),
+		Logger:      zap.NewNop().Sugar(),
+	}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /api/v1/wallets/{WALLET_UUID}/holds", handler.Create)
+}
+
+func (s *HoldHandlerTestSuite) create(walletUID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/"+walletUID+"/holds", strings.NewReader(`{"amount":20}`))
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *HoldHandlerTestSuite) TestCreate(t provider.T) {
+	walletUID := "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	s.holdRepoMock.On("Create", mock.MatchedBy(func(h *models.Hold) bool {
+		return h.WalletUID == walletUID && h.Amount == 20
+	})).Return(nil)
+
+	rec := s.create(walletUID)
+
+	t.Assert().Equal(http.StatusCreated, rec.Code)
+}
+
+func (s *HoldHandlerTestSuite) TestCreateWithBadWalletUID(t provider.T) {
+	rec := s.create("wallet")
+
+	t.Assert().Equal(http.StatusBadRequest, rec.Code)
+	s.holdRepoMock.AssertNotCalled(t, "Create", mock.Anything)
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/hold/repository"
+	walletCache "github.com/Davmie/javaCode/internal/wallet/repository/cache"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+)
+
+// cachedHoldRepo evicts the cached wallets whose held amount is changed by a hold.
+type cachedHoldRepo struct {
+	Logger logger.Logger
+	Repo   repository.HoldRepositoryI
+	Cache  *walletCache.WalletCache
+}
+
+func New(logger logger.Logger, repo repository.HoldRepositoryI, cache *walletCache.WalletCache) repository.HoldRepositoryI {
+	return &cachedHoldRepo{
+		Logger: logger,
+		Repo:   repo,
+		Cache:  cache,
+	}
+}
+
+func (cr *cachedHoldRepo) Create(h *models.Hold) error {
+	if err := cr.Repo.Create(h); err != nil {
+		return err
+	}
+
+	cr.Cache.Invalidate(h.WalletUID)
+
+	return nil
+}
+
+func (cr *cachedHoldRepo) GetByUID(uid string) (*models.Hold, error) {
+	return cr.Repo.GetByUID(uid)
+}
+
+func (cr *cachedHoldRepo) Capture(uid string, amount int, now time.Time) (*models.Hold, error) {
+	h, err := cr.Repo.Capture(uid, amount, now)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Cache.Invalidate(h.WalletUID)
+
+	return h, nil
+}
+
+func (cr *cachedHoldRepo) Void(uid string) (*models.Hold, error) {
+	h, err := cr.Repo.Void(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Cache.Invalidate(h.WalletUID)
+
+	return h, nil
+}
+
+// Expire doesn't tell which wallets were released, so the whole cache is flushed.
+func (cr *cachedHoldRepo) Expire(now time.Time) (int, error) {
+	expired, err := cr.Repo.Expire(now)
+	if err != nil {
+		return 0, err
+	}
+
+	if expired > 0 {
+		cr.Cache.Flush()
+	}
+
+	return expired, nil
+}
@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type memHoldRepo struct {
+	Logger logger.Logger
+	Store  *memstore.Store
+}
+
+func New(logger logger.Logger, store *memstore.Store) repository.HoldRepositoryI {
+	return &memHoldRepo{
+		Logger: logger,
+		Store:  store,
+	}
+}
+
+// Create reserves h.Amount on the wallet and stores the hold.
+func (mr *memHoldRepo) Create(h *models.Hold) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w := mr.Store.WalletByUID(h.WalletUID, false)
+	switch {
+	case w == nil:
+		return errors.Wrap(models.ErrNotFound, "memHoldRepo.Create error")
+	case w.Status == models.WalletStatusFrozen:
+		return errors.Wrap(models.ErrWalletFrozen, "memHoldRepo.Create error")
+	case w.Status == models.WalletStatusClosed:
+		return errors.Wrap(models.ErrWalletClosed, "memHoldRepo.Create error")
+	case w.Available() < h.Amount:
+		return errors.Wrap(models.ErrInsufficientFunds, "memHoldRepo.Create error")
+	}
+
+	w.Held += h.Amount
+	w.Version++
+
+	h.ID = mr.Store.NextID(h.TableName())
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = time.Now()
+	}
+	stored := *h
+	mr.Store.Holds = append(mr.Store.Holds, &stored)
+	mr.Store.AddEvents(outbox.HoldEvent(models.EventHoldCreated, &stored, w.OwnerID))
+
+	return nil
+}
+
+func (mr *memHoldRepo) GetByUID(uid string) (*models.Hold, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	h := mr.byUID(uid)
+	if h == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memHoldRepo.GetByUID error")
+	}
+
+	res := *h
+	return &res, nil
+}
+
+// Capture charges amount from the wallet and releases the whole reservation.
+// The charge is recorded as a CAPTURE ledger entry. Like the postgres
+// repository it needs an active wallet.
+func (mr *memHoldRepo) Capture(uid string, amount int, now time.Time) (*models.Hold, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	h := mr.byUID(uid)
+	switch {
+	case h == nil:
+		return nil, errors.Wrap(models.ErrNotFound, "memHoldRepo.Capture error")
+	case h.Status != models.HoldStatusActive || !h.ExpiresAt.After(now):
+		return nil, errors.Wrap(models.ErrHoldNotActive, "memHoldRepo.Capture error")
+	case amount > h.Amount:
+		return nil, errors.Wrap(models.ErrCaptureExceedsHold, "memHoldRepo.Capture error")
+	}
+
+	w := mr.Store.WalletByUID(h.WalletUID, false)
+	switch {
+	case w == nil:
+		return nil, errors.Wrap(models.ErrNotFound, "memHoldRepo.Capture error")
+	case w.Status == models.WalletStatusFrozen:
+		return nil, errors.Wrap(models.ErrWalletFrozen, "memHoldRepo.Capture error")
+	case w.Status == models.WalletStatusClosed:
+		return nil, errors.Wrap(models.ErrWalletClosed, "memHoldRepo.Capture error")
+	}
+
+	h.Status = models.HoldStatusCaptured
+	h.Captured = amount
+
+	w.Amount -= amount
+	w.Held -= h.Amount
+	w.Version++
+
+	t := &models.Transaction{
+		UID:       uuid.NewString(),
+		WalletUID: h.WalletUID,
+		Type:      models.TransactionTypeCapture,
+		Amount:    -amount,
+		CreatedAt: now,
+	}
+	mr.Store.AddTransaction(t)
+	mr.Store.AddEvents(outbox.HoldEvent(models.EventHoldCaptured, h, w.OwnerID), outbox.BalanceChanged(t, w))
+
+	res := *h
+	return &res, nil
+}
+
+func (mr *memHoldRepo) Void(uid string) (*models.Hold, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	h := mr.byUID(uid)
+	switch {
+	case h == nil:
+		return nil, errors.Wrap(models.ErrNotFound, "memHoldRepo.Void error")
+	case h.Status != models.HoldStatusActive:
+		return nil, errors.Wrap(models.ErrHoldNotActive, "memHoldRepo.Void error")
+	}
+
+	h.Status = models.HoldStatusVoided
+	ownerID := mr.release(h)
+	mr.Store.AddEvents(outbox.HoldEvent(models.EventHoldVoided, h, ownerID))
+
+	res := *h
+	return &res, nil
+}
+
+// Expire releases every active hold whose TTL has passed and returns their number.
+func (mr *memHoldRepo) Expire(now time.Time) (int, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	expired := 0
+	for _, h := range mr.Store.Holds {
+		if h.Status == models.HoldStatusActive && !h.ExpiresAt.After(now) {
+			h.Status = models.HoldStatusExpired
+			ownerID := mr.release(h)
+			mr.Store.AddEvents(outbox.HoldEvent(models.EventHoldExpired, h, ownerID))
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// release returns the reserved amount of h to the available wallet balance,
+// whatever the status of the wallet, deleted wallets included. It returns the
+// owner of the wallet.
+func (mr *memHoldRepo) release(h *models.Hold) int {
+	w := mr.Store.WalletByUID(h.WalletUID, true)
+	if w == nil {
+		return 0
+	}
+
+	w.Held -= h.Amount
+	w.Version++
+
+	return w.OwnerID
+}
+
+func (mr *memHoldRepo) byUID(uid string) *models.Hold {
+	for _, h := range mr.Store.Holds {
+		if h.UID == uid {
+			return h
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	holdRep "github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	walletMem "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type HoldMemRepoTestSuite struct {
+	suite.Suite
+	repo        holdRep.HoldRepositoryI
+	walletRepo  walletRep.WalletRepositoryI
+	holdBuilder *testBuilders.HoldBuilder
+	now         time.Time
+}
+
+func TestHoldMemRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(HoldMemRepoTestSuite))
+}
+
+func (s *HoldMemRepoTestSuite) BeforeEach(t provider.T) {
+	var logger logger.Logger
+
+	store := memstore.New()
+	s.repo = New(logger, store)
+	s.walletRepo = walletMem.New(logger, store)
+	s.holdBuilder = testBuilders.NewHoldBuilder()
+	s.now = time.Now()
+
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithAmount(100).
+		WithStatus(models.WalletStatusActive).
+		Build()
+	err := s.walletRepo.Create(&wallet)
+	t.Require().NoError(err)
+}
+
+func (s *HoldMemRepoTestSuite) createHold(t provider.T, uid string, amount int) {
+	hold := s.holdBuilder.
+		WithUID(uid).
+		WithWalletUID("wallet").
+		WithAmount(amount).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(s.now.Add(time.Hour)).
+		Build()
+
+	err := s.repo.Create(&hold)
+	t.Require().NoError(err)
+}
+
+func (s *HoldMemRepoTestSuite) TestCreateHold(t provider.T) {
+	s.createHold(t, "hold", 60)
+
+	wallet, err := s.walletRepo.GetByUID("wallet")
+	t.Assert().NoError(err)
+	t.Assert().Equal(60, wallet.Held)
+
+	tooBig := s.holdBuilder.WithUID("other").WithAmount(50).Build()
+	err = s.repo.Create(&tooBig)
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
+
+func (s *HoldMemRepoTestSuite) TestCaptureHold(t provider.T) {
+	s.createHold(t, "hold", 60)
+
+	_, err := s.repo.Capture("hold", 70, s.now)
+	t.Assert().ErrorIs(err, models.ErrCaptureExceedsHold)
+
+	hold, err := s.repo.Capture("hold", 40, s.now)
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.HoldStatusCaptured, hold.Status)
+
+	wallet, err := s.walletRepo.GetByUID("wallet")
+	t.Assert().NoError(err)
+	t.Assert().Equal(60, wallet.Amount)
+	t.Assert().Equal(0, wallet.Held)
+
+	_, err = s.repo.Capture("hold", 10, s.now)
+	t.Assert().ErrorIs(err, models.ErrHoldNotActive)
+}
+
+func (s *HoldMemRepoTestSuite) TestVoidHold(t provider.T) {
+	s.createHold(t, "hold", 60)
+
+	hold, err := s.repo.Void("hold")
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.HoldStatusVoided, hold.Status)
+
+	_, err = s.repo.Void("hold")
+	t.Assert().ErrorIs(err, models.ErrHoldNotActive)
+
+	_, err = s.repo.Void("unknown")
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *HoldMemRepoTestSuite) TestExpireHolds(t provider.T) {
+	s.createHold(t, "hold", 60)
+
+	expired, err := s.repo.Expire(s.now)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, expired)
+
+	expired, err = s.repo.Expire(s.now.Add(time.Hour))
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, expired)
+
+	wallet, err := s.walletRepo.GetByUID("wallet")
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, wallet.Held)
+}
+
+func (s *HoldMemRepoTestSuite) TestCaptureFrozenWallet(t provider.T) {
+	s.createHold(t, "hold", 60)
+	_, err := s.walletRepo.ChangeStatus(&models.WalletStatusChange{
+		WalletUID:  "wallet",
+		FromStatus: models.WalletStatusActive,
+		ToStatus:   models.WalletStatusFrozen,
+	})
+	t.Require().NoError(err)
+
+	_, err = s.repo.Capture("hold", 40, s.now)
+	t.Assert().ErrorIs(err, models.ErrWalletFrozen)
+
+	hold, err := s.repo.GetByUID("hold")
+	t.Require().NoError(err)
+	t.Assert().Equal(models.HoldStatusActive, hold.Status)
+
+	_, err = s.repo.Void("hold")
+	t.Assert().NoError(err)
+
+	wallet, err := s.walletRepo.GetByUID("wallet")
+	t.Assert().NoError(err)
+	t.Assert().Equal(100, wallet.Amount)
+	t.Assert().Equal(0, wallet.Held)
+}
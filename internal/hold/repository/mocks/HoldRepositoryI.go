@@ -0,0 +1,145 @@
+// Code generated by mockery v2.33.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "github.com/Davmie/javaCode/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// HoldRepositoryI is an autogenerated mock type for the HoldRepositoryI type
+type HoldRepositoryI struct {
+	mock.Mock
+}
+
+// Capture provides a mock function with given fields: uid, amount, now
+func (_m *HoldRepositoryI) Capture(uid string, amount int, now time.Time) (*models.Hold, error) {
+	ret := _m.Called(uid, amount, now)
+
+	var r0 *models.Hold
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, time.Time) (*models.Hold, error)); ok {
+		return rf(uid, amount, now)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, time.Time) *models.Hold); ok {
+		r0 = rf(uid, amount, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Hold)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, time.Time) error); ok {
+		r1 = rf(uid, amount, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: h
+func (_m *HoldRepositoryI) Create(h *models.Hold) error {
+	ret := _m.Called(h)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Hold) error); ok {
+		r0 = rf(h)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Expire provides a mock function with given fields: now
+func (_m *HoldRepositoryI) Expire(now time.Time) (int, error) {
+	ret := _m.Called(now)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) (int, error)); ok {
+		return rf(now)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) int); ok {
+		r0 = rf(now)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByUID provides a mock function with given fields: uid
+func (_m *HoldRepositoryI) GetByUID(uid string) (*models.Hold, error) {
+	ret := _m.Called(uid)
+
+	var r0 *models.Hold
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.Hold, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Hold); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Hold)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Void provides a mock function with given fields: uid
+func (_m *HoldRepositoryI) Void(uid string) (*models.Hold, error) {
+	ret := _m.Called(uid)
+
+	var r0 *models.Hold
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.Hold, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Hold); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Hold)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewHoldRepositoryI creates a new instance of HoldRepositoryI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewHoldRepositoryI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *HoldRepositoryI {
+	mock := &HoldRepositoryI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/internal/outbox"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pgHoldRepo struct {
+	Logger logger.Logger
+	DB     *gorm.DB
+}
+
+func New(logger logger.Logger, db *gorm.DB) repository.HoldRepositoryI {
+	return &pgHoldRepo{
+		Logger: logger,
+		DB:     db,
+	}
+}
+
+// Create reserves h.Amount on the wallet and stores the hold in one transaction.
+func (pr *pgHoldRepo) Create(h *models.Hold) error {
+	err := dbtx.Run(pr.DB, "hold.create", func(tx *gorm.DB) error {
+		// A failed attempt leaves the id of its insert behind.
+		h.ID = 0
+
+		var w models.Wallet
+		reserve := func() (bool, error) {
+			res := tx.Model(&w).Clauses(clause.Returning{}).
+				Where("uid = ? AND status = ? AND amount - held >= ?", h.WalletUID, models.WalletStatusActive, h.Amount).
+				Updates(map[string]interface{}{"held": gorm.Expr("held + ?", h.Amount), "version": gorm.Expr("version + 1")})
+			return res.RowsAffected > 0, res.Error
+		}
+
+		reserved, err := reserve()
+		if err != nil {
+			return err
+		}
+
+		// Holds reserve the balance of the wallet row, the shards of a sharded
+		// wallet are swept into it first.
+		if !reserved {
+			swept, err := walletPg.SweepIfSharded(tx, h.WalletUID)
+			if err != nil {
+				return err
+			}
+			if swept {
+				if reserved, err = reserve(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if !reserved {
+			return walletPg.Rejection(tx, h.WalletUID)
+		}
+
+		if err = tx.Create(h).Error; err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.HoldEvent(models.EventHoldCreated, h, w.OwnerID))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgHoldRepo.Create error")
+	}
+
+	return nil
+}
+
+func (pr *pgHoldRepo) GetByUID(uid string) (*models.Hold, error) {
+	var h models.Hold
+	tx := pr.DB.Where("uid = ?", uid).Take(&h)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgHoldRepo.GetByUID error")
+	}
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgHoldRepo.GetByUID error")
+	}
+
+	return &h, nil
+}
+
+// Capture charges amount from the wallet and releases the whole reservation.
+// The uncaptured remainder of the hold becomes available again. The charge is
+// recorded as a CAPTURE ledger entry. Like any other debit it needs an active
+// wallet, the hold of a frozen, closed or deleted wallet stays active.
+func (pr *pgHoldRepo) Capture(uid string, amount int, now time.Time) (*models.Hold, error) {
+	var h models.Hold
+	err := dbtx.Run(pr.DB, "hold.capture", func(tx *gorm.DB) error {
+		h = models.Hold{}
+		res := tx.Model(&h).Clauses(clause.Returning{}).
+			Where("uid = ? AND status = ? AND expires_at > ? AND amount >= ?", uid, models.HoldStatusActive, now, amount).
+			Updates(map[string]interface{}{"status": models.HoldStatusCaptured, "captured": amount})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return pr.explain(tx, uid, amount, now)
+		}
+
+		var w models.Wallet
+		res = tx.Model(&w).Clauses(clause.Returning{}).
+			Where("uid = ? AND status = ?", h.WalletUID, models.WalletStatusActive).
+			Updates(map[string]interface{}{
+				"amount":  gorm.Expr("amount - ?", amount),
+				"held":    gorm.Expr("held - ?", h.Amount),
+				"version": gorm.Expr("version + 1"),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return walletPg.Rejection(tx, h.WalletUID)
+		}
+
+		if err := walletPg.AddShards(tx, &w); err != nil {
+			return err
+		}
+
+		t := &models.Transaction{
+			UID:       uuid.NewString(),
+			WalletUID: h.WalletUID,
+			Type:      models.TransactionTypeCapture,
+			Amount:    -amount,
+			CreatedAt: now,
+		}
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.HoldEvent(models.EventHoldCaptured, &h, w.OwnerID), outbox.BalanceChanged(t, &w))
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgHoldRepo.Capture error")
+	}
+
+	return &h, nil
+}
+
+// Void releases the reservation without a charge. Releasing moves no money, so
+// it is done whatever the status of the wallet, deleted wallets included.
+func (pr *pgHoldRepo) Void(uid string) (*models.Hold, error) {
+	var h models.Hold
+	err := dbtx.Run(pr.DB, "hold.void", func(tx *gorm.DB) error {
+		h = models.Hold{}
+		res := tx.Model(&h).Clauses(clause.Returning{}).
+			Where("uid = ? AND status = ?", uid, models.HoldStatusActive).
+			Update("status", models.HoldStatusVoided)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return pr.explain(tx, uid, 0, time.Time{})
+		}
+
+		ownerID, err := release(tx, &h)
+		if err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.HoldEvent(models.EventHoldVoided, &h, ownerID))
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgHoldRepo.Void error")
+	}
+
+	return &h, nil
+}
+
+// Expire releases every active hold whose TTL has passed and returns their
+// number. Like Void it releases the holds of wallets in any status.
+func (pr *pgHoldRepo) Expire(now time.Time) (int, error) {
+	var holds []models.Hold
+	err := dbtx.Run(pr.DB, "hold.expire", func(tx *gorm.DB) error {
+		holds = nil
+		res := tx.Model(&holds).Clauses(clause.Returning{}).
+			Where("status = ? AND expires_at <= ?", models.HoldStatusActive, now).
+			Update("status", models.HoldStatusExpired)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		events := make([]*models.OutboxEvent, 0, len(holds))
+		for i := range holds {
+			ownerID, err := release(tx, &holds[i])
+			if err != nil {
+				return err
+			}
+
+			events = append(events, outbox.HoldEvent(models.EventHoldExpired, &holds[i], ownerID))
+		}
+
+		return outbox.Record(tx, events...)
+	})
+
+	if err != nil {
+		return 0, errors.Wrap(err, "pgHoldRepo.Expire error")
+	}
+
+	return len(holds), nil
+}
+
+// release returns the reserved amount of h to the available balance of its
+// wallet and returns the owner of the wallet. A hold without a wallet row is
+// reported as ErrNotFound.
+func release(tx *gorm.DB, h *models.Hold) (int, error) {
+	var w models.Wallet
+	res := tx.Unscoped().Model(&w).Clauses(clause.Returning{}).Where("uid = ?", h.WalletUID).
+		Updates(map[string]interface{}{"held": gorm.Expr("held - ?", h.Amount), "version": gorm.Expr("version + 1")})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		return 0, models.ErrNotFound
+	}
+
+	return w.OwnerID, nil
+}
+
+// explain finds out why a conditional update of an active hold matched no rows.
+func (pr *pgHoldRepo) explain(tx *gorm.DB, uid string, amount int, now time.Time) error {
+	var h models.Hold
+	res := tx.Where("uid = ?", uid).Take(&h)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return models.ErrNotFound
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if h.Status != models.HoldStatusActive || !now.IsZero() && !h.ExpiresAt.After(now) {
+		return models.ErrHoldNotActive
+	}
+	if amount > h.Amount {
+		return models.ErrCaptureExceedsHold
+	}
+
+	return models.ErrHoldNotActive
+}
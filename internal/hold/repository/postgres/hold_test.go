@@ -0,0 +1,217 @@
+package postgres
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	holdRep "github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type HoldRepoTestSuite struct {
+	suite.Suite
+	db          *sql.DB
+	gormDB      *gorm.DB
+	mock        sqlmock.Sqlmock
+	repo        holdRep.HoldRepositoryI
+	holdBuilder *testBuilders.HoldBuilder
+}
+
+func TestHoldRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(HoldRepoTestSuite))
+}
+
+func (s *HoldRepoTestSuite) BeforeEach(t provider.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("error while creating sql mock")
+	}
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal("error gorm open")
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.gormDB = gormDB
+	s.mock = mock
+
+	s.repo = New(logger, gormDB)
+	s.holdBuilder = testBuilders.NewHoldBuilder()
+}
+
+func (s *HoldRepoTestSuite) AfterEach(t provider.T) {
+	err := s.mock.ExpectationsWereMet()
+	t.Assert().NoError(err)
+	s.db.Close()
+}
+
+func (s *HoldRepoTestSuite) expectOutboxEvent(eventType string, walletUID string, ownerID int) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "outbox_event" ("uid","type","wallet_uid","owner_id","payload","created_at","published_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(sqlmock.AnyArg(), eventType, walletUID, ownerID, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+func (s *HoldRepoTestSuite) TestCreateHold(t provider.T) {
+	expiresAt := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	hold := s.holdBuilder.
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(expiresAt).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "held"=held + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND amount - held >= $4) AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(hold.Amount, hold.WalletUID, models.WalletStatusActive, hold.Amount).
+		WillReturnRows(sqlmock.NewRows([]string{"uid", "owner_id"}).AddRow(hold.WalletUID, 7))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "hold" ("uid","wallet_uid","amount","captured","status","expires_at","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(hold.UID, hold.WalletUID, hold.Amount, 0, hold.Status, expiresAt, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	s.expectOutboxEvent(models.EventHoldCreated, hold.WalletUID, 7)
+
+	s.mock.ExpectCommit()
+
+	err := s.repo.Create(&hold)
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, hold.ID)
+}
+
+func (s *HoldRepoTestSuite) TestCreateHoldInsufficientFunds(t provider.T) {
+	hold := s.holdBuilder.
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "held"=held + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND amount - held >= $4) AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(hold.Amount, hold.WalletUID, models.WalletStatusActive, hold.Amount).
+		WillReturnRows(sqlmock.NewRows([]string{"uid", "owner_id"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT "shards" FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(hold.WalletUID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"shards"}).AddRow(0))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(hold.WalletUID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held", "status"}).AddRow(1, hold.WalletUID, 20, 0, models.WalletStatusActive))
+
+	s.mock.ExpectRollback()
+
+	err := s.repo.Create(&hold)
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
+
+func (s *HoldRepoTestSuite) TestGetByUID(t provider.T) {
+	hold := s.holdBuilder.
+		WithID(1).
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		WithStatus(models.HoldStatusActive).
+		Build()
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "amount", "status"}).
+		AddRow(hold.ID, hold.UID, hold.WalletUID, hold.Amount, hold.Status)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "hold" WHERE uid = $1 LIMIT $2`)).
+		WithArgs(hold.UID, 1).
+		WillReturnRows(rows)
+
+	resHold, err := s.repo.GetByUID(hold.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(hold, *resHold)
+}
+
+func (s *HoldRepoTestSuite) TestVoidHold(t provider.T) {
+	hold := s.holdBuilder.
+		WithID(1).
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		WithStatus(models.HoldStatusVoided).
+		Build()
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "amount", "status"}).
+		AddRow(hold.ID, hold.UID, hold.WalletUID, hold.Amount, hold.Status)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "hold" SET "status"=$1 WHERE uid = $2 AND status = $3 RETURNING *`)).
+		WithArgs(models.HoldStatusVoided, hold.UID, models.HoldStatusActive).
+		WillReturnRows(rows)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "held"=held - $1,"version"=version + 1 WHERE uid = $2 RETURNING *`)).
+		WithArgs(hold.Amount, hold.WalletUID).
+		WillReturnRows(sqlmock.NewRows([]string{"uid", "owner_id"}).AddRow(hold.WalletUID, 7))
+
+	s.expectOutboxEvent(models.EventHoldVoided, hold.WalletUID, 7)
+
+	s.mock.ExpectCommit()
+
+	resHold, err := s.repo.Void(hold.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(hold, *resHold)
+}
+
+func (s *HoldRepoTestSuite) TestCaptureExpiredHold(t provider.T) {
+	now := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	hold := s.holdBuilder.
+		WithID(1).
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(now.Add(-time.Minute)).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "hold" SET "captured"=$1,"status"=$2 WHERE uid = $3 AND status = $4 AND expires_at > $5 AND amount >= $6 RETURNING *`)).
+		WithArgs(10, models.HoldStatusCaptured, hold.UID, models.HoldStatusActive, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "hold" WHERE uid = $1 LIMIT $2`)).
+		WithArgs(hold.UID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "amount", "status", "expires_at"}).
+			AddRow(hold.ID, hold.UID, hold.WalletUID, hold.Amount, hold.Status, hold.ExpiresAt))
+
+	s.mock.ExpectRollback()
+
+	_, err := s.repo.Capture(hold.UID, 10, now)
+	t.Assert().ErrorIs(err, models.ErrHoldNotActive)
+}
@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	holdRep "github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/gorm"
+)
+
+// HoldSQLiteTestSuite runs the repository against a real SQLite database, which
+// stores timestamps as text, to check the TTL comparisons.
+type HoldSQLiteTestSuite struct {
+	suite.Suite
+	db          *gorm.DB
+	repo        holdRep.HoldRepositoryI
+	holdBuilder *testBuilders.HoldBuilder
+	now         time.Time
+}
+
+func TestHoldSQLiteSuite(t *testing.T) {
+	suite.RunSuite(t, new(HoldSQLiteTestSuite))
+}
+
+func (s *HoldSQLiteTestSuite) BeforeEach(t provider.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal("error while opening sqlite", err)
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.repo = New(logger, db)
+	s.holdBuilder = testBuilders.NewHoldBuilder()
+	s.now = time.Now()
+
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithAmount(100).
+		WithStatus(models.WalletStatusActive).
+		Build()
+	t.Require().NoError(db.Create(&wallet).Error)
+
+	hold := s.holdBuilder.
+		WithUID("hold").
+		WithWalletUID(wallet.UID).
+		WithAmount(60).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(s.now.Add(time.Hour)).
+		Build()
+	t.Require().NoError(s.repo.Create(&hold))
+}
+
+func (s *HoldSQLiteTestSuite) wallet(t provider.T) models.Wallet {
+	var wallet models.Wallet
+	t.Require().NoError(s.db.Where("uid = ?", "wallet").Take(&wallet).Error)
+
+	return wallet
+}
+
+func (s *HoldSQLiteTestSuite) TestCreateHold(t provider.T) {
+	t.Assert().Equal(60, s.wallet(t).Held)
+
+	tooBig := s.holdBuilder.WithUID("other").WithAmount(50).Build()
+	err := s.repo.Create(&tooBig)
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
+
+func (s *HoldSQLiteTestSuite) TestCreateHoldShardedWallet(t provider.T) {
+	var logger logger.Logger
+	wallets := walletPg.New(logger, s.db)
+
+	_, err := wallets.SetShards("wallet", 2)
+	t.Require().NoError(err)
+
+	deposit := testBuilders.NewTransactionBuilder().
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(50).
+		Build()
+	_, err = wallets.ChangeAmount(&deposit)
+	t.Require().NoError(err)
+	t.Require().Equal(100, s.wallet(t).Amount)
+
+	hold := s.holdBuilder.WithUID("other").WithAmount(50).Build()
+	t.Require().NoError(s.repo.Create(&hold))
+
+	wallet := s.wallet(t)
+	t.Assert().Equal(150, wallet.Amount)
+	t.Assert().Equal(110, wallet.Held)
+}
+
+func (s *HoldSQLiteTestSuite) TestCaptureHold(t provider.T) {
+	_, err := s.repo.Capture("hold", 10, s.now.Add(2*time.Hour))
+	t.Assert().ErrorIs(err, models.ErrHoldNotActive)
+
+	_, err = s.repo.Capture("hold", 70, s.now)
+	t.Assert().ErrorIs(err, models.ErrCaptureExceedsHold)
+
+	hold, err := s.repo.Capture("hold", 40, s.now)
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.HoldStatusCaptured, hold.Status)
+
+	wallet := s.wallet(t)
+	t.Assert().Equal(60, wallet.Amount)
+	t.Assert().Equal(0, wallet.Held)
+}
+
+func (s *HoldSQLiteTestSuite) TestExpireHolds(t provider.T) {
+	expired, err := s.repo.Expire(s.now)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, expired)
+
+	expired, err = s.repo.Expire(s.now.Add(time.Hour))
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, expired)
+
+	t.Assert().Equal(0, s.wallet(t).Held)
+}
+
+func (s *HoldSQLiteTestSuite) TestCaptureFrozenWallet(t provider.T) {
+	t.Require().NoError(s.db.Model(&models.Wallet{}).Where("uid = ?", "wallet").Update("status", models.WalletStatusFrozen).Error)
+
+	_, err := s.repo.Capture("hold", 40, s.now)
+	t.Assert().ErrorIs(err, models.ErrWalletFrozen)
+
+	hold, err := s.repo.GetByUID("hold")
+	t.Require().NoError(err)
+	t.Assert().Equal(models.HoldStatusActive, hold.Status)
+
+	var entries int64
+	t.Require().NoError(s.db.Model(&models.Transaction{}).Count(&entries).Error)
+	t.Assert().Zero(entries)
+
+	// The reservation is released even though the wallet can't be debited.
+	_, err = s.repo.Void("hold")
+	t.Assert().NoError(err)
+
+	wallet := s.wallet(t)
+	t.Assert().Equal(100, wallet.Amount)
+	t.Assert().Equal(0, wallet.Held)
+}
+
+func (s *HoldSQLiteTestSuite) TestDeletedWallet(t provider.T) {
+	t.Require().NoError(s.db.Where("uid = ?", "wallet").Delete(&models.Wallet{}).Error)
+
+	_, err := s.repo.Capture("hold", 40, s.now)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	expired, err := s.repo.Expire(s.now.Add(time.Hour))
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, expired)
+
+	var wallet models.Wallet
+	t.Require().NoError(s.db.Unscoped().Where("uid = ?", "wallet").Take(&wallet).Error)
+	t.Assert().Equal(0, wallet.Held)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+type HoldRepositoryI interface {
+	Create(h *models.Hold) error
+	GetByUID(uid string) (*models.Hold, error)
+	Capture(uid string, amount int, now time.Time) (*models.Hold, error)
+	Void(uid string) (*models.Hold, error)
+	Expire(now time.Time) (int, error)
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"time"
+
+	holdRep "github.com/Davmie/javaCode/internal/hold/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DefaultHoldTTL is used when a hold is created without an explicit TTL.
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+type HoldUseCaseI interface {
+	Create(walletUID string, amount int, ttl time.Duration) (*models.Hold, error)
+	GetByUID(uid string) (*models.Hold, error)
+	Capture(uid string, amount int) (*models.Hold, error)
+	Void(uid string) (*models.Hold, error)
+	ExpireHolds() (int, error)
+}
+
+type holdUseCase struct {
+	holdRepository holdRep.HoldRepositoryI
+	now            func() time.Time
+}
+
+func New(hRep holdRep.HoldRepositoryI) HoldUseCaseI {
+	return &holdUseCase{
+		holdRepository: hRep,
+		now:            time.Now,
+	}
+}
+
+func (hUC *holdUseCase) Create(walletUID string, amount int, ttl time.Duration) (*models.Hold, error) {
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	now := hUC.now()
+	h := &models.Hold{
+		UID:       uuid.NewString(),
+		WalletUID: walletUID,
+		Amount:    amount,
+		Status:    models.HoldStatusActive,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	err := hUC.holdRepository.Create(h)
+	if err != nil {
+		return nil, errors.Wrap(err, "holdUseCase.Create error")
+	}
+
+	return h, nil
+}
+
+func (hUC *holdUseCase) GetByUID(uid string) (*models.Hold, error) {
+	h, err := hUC.holdRepository.GetByUID(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "holdUseCase.GetByUID error")
+	}
+
+	return h, nil
+}
+
+// Capture charges amount from the hold, zero means the whole held amount.
+func (hUC *holdUseCase) Capture(uid string, amount int) (*models.Hold, error) {
+	if amount == 0 {
+		h, err := hUC.holdRepository.GetByUID(uid)
+		if err != nil {
+			return nil, errors.Wrap(err, "holdUseCase.Capture error: Hold not found")
+		}
+		amount = h.Amount
+	}
+
+	h, err := hUC.holdRepository.Capture(uid, amount, hUC.now())
+	if err != nil {
+		return nil, errors.Wrap(err, "holdUseCase.Capture error")
+	}
+
+	return h, nil
+}
+
+func (hUC *holdUseCase) Void(uid string) (*models.Hold, error) {
+	h, err := hUC.holdRepository.Void(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "holdUseCase.Void error")
+	}
+
+	return h, nil
+}
+
+func (hUC *holdUseCase) ExpireHolds() (int, error) {
+	n, err := hUC.holdRepository.Expire(hUC.now())
+	if err != nil {
+		return 0, errors.Wrap(err, "holdUseCase.ExpireHolds error")
+	}
+
+	return n, nil
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	holdMocks "github.com/Davmie/javaCode/internal/hold/repository/mocks"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type HoldTestSuite struct {
+	suite.Suite
+	uc           *holdUseCase
+	holdRepoMock *holdMocks.HoldRepositoryI
+	holdBuilder  *testBuilders.HoldBuilder
+	now          time.Time
+}
+
+func TestHoldTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(HoldTestSuite))
+}
+
+func (s *HoldTestSuite) BeforeEach(t provider.T) {
+	s.holdRepoMock = holdMocks.NewHoldRepositoryI(t)
+	s.now = time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	s.uc = &holdUseCase{
+		holdRepository: s.holdRepoMock,
+		now:            func() time.Time { return s.now },
+	}
+	s.holdBuilder = testBuilders.NewHoldBuilder()
+}
+
+func (s *HoldTestSuite) TestCreateHold(t provider.T) {
+	s.holdRepoMock.On("Create", mock.MatchedBy(func(h *models.Hold) bool {
+		return h.WalletUID == "wallet" && h.Amount == 20 && h.Status == models.HoldStatusActive &&
+			h.ExpiresAt.Equal(s.now.Add(time.Minute)) && h.UID != ""
+	})).Return(nil)
+
+	hold, err := s.uc.Create("wallet", 20, time.Minute)
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(20, hold.Amount)
+}
+
+func (s *HoldTestSuite) TestCreateHoldDefaultTTL(t provider.T) {
+	s.holdRepoMock.On("Create", mock.MatchedBy(func(h *models.Hold) bool {
+		return h.ExpiresAt.Equal(s.now.Add(DefaultHoldTTL))
+	})).Return(nil)
+
+	_, err := s.uc.Create("wallet", 20, 0)
+
+	t.Assert().NoError(err)
+}
+
+func (s *HoldTestSuite) TestCreateHoldInsufficientFunds(t provider.T) {
+	s.holdRepoMock.On("Create", mock.Anything).Return(errors.Wrap(models.ErrInsufficientFunds, "pgHoldRepo.Create error"))
+
+	_, err := s.uc.Create("wallet", 20, time.Minute)
+
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
+
+func (s *HoldTestSuite) TestCaptureHold(t provider.T) {
+	hold := s.holdBuilder.
+		WithID(1).
+		WithUID("hold").
+		WithWalletUID("wallet").
+		WithAmount(20).
+		WithStatus(models.HoldStatusActive).
+		Build()
+
+	captured := hold
+	captured.Status = models.HoldStatusCaptured
+
+	s.holdRepoMock.On("GetByUID", hold.UID).Return(&hold, nil)
+	s.holdRepoMock.On("Capture", hold.UID, 20, s.now).Return(&captured, nil)
+	s.holdRepoMock.On("Capture", hold.UID, 5, s.now).Return(&captured, nil)
+	s.holdRepoMock.On("Capture", hold.UID, 30, s.now).Return(nil, errors.Wrap(models.ErrCaptureExceedsHold, "pgHoldRepo.Capture error"))
+
+	cases := map[string]struct {
+		Amount int
+		Error  error
+	}{
+		"full": {
+			Amount: 0,
+			Error:  nil,
+		},
+		"partial": {
+			Amount: 5,
+			Error:  nil,
+		},
+		"exceeds hold": {
+			Amount: 30,
+			Error:  models.ErrCaptureExceedsHold,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := s.uc.Capture(hold.UID, test.Amount)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *HoldTestSuite) TestVoidHold(t provider.T) {
+	hold := s.holdBuilder.
+		WithID(1).
+		WithUID("hold").
+		WithStatus(models.HoldStatusVoided).
+		Build()
+
+	s.holdRepoMock.On("Void", hold.UID).Return(&hold, nil)
+	s.holdRepoMock.On("Void", "voided").Return(nil, errors.Wrap(models.ErrHoldNotActive, "pgHoldRepo.Void error"))
+
+	result, err := s.uc.Void(hold.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.HoldStatusVoided, result.Status)
+
+	_, err = s.uc.Void("voided")
+	t.Assert().ErrorIs(err, models.ErrHoldNotActive)
+}
+
+func (s *HoldTestSuite) TestExpireHolds(t provider.T) {
+	s.holdRepoMock.On("Expire", s.now).Return(3, nil)
+
+	n, err := s.uc.ExpireHolds()
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(3, n)
+}
@@ -0,0 +1,96 @@
+package memstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+// Store keeps wallets, their ledger, holds, status history, outbox and webhooks
+// in memory. It is shared by the memory repositories of all entities so that
+// operations touching several of them (a capture changes a hold, a wallet and
+// the ledger) are atomic.
+// Repositories must hold the lock while they access the fields.
+type Store struct {
+	sync.Mutex
+
+	Wallets       []*models.Wallet
+	Transactions  []*models.Transaction
+	Holds         []*models.Hold
+	StatusChanges []*models.WalletStatusChange
+	Events        []*models.OutboxEvent
+
+	Webhooks          []*models.Webhook
+	WebhookDeliveries []*models.WebhookDelivery
+
+	lastIDs map[string]int
+}
+
+func New() *Store {
+	return &Store{
+		lastIDs: make(map[string]int),
+	}
+}
+
+// NextID returns a new identifier for the table, like a serial column does.
+func (s *Store) NextID(table string) int {
+	s.lastIDs[table]++
+	return s.lastIDs[table]
+}
+
+// WalletByUID returns the stored wallet with the given uid. Soft deleted wallets
+// are only returned when includeDeleted is set.
+func (s *Store) WalletByUID(uid string, includeDeleted bool) *models.Wallet {
+	for _, w := range s.Wallets {
+		if w.UID == uid && (includeDeleted || !w.DeletedAt.Valid) {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// ApplyDelta changes the wallet balance by delta with the same rules as the
+// postgres repository: only active wallets are changed, and withdrawals must fit
+// into the available balance. A copy of the changed wallet is returned.
+func (s *Store) ApplyDelta(uid string, delta int) (*models.Wallet, error) {
+	w := s.WalletByUID(uid, false)
+	if w == nil {
+		return nil, models.ErrNotFound
+	}
+
+	switch {
+	case w.Status == models.WalletStatusFrozen:
+		return nil, models.ErrWalletFrozen
+	case w.Status == models.WalletStatusClosed:
+		return nil, models.ErrWalletClosed
+	case delta < 0 && w.Available()+delta < 0:
+		return nil, models.ErrInsufficientFunds
+	}
+
+	w.Amount += delta
+	w.Version++
+
+	res := *w
+	return &res, nil
+}
+
+// AddTransaction stores a copy of t, filling in the id and creation time.
+func (s *Store) AddTransaction(t *models.Transaction) {
+	t.ID = s.NextID(t.TableName())
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+
+	stored := *t
+	s.Transactions = append(s.Transactions, &stored)
+}
+
+// AddEvents appends the events to the outbox, filling in their ids.
+func (s *Store) AddEvents(events ...*models.OutboxEvent) {
+	for _, e := range events {
+		e.ID = s.NextID(e.TableName())
+		s.Events = append(s.Events, e)
+	}
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Models lists every table of the service, parents before the tables referring
+// to them. The tags of the models are the only description of the schema: types,
+// defaults, foreign keys, checks and indexes.
+var Models = []interface{}{
+	&models.Wallet{},
+	&models.WalletShard{},
+	&models.WalletStatusChange{},
+	&models.Hold{},
+	&models.Transaction{},
+	&models.OutboxEvent{},
+	&models.Webhook{},
+	&models.WebhookDelivery{},
+}
+
+// walletChangedTrigger notifies the wallet_changed channel with the uid of every
+// changed wallet row or shard. Other instances evict the wallet from their cache.
+const walletChangedTrigger = `
+CREATE OR REPLACE FUNCTION notify_wallet_changed() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('wallet_changed', OLD.uid::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS wallet_changed ON wallet;
+CREATE TRIGGER wallet_changed AFTER UPDATE OR DELETE ON wallet
+    FOR EACH ROW EXECUTE FUNCTION notify_wallet_changed();
+
+CREATE OR REPLACE FUNCTION notify_wallet_shard_changed() RETURNS trigger AS
+$$
+BEGIN
+    PERFORM pg_notify('wallet_changed', OLD.wallet_uid::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS wallet_shard_changed ON wallet_shard;
+CREATE TRIGGER wallet_shard_changed AFTER UPDATE OR DELETE ON wallet_shard
+    FOR EACH ROW EXECUTE FUNCTION notify_wallet_shard_changed();
+`
+
+// webhookScopes sets the scope of the webhooks created before webhooks had one.
+const webhookScopes = `UPDATE webhook SET scope = CASE WHEN wallet_uid = '' THEN 'ALL' ELSE 'WALLET' END WHERE scope = ''`
+
+// Migrate creates the tables and indexes described by Models, or adds what is
+// missing from them, and on Postgres the triggers. It is idempotent and runs
+// every time the database is opened.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(Models...); err != nil {
+		return errors.Wrap(err, "migrations.Migrate error")
+	}
+
+	if err := db.Exec(webhookScopes).Error; err != nil {
+		return errors.Wrap(err, "migrations.Migrate error")
+	}
+
+	if db.Dialector.Name() == "postgres" {
+		if err := db.Exec(walletChangedTrigger).Error; err != nil {
+			return errors.Wrap(err, "migrations.Migrate error")
+		}
+	}
+
+	return nil
+}
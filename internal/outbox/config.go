@@ -0,0 +1,25 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// Open builds the publisher chosen in cfg.
+func Open(ctx context.Context, cfg *config.Config, logger logger.Logger) (Publisher, error) {
+	switch cfg.Events {
+	case config.EventsLog:
+		return LogPublisher{Logger: logger}, nil
+	case config.EventsKafka:
+		return NewKafka(cfg.KafkaBrokers, cfg.KafkaTopic)
+	case config.EventsNATS:
+		return NewNATS(ctx, cfg.NATSURL, cfg.NATSStream, cfg.NATSSubject)
+	case config.EventsFile:
+		return NewFile(cfg.EventsFile)
+	default:
+		return nil, errors.Errorf("outbox.Open error: unknown events sink %q", cfg.Events)
+	}
+}
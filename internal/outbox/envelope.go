@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+)
+
+// SchemaVersion is the version of Envelope written by this service. Fields are
+// only added within a version, a consumer skips the versions it doesn't know.
+// Version 2 renamed sequence to globalSequence.
+const SchemaVersion = 2
+
+var ErrUnknownSchema = errors.New("unknown event schema version")
+
+// Envelope is the published form of an outbox event, the same for every sink.
+// GlobalSequence is the order in which the events of all the wallets were
+// recorded and only a global ordering key: it has gaps, and it is not a
+// sequence of the wallet. The events of a wallet are recorded in the order of
+// its changes, except the deposits into the shards of a sharded wallet, which
+// don't lock the wallet and may be published after events with higher values.
+type Envelope struct {
+	SchemaVersion  int             `json:"schemaVersion"`
+	EventID        string          `json:"eventId"`
+	Type           string          `json:"type"`
+	WalletUID      string          `json:"walletId"`
+	GlobalSequence int             `json:"globalSequence"`
+	OccurredAt     time.Time       `json:"occurredAt"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func NewEnvelope(e *models.OutboxEvent) *Envelope {
+	return &Envelope{
+		SchemaVersion:  SchemaVersion,
+		EventID:        e.UID,
+		Type:           e.Type,
+		WalletUID:      e.WalletUID,
+		GlobalSequence: e.ID,
+		OccurredAt:     e.CreatedAt,
+		Payload:        e.Payload,
+	}
+}
+
+// Encode returns the JSON of the envelope of e.
+func Encode(e *models.OutboxEvent) ([]byte, error) {
+	data, err := json.Marshal(NewEnvelope(e))
+	if err != nil {
+		return nil, errors.Wrap(err, "outbox.Encode error")
+	}
+
+	return data, nil
+}
+
+// Decode parses an envelope written by Encode. Envelopes of other schema
+// versions are rejected with ErrUnknownSchema.
+func Decode(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, errors.Wrap(err, "outbox.Decode error")
+	}
+
+	if env.SchemaVersion != SchemaVersion {
+		return nil, errors.Wrapf(ErrUnknownSchema, "outbox.Decode error: version %d", env.SchemaVersion)
+	}
+
+	return &env, nil
+}
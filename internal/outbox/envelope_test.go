@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+// testEvent returns a stored event of the wallet with the given id.
+func testEvent(id int, walletUID string, eventType string) *models.OutboxEvent {
+	return &models.OutboxEvent{
+		ID:        id,
+		UID:       fmt.Sprintf("event-%d", id),
+		Type:      eventType,
+		WalletUID: walletUID,
+		Payload:   json.RawMessage(fmt.Sprintf(`{"id":%d}`, id)),
+		CreatedAt: time.Date(2024, 11, 9, 12, 0, id, 0, time.UTC),
+	}
+}
+
+type EnvelopeTestSuite struct {
+	suite.Suite
+}
+
+func TestEnvelopeSuite(t *testing.T) {
+	suite.RunSuite(t, new(EnvelopeTestSuite))
+}
+
+func (s *EnvelopeTestSuite) TestEncodeDecode(t provider.T) {
+	e := testEvent(7, "wallet", models.EventWalletBalanceChanged)
+
+	data, err := Encode(e)
+	t.Require().NoError(err)
+
+	env, err := Decode(data)
+	t.Require().NoError(err)
+	t.Assert().Equal(&Envelope{
+		SchemaVersion:  SchemaVersion,
+		EventID:        e.UID,
+		Type:           e.Type,
+		WalletUID:      e.WalletUID,
+		GlobalSequence: 7,
+		OccurredAt:     e.CreatedAt,
+		Payload:        e.Payload,
+	}, env)
+}
+
+func (s *EnvelopeTestSuite) TestDecodeUnknownVersion(t provider.T) {
+	_, err := Decode([]byte(`{"schemaVersion":1,"eventId":"event-1","sequence":1}`))
+	t.Assert().ErrorIs(err, ErrUnknownSchema)
+}
+
+func (s *EnvelopeTestSuite) TestMemoryPublisher(t provider.T) {
+	mp := NewMemory()
+	for i := 1; i <= 2; i++ {
+		t.Require().NoError(mp.Publish(context.Background(), testEvent(i, "wallet", models.EventWalletUpdated)))
+	}
+
+	envelopes := mp.Envelopes()
+	t.Require().Len(envelopes, 2)
+	t.Assert().Equal(1, envelopes[0].GlobalSequence)
+	t.Assert().Equal(2, envelopes[1].GlobalSequence)
+}
+
+func (s *EnvelopeTestSuite) TestBalanceChangedOfShardedWallet(t provider.T) {
+	transaction := &models.Transaction{UID: "deposit", WalletUID: "wallet", Amount: 10}
+
+	var change map[string]json.RawMessage
+	e := BalanceChanged(transaction, &models.Wallet{UID: "wallet", Amount: 10})
+	t.Require().NoError(json.Unmarshal(e.Payload, &change))
+	t.Assert().Contains(change, "wallet")
+
+	change = nil
+	e = BalanceChanged(transaction, &models.Wallet{UID: "wallet", Amount: 10, Shards: 4})
+	t.Require().NoError(json.Unmarshal(e.Payload, &change))
+	t.Assert().Contains(change, "transaction")
+	t.Assert().NotContains(change, "wallet")
+}
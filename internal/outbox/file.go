@@ -0,0 +1,55 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+)
+
+type filePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFile returns a publisher appending the envelopes to the file at path, one
+// JSON document per line. An event counts as published once its line is
+// synced to disk.
+func NewFile(path string) (Publisher, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, errors.Wrap(err, "outbox.NewFile error")
+	}
+
+	return &filePublisher{file: file}, nil
+}
+
+func (fp *filePublisher) Publish(_ context.Context, e *models.OutboxEvent) error {
+	data, err := Encode(e)
+	if err != nil {
+		return errors.Wrap(err, "filePublisher.Publish error")
+	}
+
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	if _, err = fp.file.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "filePublisher.Publish error")
+	}
+
+	if err = fp.file.Sync(); err != nil {
+		return errors.Wrap(err, "filePublisher.Publish error")
+	}
+
+	return nil
+}
+
+func (fp *filePublisher) Close() error {
+	if err := fp.file.Close(); err != nil {
+		return errors.Wrap(err, "filePublisher.Close error")
+	}
+
+	return nil
+}
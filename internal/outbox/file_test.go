@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type FilePublisherTestSuite struct {
+	suite.Suite
+	dir  string
+	path string
+}
+
+func TestFilePublisherSuite(t *testing.T) {
+	suite.RunSuite(t, new(FilePublisherTestSuite))
+}
+
+func (s *FilePublisherTestSuite) BeforeEach(t provider.T) {
+	dir, err := os.MkdirTemp("", "outbox")
+	t.Require().NoError(err)
+
+	s.dir = dir
+	s.path = filepath.Join(dir, "events.ndjson")
+}
+
+func (s *FilePublisherTestSuite) AfterEach(t provider.T) {
+	os.RemoveAll(s.dir)
+}
+
+func (s *FilePublisherTestSuite) publish(t provider.T, events ...*models.OutboxEvent) {
+	fp, err := NewFile(s.path)
+	t.Require().NoError(err)
+
+	for _, e := range events {
+		t.Require().NoError(fp.Publish(context.Background(), e))
+	}
+	t.Require().NoError(fp.Close())
+}
+
+func (s *FilePublisherTestSuite) TestAppend(t provider.T) {
+	s.publish(t, testEvent(1, "wallet", models.EventWalletCreated), testEvent(2, "wallet", models.EventWalletBalanceChanged))
+	s.publish(t, testEvent(3, "wallet", models.EventWalletBalanceChanged))
+
+	file, err := os.Open(s.path)
+	t.Require().NoError(err)
+	defer file.Close()
+
+	var sequences []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		env, err := Decode(scanner.Bytes())
+		t.Require().NoError(err)
+		sequences = append(sequences, env.GlobalSequence)
+	}
+	t.Require().NoError(scanner.Err())
+
+	t.Assert().Equal([]int{1, 2, 3}, sequences)
+}
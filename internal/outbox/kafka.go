@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type kafkaPublisher struct {
+	client *kgo.Client
+}
+
+// NewKafka returns a publisher producing the envelopes to topic. The records
+// are keyed by the wallet uid, so the events of a wallet share a partition and
+// keep their order.
+func NewKafka(brokers []string, topic string) (Publisher, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(brokers...),
+		kgo.DefaultProduceTopic(topic),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "outbox.NewKafka error")
+	}
+
+	return &kafkaPublisher{client: client}, nil
+}
+
+func (kp *kafkaPublisher) Publish(ctx context.Context, e *models.OutboxEvent) error {
+	data, err := Encode(e)
+	if err != nil {
+		return errors.Wrap(err, "kafkaPublisher.Publish error")
+	}
+
+	record := &kgo.Record{
+		Key:   []byte(e.WalletUID),
+		Value: data,
+		Headers: []kgo.RecordHeader{
+			{Key: "type", Value: []byte(e.Type)},
+			{Key: "schema-version", Value: []byte(strconv.Itoa(SchemaVersion))},
+		},
+	}
+	if err = kp.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return errors.Wrap(err, "kafkaPublisher.Publish error")
+	}
+
+	return nil
+}
+
+func (kp *kafkaPublisher) Close() error {
+	kp.client.Close()
+	return nil
+}
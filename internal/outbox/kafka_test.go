@@ -0,0 +1,82 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const testTopic = "wallet-events"
+
+// KafkaPublisherTestSuite produces to an in-process fake Kafka cluster.
+type KafkaPublisherTestSuite struct {
+	suite.Suite
+	cluster *kfake.Cluster
+}
+
+func TestKafkaPublisherSuite(t *testing.T) {
+	suite.RunSuite(t, new(KafkaPublisherTestSuite))
+}
+
+func (s *KafkaPublisherTestSuite) BeforeEach(t provider.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(4, testTopic))
+	t.Require().NoError(err)
+
+	s.cluster = cluster
+}
+
+func (s *KafkaPublisherTestSuite) AfterEach(t provider.T) {
+	s.cluster.Close()
+}
+
+func (s *KafkaPublisherTestSuite) TestPublish(t provider.T) {
+	events := []*models.OutboxEvent{
+		testEvent(1, "first", models.EventWalletCreated),
+		testEvent(2, "second", models.EventWalletCreated),
+		testEvent(3, "first", models.EventWalletBalanceChanged),
+		testEvent(4, "second", models.EventWalletBalanceChanged),
+		testEvent(5, "first", models.EventWalletBalanceChanged),
+	}
+
+	kp, err := NewKafka(s.cluster.ListenAddrs(), testTopic)
+	t.Require().NoError(err)
+	defer kp.Close()
+
+	for _, e := range events {
+		t.Require().NoError(kp.Publish(context.Background(), e))
+	}
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(s.cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(testTopic),
+	)
+	t.Require().NoError(err)
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sequences := make(map[string][]int)
+	for received := 0; received < len(events); {
+		fetches := consumer.PollFetches(ctx)
+		t.Require().NoError(fetches.Err())
+
+		fetches.EachRecord(func(r *kgo.Record) {
+			env, err := Decode(r.Value)
+			t.Require().NoError(err)
+			t.Assert().Equal(env.WalletUID, string(r.Key))
+			t.Assert().Equal(env.Type, string(r.Headers[0].Value))
+
+			sequences[env.WalletUID] = append(sequences[env.WalletUID], env.GlobalSequence)
+			received++
+		})
+	}
+
+	t.Assert().Equal(map[string][]int{"first": {1, 3, 5}, "second": {2, 4}}, sequences)
+}
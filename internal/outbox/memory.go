@@ -0,0 +1,39 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+// MemoryPublisher keeps the published envelopes in memory, for tests.
+type MemoryPublisher struct {
+	mu        sync.Mutex
+	envelopes []*Envelope
+}
+
+func NewMemory() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (mp *MemoryPublisher) Publish(_ context.Context, e *models.OutboxEvent) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.envelopes = append(mp.envelopes, NewEnvelope(e))
+
+	return nil
+}
+
+// Envelopes returns the envelopes published so far in the order of Publish.
+func (mp *MemoryPublisher) Envelopes() []*Envelope {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	return append([]*Envelope(nil), mp.envelopes...)
+}
+
+func (mp *MemoryPublisher) Close() error {
+	return nil
+}
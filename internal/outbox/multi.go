@@ -0,0 +1,47 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, e *models.OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e *models.OutboxEvent) error {
+	return f(ctx, e)
+}
+
+func (f PublisherFunc) Close() error {
+	return nil
+}
+
+type multiPublisher []Publisher
+
+// Multi publishes every event to each of the publishers in turn. An event
+// failing on one of them is published to all of them again.
+func Multi(publishers ...Publisher) Publisher {
+	return multiPublisher(publishers)
+}
+
+func (mp multiPublisher) Publish(ctx context.Context, e *models.OutboxEvent) error {
+	for _, p := range mp {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mp multiPublisher) Close() error {
+	var first error
+	for _, p := range mp {
+		if err := p.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
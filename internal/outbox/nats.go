@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+)
+
+type natsPublisher struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject string
+}
+
+// NewNATS returns a publisher storing the envelopes in the JetStream stream,
+// which is created if missing. An event of type t goes to subject.t, the event
+// uid is the message id, so the stream drops the duplicates of a retry.
+func NewNATS(ctx context.Context, url string, stream string, subject string) (Publisher, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, errors.Wrap(err, "outbox.NewNATS error")
+	}
+
+	js, err := jetstream.New(conn)
+	if err == nil {
+		_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     stream,
+			Subjects: []string{subject + ".>"},
+		})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, errors.Wrap(err, "outbox.NewNATS error")
+	}
+
+	return &natsPublisher{
+		conn:    conn,
+		js:      js,
+		subject: subject,
+	}, nil
+}
+
+func (np *natsPublisher) Publish(ctx context.Context, e *models.OutboxEvent) error {
+	data, err := Encode(e)
+	if err != nil {
+		return errors.Wrap(err, "natsPublisher.Publish error")
+	}
+
+	msg := nats.NewMsg(np.subject + "." + e.Type)
+	msg.Data = data
+	msg.Header.Set("Schema-Version", strconv.Itoa(SchemaVersion))
+
+	if _, err = np.js.PublishMsg(ctx, msg, jetstream.WithMsgID(e.UID)); err != nil {
+		return errors.Wrap(err, "natsPublisher.Publish error")
+	}
+
+	return nil
+}
+
+func (np *natsPublisher) Close() error {
+	if err := np.conn.Drain(); err != nil {
+		return errors.Wrap(err, "natsPublisher.Close error")
+	}
+
+	return nil
+}
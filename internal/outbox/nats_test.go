@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+// NATSPublisherTestSuite publishes to an embedded NATS server with JetStream.
+type NATSPublisherTestSuite struct {
+	suite.Suite
+	dir    string
+	server *server.Server
+}
+
+func TestNATSPublisherSuite(t *testing.T) {
+	suite.RunSuite(t, new(NATSPublisherTestSuite))
+}
+
+func (s *NATSPublisherTestSuite) BeforeEach(t provider.T) {
+	dir, err := os.MkdirTemp("", "jetstream")
+	t.Require().NoError(err)
+	s.dir = dir
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	t.Require().NoError(err)
+
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+
+	s.server = srv
+}
+
+func (s *NATSPublisherTestSuite) AfterEach(t provider.T) {
+	s.server.Shutdown()
+	s.server.WaitForShutdown()
+	os.RemoveAll(s.dir)
+}
+
+func (s *NATSPublisherTestSuite) TestPublish(t provider.T) {
+	ctx := context.Background()
+
+	np, err := NewNATS(ctx, s.server.ClientURL(), "WALLET_EVENTS", "wallets.events")
+	t.Require().NoError(err)
+	defer np.Close()
+
+	events := []*models.OutboxEvent{
+		testEvent(1, "wallet", models.EventWalletCreated),
+		testEvent(2, "wallet", models.EventWalletBalanceChanged),
+	}
+	for _, e := range events {
+		t.Require().NoError(np.Publish(ctx, e))
+	}
+	// A retry after a lost acknowledgement publishes the event again.
+	t.Require().NoError(np.Publish(ctx, events[1]))
+
+	conn, err := nats.Connect(s.server.ClientURL())
+	t.Require().NoError(err)
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	t.Require().NoError(err)
+
+	stream, err := js.Stream(ctx, "WALLET_EVENTS")
+	t.Require().NoError(err)
+
+	info, err := stream.Info(ctx)
+	t.Require().NoError(err)
+	t.Assert().EqualValues(2, info.State.Msgs)
+
+	for i, e := range events {
+		msg, err := stream.GetMsg(ctx, uint64(i+1))
+		t.Require().NoError(err)
+		t.Assert().Equal("wallets.events."+e.Type, msg.Subject)
+
+		env, err := Decode(msg.Data)
+		t.Require().NoError(err)
+		t.Assert().Equal(e.UID, env.EventID)
+	}
+}
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Publisher delivers events to other services. Publish returns only after the
+// event is accepted, a failed event is published again later, so consumers
+// must tolerate duplicates.
+type Publisher interface {
+	Publish(ctx context.Context, e *models.OutboxEvent) error
+	Close() error
+}
+
+// LogPublisher only logs the events, for setups without a broker.
+type LogPublisher struct {
+	Logger logger.Logger
+}
+
+func (lp LogPublisher) Publish(_ context.Context, e *models.OutboxEvent) error {
+	lp.Logger.Infow("outbox event",
+		"uid", e.UID,
+		"type", e.Type,
+		"walletUID", e.WalletUID,
+		"payload", string(e.Payload))
+
+	return nil
+}
+
+func (lp LogPublisher) Close() error {
+	return nil
+}
+
+// Record stores the events in tx, which has to be the transaction of the
+// change they describe.
+func Record(tx *gorm.DB, events ...*models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	return tx.Create(&events).Error
+}
+
+// BalanceChange is the payload of wallet.balance_changed. Wallet is the wallet
+// after the change and is left out for sharded wallets: deposits into their
+// shards don't lock the wallet row, so neither the order of their events nor
+// the balance each of them would carry is defined.
+type BalanceChange struct {
+	Transaction *models.Transaction `json:"transaction"`
+	Wallet      *models.Wallet      `json:"wallet,omitempty"`
+}
+
+// StatusChange is the payload of wallet.status_changed.
+type StatusChange struct {
+	Change *models.WalletStatusChange `json:"change"`
+	Wallet *models.Wallet             `json:"wallet"`
+}
+
+func WalletEvent(eventType string, w *models.Wallet) *models.OutboxEvent {
+	return newEvent(eventType, w.UID, w.OwnerID, w)
+}
+
+func BalanceChanged(t *models.Transaction, w *models.Wallet) *models.OutboxEvent {
+	change := BalanceChange{Transaction: t}
+	if w.Shards == 0 {
+		change.Wallet = w
+	}
+
+	return newEvent(models.EventWalletBalanceChanged, t.WalletUID, w.OwnerID, change)
+}
+
+func StatusChanged(c *models.WalletStatusChange, w *models.Wallet) *models.OutboxEvent {
+	return newEvent(models.EventWalletStatusChanged, c.WalletUID, w.OwnerID, StatusChange{Change: c, Wallet: w})
+}
+
+// HoldEvent describes a change of the hold h of a wallet of the owner.
+func HoldEvent(eventType string, h *models.Hold, ownerID int) *models.OutboxEvent {
+	return newEvent(eventType, h.WalletUID, ownerID, h)
+}
+
+func newEvent(eventType string, walletUID string, ownerID int, payload interface{}) *models.OutboxEvent {
+	// The payloads are plain models, marshalling them can't fail.
+	data, _ := json.Marshal(payload)
+
+	return &models.OutboxEvent{
+		UID:       uuid.NewString(),
+		Type:      eventType,
+		WalletUID: walletUID,
+		OwnerID:   ownerID,
+		Payload:   data,
+		CreatedAt: time.Now(),
+	}
+}
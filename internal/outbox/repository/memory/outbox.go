@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type memOutboxRepo struct {
+	Logger logger.Logger
+	Store  *memstore.Store
+
+	// delivering lets one Deliver at a time publish, the store itself is not
+	// locked while the events are published.
+	delivering sync.Mutex
+}
+
+func New(logger logger.Logger, store *memstore.Store) repository.OutboxRepositoryI {
+	return &memOutboxRepo{
+		Logger: logger,
+		Store:  store,
+	}
+}
+
+func (mr *memOutboxRepo) Deliver(ctx context.Context, limit int, publish func(ctx context.Context, e *models.OutboxEvent) error) (int, error) {
+	mr.delivering.Lock()
+	defer mr.delivering.Unlock()
+
+	mr.Store.Lock()
+	var events []*models.OutboxEvent
+	for _, e := range mr.Store.Events {
+		if len(events) == limit {
+			break
+		}
+		if e.PublishedAt == nil {
+			res := *e
+			events = append(events, &res)
+		}
+	}
+	mr.Store.Unlock()
+
+	published := 0
+	var publishErr error
+	for _, e := range events {
+		if publishErr = publish(ctx, e); publishErr != nil {
+			break
+		}
+		published++
+	}
+
+	mr.Store.Lock()
+	now := time.Now()
+	for _, e := range events[:published] {
+		for _, stored := range mr.Store.Events {
+			if stored.ID == e.ID {
+				stored.PublishedAt = &now
+			}
+		}
+	}
+	mr.Store.Unlock()
+
+	if publishErr != nil {
+		return published, errors.Wrap(publishErr, "memOutboxRepo.Deliver error while publishing")
+	}
+
+	return published, nil
+}
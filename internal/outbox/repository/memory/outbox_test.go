@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox/repository"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletMem "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+)
+
+type OutboxMemRepoTestSuite struct {
+	suite.Suite
+	repo repository.OutboxRepositoryI
+}
+
+func TestOutboxMemRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(OutboxMemRepoTestSuite))
+}
+
+func (s *OutboxMemRepoTestSuite) BeforeEach(t provider.T) {
+	var logger logger.Logger
+
+	store := memstore.New()
+	s.repo = New(logger, store)
+	walletRepo := walletMem.New(logger, store)
+
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	t.Require().NoError(walletRepo.Create(&wallet))
+
+	_, err := walletRepo.ChangeAmount(&models.Transaction{
+		UID:       "deposit",
+		WalletUID: wallet.UID,
+		Type:      models.TransactionTypeDeposit,
+		Amount:    10,
+	})
+	t.Require().NoError(err)
+}
+
+func (s *OutboxMemRepoTestSuite) TestDeliver(t provider.T) {
+	var types []string
+	n, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		types = append(types, e.Type)
+		return nil
+	})
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, n)
+	t.Assert().Equal([]string{models.EventWalletCreated, models.EventWalletBalanceChanged}, types)
+
+	n, err = s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error { return nil })
+	t.Assert().NoError(err)
+	t.Assert().Zero(n)
+}
+
+func (s *OutboxMemRepoTestSuite) TestDeliverStopsAtFailure(t provider.T) {
+	errBroker := errors.New("broker is down")
+
+	n, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		if e.Type == models.EventWalletBalanceChanged {
+			return errBroker
+		}
+		return nil
+	})
+	t.Assert().ErrorIs(err, errBroker)
+	t.Assert().Equal(1, n)
+
+	var types []string
+	n, err = s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		types = append(types, e.Type)
+		return nil
+	})
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, n)
+	t.Assert().Equal([]string{models.EventWalletBalanceChanged}, types)
+}
@@ -0,0 +1,53 @@
+// Code generated by mockery v2.33.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Davmie/javaCode/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OutboxRepositoryI is an autogenerated mock type for the OutboxRepositoryI type
+type OutboxRepositoryI struct {
+	mock.Mock
+}
+
+// Deliver provides a mock function with given fields: ctx, limit, publish
+func (_m *OutboxRepositoryI) Deliver(ctx context.Context, limit int, publish func(context.Context, *models.OutboxEvent) error) (int, error) {
+	ret := _m.Called(ctx, limit, publish)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(context.Context, *models.OutboxEvent) error) (int, error)); ok {
+		return rf(ctx, limit, publish)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, func(context.Context, *models.OutboxEvent) error) int); ok {
+		r0 = rf(ctx, limit, publish)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, func(context.Context, *models.OutboxEvent) error) error); ok {
+		r1 = rf(ctx, limit, publish)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOutboxRepositoryI creates a new instance of OutboxRepositoryI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOutboxRepositoryI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OutboxRepositoryI {
+	mock := &OutboxRepositoryI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
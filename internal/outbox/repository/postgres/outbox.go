@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/outbox/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pgOutboxRepo struct {
+	Logger logger.Logger
+	DB     *gorm.DB
+}
+
+func New(logger logger.Logger, db *gorm.DB) repository.OutboxRepositoryI {
+	return &pgOutboxRepo{
+		Logger: logger,
+		DB:     db,
+	}
+}
+
+// Deliver keeps the selected events locked while they are published, so relays
+// of several instances take turns and the events of a wallet can't overtake
+// each other. Whatever publish writes through dbtx.From commits together with
+// the marks.
+func (pr *pgOutboxRepo) Deliver(ctx context.Context, limit int, publish func(ctx context.Context, e *models.OutboxEvent) error) (int, error) {
+	var (
+		published  int
+		publishErr error
+	)
+	err := dbtx.Run(pr.DB, "outbox.deliver", func(tx *gorm.DB) error {
+		published, publishErr = 0, nil
+
+		var events []*models.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("published_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		txCtx := dbtx.WithTx(ctx, tx)
+		ids := make([]int, 0, len(events))
+		for _, e := range events {
+			if publishErr = publish(txCtx, e); publishErr != nil {
+				break
+			}
+			ids = append(ids, e.ID)
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err = tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Update("published_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		published = len(ids)
+		return nil
+	})
+
+	if err != nil {
+		return 0, errors.Wrap(err, "pgOutboxRepo.Deliver error")
+	}
+	if publishErr != nil {
+		return published, errors.Wrap(publishErr, "pgOutboxRepo.Deliver error while publishing")
+	}
+
+	return published, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/outbox/repository"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+)
+
+// OutboxSQLiteTestSuite checks that the wallet changes record their events and
+// that they are delivered in order.
+type OutboxSQLiteTestSuite struct {
+	suite.Suite
+	repo       repository.OutboxRepositoryI
+	walletRepo walletRep.WalletRepositoryI
+}
+
+func TestOutboxSQLiteSuite(t *testing.T) {
+	suite.RunSuite(t, new(OutboxSQLiteTestSuite))
+}
+
+func (s *OutboxSQLiteTestSuite) BeforeEach(t provider.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal("error while opening sqlite", err)
+	}
+
+	var logger logger.Logger
+
+	s.repo = New(logger, db)
+	s.walletRepo = walletPg.New(logger, db)
+
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	t.Require().NoError(s.walletRepo.Create(&wallet))
+
+	for _, amount := range []int{10, 20} {
+		_, err = s.walletRepo.ChangeAmount(&models.Transaction{
+			UID:       fmt.Sprintf("deposit-%d", amount),
+			WalletUID: wallet.UID,
+			Type:      models.TransactionTypeDeposit,
+			Amount:    amount,
+		})
+		t.Require().NoError(err)
+	}
+}
+
+func (s *OutboxSQLiteTestSuite) TestDeliver(t provider.T) {
+	var types []string
+	n, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		types = append(types, e.Type)
+		return nil
+	})
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(3, n)
+	t.Assert().Equal([]string{
+		models.EventWalletCreated,
+		models.EventWalletBalanceChanged,
+		models.EventWalletBalanceChanged,
+	}, types)
+
+	n, err = s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		t.Errorf("event %s is published twice", e.UID)
+		return nil
+	})
+	t.Assert().NoError(err)
+	t.Assert().Zero(n)
+}
+
+func (s *OutboxSQLiteTestSuite) TestDeliverLimit(t provider.T) {
+	n, err := s.repo.Deliver(context.Background(), 2, func(_ context.Context, e *models.OutboxEvent) error { return nil })
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, n)
+
+	var types []string
+	n, err = s.repo.Deliver(context.Background(), 2, func(_ context.Context, e *models.OutboxEvent) error {
+		types = append(types, e.Type)
+		return nil
+	})
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, n)
+	t.Assert().Equal([]string{models.EventWalletBalanceChanged}, types)
+}
+
+func (s *OutboxSQLiteTestSuite) TestDeliverStopsAtFailure(t provider.T) {
+	errBroker := errors.New("broker is down")
+
+	var ids []int
+	n, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		if len(ids) == 1 {
+			return errBroker
+		}
+		ids = append(ids, e.ID)
+		return nil
+	})
+	t.Assert().ErrorIs(err, errBroker)
+	t.Assert().Equal(1, n)
+
+	var retried []int
+	n, err = s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error {
+		retried = append(retried, e.ID)
+		return nil
+	})
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, n)
+	t.Assert().Equal([]int{ids[0] + 1, ids[0] + 2}, retried)
+}
+
+func (s *OutboxSQLiteTestSuite) TestChangeAmountRejectedRecordsNothing(t provider.T) {
+	_, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error { return nil })
+	t.Require().NoError(err)
+
+	_, err = s.walletRepo.ChangeAmount(&models.Transaction{
+		UID:       "withdraw",
+		WalletUID: "wallet",
+		Type:      models.TransactionTypeWithdraw,
+		Amount:    -100,
+	})
+	t.Require().ErrorIs(err, models.ErrInsufficientFunds)
+
+	n, err := s.repo.Deliver(context.Background(), 10, func(_ context.Context, e *models.OutboxEvent) error { return nil })
+	t.Assert().NoError(err)
+	t.Assert().Zero(n)
+}
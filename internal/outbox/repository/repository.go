@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+type OutboxRepositoryI interface {
+	// Deliver passes the oldest unpublished events, at most limit of them, to
+	// publish in the order of their ids and marks the published ones. It stops
+	// at the first event publish fails on and returns that error together with
+	// the number of events published before it. The context passed to publish
+	// carries the transaction marking the events, see dbtx.From.
+	Deliver(ctx context.Context, limit int, publish func(ctx context.Context, e *models.OutboxEvent) error) (int, error)
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/Davmie/javaCode/internal/outbox"
+	outboxRep "github.com/Davmie/javaCode/internal/outbox/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var published = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "outbox_events_published_total",
+	Help: "Outbox events delivered to the publisher.",
+}, []string{"type"})
+
+type OutboxUseCaseI interface {
+	Relay(ctx context.Context) (int, error)
+}
+
+type outboxUseCase struct {
+	outboxRepository outboxRep.OutboxRepositoryI
+	publisher        outbox.Publisher
+	batchSize        int
+}
+
+// New returns the relay delivering the outbox to publisher in batches of
+// batchSize events.
+func New(oRep outboxRep.OutboxRepositoryI, publisher outbox.Publisher, batchSize int) OutboxUseCaseI {
+	return &outboxUseCase{
+		outboxRepository: oRep,
+		publisher:        publisher,
+		batchSize:        batchSize,
+	}
+}
+
+// Relay publishes the pending events until the outbox is empty and returns how
+// many were published. After a failed publish the rest of the events wait for
+// the next Relay, so no event of a wallet is published before the earlier ones.
+func (oUC *outboxUseCase) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := oUC.outboxRepository.Deliver(ctx, oUC.batchSize, func(ctx context.Context, e *models.OutboxEvent) error {
+			if err := oUC.publisher.Publish(ctx, e); err != nil {
+				return err
+			}
+
+			published.WithLabelValues(e.Type).Inc()
+			return nil
+		})
+		total += n
+		if err != nil {
+			return total, errors.Wrap(err, "outboxUseCase.Relay error")
+		}
+
+		if n < oUC.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	outboxMocks "github.com/Davmie/javaCode/internal/outbox/repository/mocks"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+)
+
+// recordingPublisher keeps the published events and fails with err, if set.
+type recordingPublisher struct {
+	events []*models.OutboxEvent
+	err    error
+}
+
+func (rp *recordingPublisher) Publish(_ context.Context, e *models.OutboxEvent) error {
+	if rp.err != nil {
+		return rp.err
+	}
+
+	rp.events = append(rp.events, e)
+	return nil
+}
+
+func (rp *recordingPublisher) Close() error {
+	return nil
+}
+
+type OutboxTestSuite struct {
+	suite.Suite
+	uc             *outboxUseCase
+	outboxRepoMock *outboxMocks.OutboxRepositoryI
+	publisher      *recordingPublisher
+}
+
+func TestOutboxTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(OutboxTestSuite))
+}
+
+func (s *OutboxTestSuite) BeforeEach(t provider.T) {
+	s.outboxRepoMock = outboxMocks.NewOutboxRepositoryI(t)
+	s.publisher = &recordingPublisher{}
+	s.uc = &outboxUseCase{
+		outboxRepository: s.outboxRepoMock,
+		publisher:        s.publisher,
+		batchSize:        2,
+	}
+}
+
+// deliver returns a Deliver stub passing events to publish.
+func deliver(events ...*models.OutboxEvent) func(context.Context, int, func(context.Context, *models.OutboxEvent) error) (int, error) {
+	return func(ctx context.Context, _ int, publish func(context.Context, *models.OutboxEvent) error) (int, error) {
+		for i, e := range events {
+			if err := publish(ctx, e); err != nil {
+				return i, err
+			}
+		}
+		return len(events), nil
+	}
+}
+
+func (s *OutboxTestSuite) TestRelayUntilEmpty(t provider.T) {
+	first := []*models.OutboxEvent{{ID: 1, Type: models.EventWalletCreated}, {ID: 2, Type: models.EventWalletBalanceChanged}}
+	last := &models.OutboxEvent{ID: 3, Type: models.EventWalletBalanceChanged}
+
+	s.outboxRepoMock.On("Deliver", mock.Anything, 2, mock.Anything).Return(deliver(first...)).Once()
+	s.outboxRepoMock.On("Deliver", mock.Anything, 2, mock.Anything).Return(deliver(last)).Once()
+
+	n, err := s.uc.Relay(context.Background())
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(3, n)
+	t.Assert().Equal(append(first, last), s.publisher.events)
+}
+
+func (s *OutboxTestSuite) TestRelayPublishError(t provider.T) {
+	errBroker := errors.New("broker is down")
+	s.publisher.err = errBroker
+
+	s.outboxRepoMock.On("Deliver", mock.Anything, 2, mock.Anything).
+		Return(deliver(&models.OutboxEvent{ID: 1, Type: models.EventWalletCreated})).Once()
+
+	n, err := s.uc.Relay(context.Background())
+
+	t.Assert().ErrorIs(err, errBroker)
+	t.Assert().Zero(n)
+}
@@ -0,0 +1,213 @@
+// Package reconcile checks the stored balances of the wallets against the
+// ledger and the holds they are derived from.
+package reconcile
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Report is the state of a single wallet. Amount and Held are stored in the
+// wallet, Ledger and Holds are the sums of its ledger entries and of its active
+// holds. Problems is empty for a consistent wallet.
+type Report struct {
+	WalletUID string   `json:"walletId"`
+	Status    string   `json:"status"`
+	Amount    int      `json:"amount"`
+	Ledger    int      `json:"ledger"`
+	Held      int      `json:"held"`
+	Holds     int      `json:"holds"`
+	Problems  []string `json:"problems"`
+}
+
+// Consistent tells whether no problems were found.
+func (r *Report) Consistent() bool {
+	return len(r.Problems) == 0
+}
+
+// total is a sum of amounts of a wallet, of the reversals of an entry when
+// ReversalOf is set.
+type total struct {
+	WalletUID  string
+	ReversalOf string
+	Total      int
+}
+
+// reversal is a ledger entry compared with the reversals compensating it.
+type reversal struct {
+	WalletUID   string
+	Reversed    int
+	Compensated int
+}
+
+// Run reconciles every wallet, soft deleted ones included, in the order of
+// their ids. Ledger entries and holds of wallets that no longer exist are
+// reported after them. All the reads are made in one transaction, repeatable
+// read and read only with Postgres, so they see a single snapshot and Run may
+// run next to the service without reporting the wallets changed meanwhile.
+func Run(db *gorm.DB) ([]*Report, error) {
+	var opts *sql.TxOptions
+	if db.Dialector.Name() == "postgres" {
+		opts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	}
+
+	var reports []*Report
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		reports, err = run(tx)
+		return err
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// run reconciles the wallets with the reads of db.
+func run(db *gorm.DB) ([]*Report, error) {
+	var wallets []*models.Wallet
+	if err := db.Unscoped().Order("id").Find(&wallets).Error; err != nil {
+		return nil, errors.Wrap(err, "reconcile.Run error while loading wallets")
+	}
+	if err := walletPg.AddShards(db, wallets...); err != nil {
+		return nil, errors.Wrap(err, "reconcile.Run error while loading wallets")
+	}
+
+	ledger, err := sums(db.Model(&models.Transaction{}))
+	if err != nil {
+		return nil, errors.Wrap(err, "reconcile.Run error while summing ledger")
+	}
+
+	holds, err := sums(db.Model(&models.Hold{}).Where("status = ?", models.HoldStatusActive))
+	if err != nil {
+		return nil, errors.Wrap(err, "reconcile.Run error while summing holds")
+	}
+
+	reversals, err := loadReversals(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "reconcile.Run error while loading reversals")
+	}
+
+	reports := make([]*Report, 0, len(wallets))
+	byWallet := make(map[string]*Report, len(wallets))
+	for _, w := range wallets {
+		r := &Report{WalletUID: w.UID, Status: w.Status, Amount: w.Amount, Held: w.Held}
+		reports = append(reports, r)
+		byWallet[w.UID] = r
+	}
+
+	var missing []*Report
+	report := func(walletUID string) *Report {
+		r, ok := byWallet[walletUID]
+		if !ok {
+			r = &Report{WalletUID: walletUID, Problems: []string{"wallet does not exist"}}
+			missing = append(missing, r)
+			byWallet[walletUID] = r
+		}
+		return r
+	}
+
+	for _, t := range ledger {
+		report(t.WalletUID).Ledger = t.Total
+	}
+	for _, t := range holds {
+		report(t.WalletUID).Holds = t.Total
+	}
+
+	for _, r := range reports {
+		if r.Amount != r.Ledger {
+			r.Problems = append(r.Problems, fmt.Sprintf("amount differs from ledger by %d", r.Amount-r.Ledger))
+		}
+		if r.Held != r.Holds {
+			r.Problems = append(r.Problems, fmt.Sprintf("held differs from active holds by %d", r.Held-r.Holds))
+		}
+		if r.Held > r.Amount {
+			r.Problems = append(r.Problems, "held exceeds amount")
+		}
+	}
+
+	for _, uid := range sortedKeys(reversals) {
+		if rev := reversals[uid]; rev.Reversed != rev.Compensated {
+			r := report(rev.WalletUID)
+			r.Problems = append(r.Problems, fmt.Sprintf("entry %s is marked reversed by %d, its reversals sum to %d", uid, rev.Reversed, rev.Compensated))
+		}
+	}
+
+	reports = append(reports, missing...)
+	for _, r := range reports {
+		if r.Problems == nil {
+			r.Problems = []string{}
+		}
+	}
+
+	return reports, nil
+}
+
+// sums totals the amount of the rows of query by wallet.
+func sums(query *gorm.DB) ([]*total, error) {
+	var totals []*total
+	err := query.Select("wallet_uid, SUM(amount) AS total").Group("wallet_uid").Order("wallet_uid").Find(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// loadReversals returns the entries that are marked reversed or have reversals
+// by their uids.
+func loadReversals(db *gorm.DB) (map[string]*reversal, error) {
+	var reversed []*models.Transaction
+	if err := db.Select("uid, wallet_uid, reversed").Where("reversed <> 0").Find(&reversed).Error; err != nil {
+		return nil, err
+	}
+
+	var compensations []*total
+	err := db.Model(&models.Transaction{}).
+		Select("reversal_of, wallet_uid, SUM(amount) AS total").
+		Where("reversal_of IS NOT NULL").Group("reversal_of, wallet_uid").Find(&compensations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*reversal, len(reversed))
+	for _, t := range reversed {
+		res[t.UID] = &reversal{WalletUID: t.WalletUID, Reversed: t.Reversed}
+	}
+	for _, c := range compensations {
+		rev, ok := res[c.ReversalOf]
+		if !ok {
+			rev = &reversal{WalletUID: c.WalletUID}
+			res[c.ReversalOf] = rev
+		}
+		// Reversals have the opposite sign of the entry they compensate.
+		rev.Compensated += abs(c.Total)
+	}
+
+	return res, nil
+}
+
+func sortedKeys(m map[string]*reversal) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
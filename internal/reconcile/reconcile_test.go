@@ -0,0 +1,192 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	holdPg "github.com/Davmie/javaCode/internal/hold/repository/postgres"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	transactionPg "github.com/Davmie/javaCode/internal/transaction/repository/postgres"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/gorm"
+)
+
+// ReconcileTestSuite builds the ledger with the repositories of the service, so
+// a consistent wallet is what they actually write.
+type ReconcileTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	wallets walletRep.WalletRepositoryI
+}
+
+func TestReconcileTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(ReconcileTestSuite))
+}
+
+func (s *ReconcileTestSuite) BeforeEach(t provider.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal("error while opening sqlite", err)
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.wallets = walletPg.New(logger, db)
+}
+
+func (s *ReconcileTestSuite) createWallet(t provider.T, uid string) {
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID(uid).
+		WithStatus(models.WalletStatusActive).
+		Build()
+	t.Require().NoError(s.wallets.Create(&wallet))
+}
+
+func (s *ReconcileTestSuite) change(t provider.T, uid string, walletUID string, amount int) {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID(uid).
+		WithWalletUID(walletUID).
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(amount).
+		Build()
+	_, err := s.wallets.ChangeAmount(&transaction)
+	t.Require().NoError(err)
+}
+
+func (s *ReconcileTestSuite) run(t provider.T) map[string]*Report {
+	reports, err := Run(s.db)
+	t.Require().NoError(err)
+
+	res := make(map[string]*Report, len(reports))
+	for _, r := range reports {
+		res[r.WalletUID] = r
+	}
+
+	return res
+}
+
+func (s *ReconcileTestSuite) TestConsistent(t provider.T) {
+	var logger logger.Logger
+	holds := holdPg.New(logger, s.db)
+	transactions := transactionPg.New(logger, s.db)
+
+	s.createWallet(t, "wallet")
+	s.change(t, "deposit", "wallet", 100)
+
+	captured := testBuilders.NewHoldBuilder().
+		WithUID("captured").
+		WithWalletUID("wallet").
+		WithAmount(30).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(time.Now().Add(time.Hour)).
+		Build()
+	t.Require().NoError(holds.Create(&captured))
+	_, err := holds.Capture("captured", 20, time.Now())
+	t.Require().NoError(err)
+
+	active := testBuilders.NewHoldBuilder().
+		WithUID("active").
+		WithWalletUID("wallet").
+		WithAmount(15).
+		WithStatus(models.HoldStatusActive).
+		WithExpiresAt(time.Now().Add(time.Hour)).
+		Build()
+	t.Require().NoError(holds.Create(&active))
+
+	reversal := testBuilders.NewTransactionBuilder().
+		WithUID("reversal").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeReversal).
+		WithAmount(-40).
+		WithReversalOf("deposit").
+		Build()
+	t.Require().NoError(transactions.Reverse(&reversal))
+
+	s.createWallet(t, "sharded")
+	_, err = s.wallets.SetShards("sharded", 4)
+	t.Require().NoError(err)
+	s.change(t, "sharded-1", "sharded", 10)
+	s.change(t, "sharded-2", "sharded", 5)
+
+	reports, err := Run(s.db)
+	t.Require().NoError(err)
+	t.Require().Len(reports, 2)
+
+	t.Assert().Equal(&Report{
+		WalletUID: "wallet",
+		Status:    models.WalletStatusActive,
+		Amount:    40,
+		Ledger:    40,
+		Held:      15,
+		Holds:     15,
+		Problems:  []string{},
+	}, reports[0])
+	t.Assert().Equal("sharded", reports[1].WalletUID)
+	t.Assert().Equal(15, reports[1].Amount)
+	t.Assert().True(reports[1].Consistent())
+}
+
+func (s *ReconcileTestSuite) TestBalanceMismatch(t provider.T) {
+	s.createWallet(t, "wallet")
+	s.change(t, "deposit", "wallet", 100)
+	t.Require().NoError(s.db.Model(&models.Wallet{}).Where("uid = ?", "wallet").
+		Updates(map[string]interface{}{"amount": 120, "held": 30}).Error)
+
+	report := s.run(t)["wallet"]
+	t.Require().NotNil(report)
+	t.Assert().Equal([]string{
+		"amount differs from ledger by 20",
+		"held differs from active holds by 30",
+	}, report.Problems)
+}
+
+func (s *ReconcileTestSuite) TestDeletedWallet(t provider.T) {
+	s.createWallet(t, "wallet")
+	s.change(t, "deposit", "wallet", 100)
+
+	wallet, err := s.wallets.GetByUID("wallet")
+	t.Require().NoError(err)
+	t.Require().NoError(s.wallets.Delete(wallet.ID, wallet.Version))
+	t.Require().NoError(s.db.Model(&models.Wallet{}).Unscoped().Where("uid = ?", "wallet").Update("amount", 50).Error)
+
+	report := s.run(t)["wallet"]
+	t.Require().NotNil(report)
+	t.Assert().Equal([]string{"amount differs from ledger by -50"}, report.Problems)
+}
+
+func (s *ReconcileTestSuite) TestReversalMismatch(t provider.T) {
+	s.createWallet(t, "wallet")
+	s.change(t, "deposit", "wallet", 100)
+	t.Require().NoError(s.db.Model(&models.Transaction{}).Where("uid = ?", "deposit").Update("reversed", 30).Error)
+
+	report := s.run(t)["wallet"]
+	t.Require().NotNil(report)
+	t.Assert().Equal([]string{"entry deposit is marked reversed by 30, its reversals sum to 0"}, report.Problems)
+}
+
+func (s *ReconcileTestSuite) TestMissingWallet(t provider.T) {
+	orphan := testBuilders.NewTransactionBuilder().
+		WithUID("orphan").
+		WithWalletUID("gone").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(10).
+		Build()
+	// The foreign key keeps the service from writing such an entry, it is left
+	// by a wallet deleted by hand.
+	t.Require().NoError(s.db.Exec("PRAGMA foreign_keys = OFF").Error)
+	t.Require().NoError(s.db.Create(&orphan).Error)
+
+	reports, err := Run(s.db)
+	t.Require().NoError(err)
+	t.Require().Len(reports, 1)
+	t.Assert().Equal("gone", reports[0].WalletUID)
+	t.Assert().Equal(10, reports[0].Ledger)
+	t.Assert().Equal([]string{"wallet does not exist"}, reports[0].Problems)
+}
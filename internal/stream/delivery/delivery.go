@@ -0,0 +1,258 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/stream"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ledgerPage is how many ledger entries are read at once.
+	ledgerPage = 100
+	// writeTimeout bounds every write of a stream, the server WriteTimeout
+	// would end the whole stream instead.
+	writeTimeout = 10 * time.Second
+)
+
+type UserContextManager interface {
+	UserIDFromContext(context.Context) (int, error)
+	UserRoleFromContext(context.Context) (string, error)
+}
+
+// StreamHandler pushes the changes of wallets to their owners and to admins.
+type StreamHandler struct {
+	WalletUseCase      walletUseCase.WalletUseCaseI
+	TransactionUseCase transactionUseCase.TransactionUseCaseI
+	Hub                *stream.Hub
+	Logger             logger.Logger
+	ContextManager     UserContextManager
+	// Heartbeat is how long a stream may stay silent, proxies close idle
+	// connections. Every heartbeat also looks for changes the hub missed.
+	Heartbeat time.Duration
+}
+
+// WalletEvents streams the changes of the wallet as Server-Sent Events. Every
+// new ledger entry is a "transaction" event with the id of the entry, the
+// wallet after the entries is a "balance" event. The stream starts with the
+// current balance, a client reconnecting with Last-Event-ID gets the entries
+// it missed first.
+func (sh *StreamHandler) WalletEvents(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		sh.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, resumed, err := lastEventID(r)
+	if err != nil {
+		sh.Logger.Infow("can`t parse Last-Event-ID",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := sh.WalletUseCase.GetByUID(walletUID)
+	if err == nil && !sh.mayFollow(r, wallet) {
+		err = errors.Wrap(models.ErrNotFound, "wallet of another user")
+	}
+	if err != nil {
+		sh.Logger.Infow("can`t stream wallet",
+			"err:", err.Error())
+		http.Error(w, "can`t stream wallet", errorStatus(err))
+		return
+	}
+
+	// Subscribed before the ledger is read, so no change falls in between.
+	sub := sh.Hub.Subscribe(walletUID)
+	defer sub.Close()
+
+	if !resumed {
+		if lastID, err = sh.TransactionUseCase.LastID(walletUID); err != nil {
+			sh.Logger.Errorw("can`t get last transaction",
+				"err:", err.Error())
+			http.Error(w, "can`t stream wallet", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	// The request is read already, the deadline set for it would cancel the
+	// context of the stream.
+	if err = rc.SetReadDeadline(time.Time{}); err != nil {
+		sh.Logger.Errorw("can`t stream wallet",
+			"err:", err.Error())
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ws := &walletStream{
+		handler: sh,
+		w:       w,
+		rc:      rc,
+		uid:     walletUID,
+		lastID:  lastID,
+	}
+
+	err = ws.catchUp(wallet)
+	heartbeat := time.NewTicker(sh.Heartbeat)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.C:
+			sub.Changed()
+			err = ws.catchUp(nil)
+		case <-heartbeat.C:
+			if err = ws.comment("heartbeat"); err == nil {
+				err = ws.catchUp(nil)
+			}
+		}
+	}
+
+	if !errors.Is(err, models.ErrNotFound) {
+		sh.Logger.Infow("wallet stream ended",
+			"err:", err.Error())
+	}
+}
+
+// mayFollow tells whether the user may see the changes of the wallet.
+func (sh *StreamHandler) mayFollow(r *http.Request, wallet *models.Wallet) bool {
+	if role, err := sh.ContextManager.UserRoleFromContext(r.Context()); err == nil && role == session.AdminRole {
+		return true
+	}
+
+	userID, err := sh.ContextManager.UserIDFromContext(r.Context())
+	return err == nil && wallet.OwnedBy(userID)
+}
+
+// walletStream is the state of an open stream: the last entry sent and the
+// wallet as last sent.
+type walletStream struct {
+	handler *StreamHandler
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	uid     string
+	lastID  int
+	sent    *models.Wallet
+}
+
+// catchUp sends the ledger entries after the last one sent and the wallet, if
+// it changed. A nil wallet is read again.
+func (ws *walletStream) catchUp(wallet *models.Wallet) error {
+	for {
+		transactions, err := ws.handler.TransactionUseCase.GetByWalletUIDAfter(ws.uid, ws.lastID, ledgerPage)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range transactions {
+			if err = ws.event(t.ID, "transaction", t); err != nil {
+				return err
+			}
+			ws.lastID = t.ID
+		}
+
+		if len(transactions) < ledgerPage {
+			break
+		}
+	}
+
+	if wallet == nil {
+		var err error
+		if wallet, err = ws.handler.WalletUseCase.GetByUID(ws.uid); err != nil {
+			return err
+		}
+	}
+
+	if ws.sent != nil && ws.sent.Version == wallet.Version && ws.sent.Amount == wallet.Amount &&
+		ws.sent.Held == wallet.Held && ws.sent.Status == wallet.Status {
+		return nil
+	}
+
+	ws.sent = wallet
+	return ws.event(0, "balance", wallet)
+}
+
+// event writes an event, with the id unless it is 0, and flushes it.
+func (ws *walletStream) event(id int, name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err = ws.rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+
+	if id != 0 {
+		if _, err = fmt.Fprintf(ws.w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+
+	if _, err = fmt.Fprintf(ws.w, "event: %s\ndata: %s\n\n", name, data); err != nil {
+		return err
+	}
+
+	return ws.rc.Flush()
+}
+
+func (ws *walletStream) comment(text string) error {
+	if err := ws.rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(ws.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+
+	return ws.rc.Flush()
+}
+
+// lastEventID returns the id of the last event the client got, from the
+// Last-Event-ID header or, for clients unable to set it, the lastEventId
+// query parameter.
+func lastEventID(r *http.Request) (int, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, false, errors.Errorf("bad event id %q", value)
+	}
+
+	return id, true, nil
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,359 @@
+package delivery
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/stream"
+	transactionRepo "github.com/Davmie/javaCode/internal/transaction/repository"
+	memTransaction "github.com/Davmie/javaCode/internal/transaction/repository/memory"
+	pgTransaction "github.com/Davmie/javaCode/internal/transaction/repository/postgres"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	walletRepo "github.com/Davmie/javaCode/internal/wallet/repository"
+	memWallet "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	pgWallet "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/models"
+	ctxManager "github.com/Davmie/javaCode/pkg/context"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/middleware"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/google/uuid"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+)
+
+const (
+	testOwnerID = 7
+	testOtherID = 8
+	testAdminID = 1
+	// testHeartbeat is short, so the tests see heartbeats, and long enough for
+	// the events they wait for to come first.
+	testHeartbeat = 100 * time.Millisecond
+)
+
+// streamServer serves the streams of wallets kept in memory. The hub is not
+// fed by an outbox relay, tests notify it themselves.
+type streamServer struct {
+	server  *httptest.Server
+	wallets walletUseCase.WalletUseCaseI
+	hub     *stream.Hub
+}
+
+func newStreamServer(t provider.T, heartbeat time.Duration) *streamServer {
+	logger := zap.NewNop().Sugar()
+	store := memstore.New()
+
+	return serveStreams(heartbeat, memWallet.New(logger, store), memTransaction.New(logger, store))
+}
+
+// newSQLiteStreamServer serves the streams of wallets kept in SQLite, where
+// deposits into sharded wallets go into their shards.
+func newSQLiteStreamServer(t provider.T, heartbeat time.Duration) *streamServer {
+	logger := zap.NewNop().Sugar()
+	db, err := database.OpenSQLite(":memory:")
+	t.Require().NoError(err)
+
+	return serveStreams(heartbeat, pgWallet.New(logger, db), pgTransaction.New(logger, db))
+}
+
+func serveStreams(heartbeat time.Duration, wallets walletRepo.WalletRepositoryI, transactions transactionRepo.TransactionRepositoryI) *streamServer {
+	logger := zap.NewNop().Sugar()
+
+	ss := &streamServer{
+		wallets: walletUseCase.New(wallets),
+		hub:     stream.NewHub(),
+	}
+	handler := StreamHandler{
+		WalletUseCase:      ss.wallets,
+		TransactionUseCase: transactionUseCase.New(transactions),
+		Hub:                ss.hub,
+		Logger:             logger,
+		ContextManager:     ctxManager.Manager{},
+		Heartbeat:          heartbeat,
+	}
+	authManager := middleware.AuthManager{
+		SessionManager: session.JWTSessionsManager{},
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+
+	r := http.NewServeMux()
+	r.Handle("GET /api/v1/wallets/{WALLET_UUID}/events", authManager.Auth(http.HandlerFunc(handler.WalletEvents)))
+	r.Handle("GET /api/v1/stream", authManager.Auth(http.HandlerFunc(handler.Subscriptions)))
+	ss.server = httptest.NewServer(r)
+
+	return ss
+}
+
+func (ss *streamServer) token(t provider.T, userID int, role string) string {
+	token, err := session.JWTSessionsManager{}.CreateSession(userID, role)
+	t.Require().NoError(err)
+
+	return token
+}
+
+// createWallet creates a wallet of the owner with the deposits on it.
+func (ss *streamServer) createWallet(t provider.T, ownerID int, deposits ...int) *models.Wallet {
+	wallet := &models.Wallet{UID: uuid.NewString(), OwnerID: ownerID}
+	t.Require().NoError(ss.wallets.Create(wallet))
+	for _, amount := range deposits {
+		_, err := ss.wallets.ChangeAmount(wallet.UID, amount)
+		t.Require().NoError(err)
+	}
+
+	return wallet
+}
+
+// sseEvent is an event of a stream, or a comment when Comment is set.
+type sseEvent struct {
+	ID      string
+	Name    string
+	Data    string
+	Comment string
+}
+
+type StreamTestSuite struct {
+	suite.Suite
+	ss *streamServer
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.RunSuite(t, new(StreamTestSuite))
+}
+
+func (s *StreamTestSuite) BeforeEach(t provider.T) {
+	s.ss = newStreamServer(t, testHeartbeat)
+}
+
+func (s *StreamTestSuite) AfterEach(t provider.T) {
+	s.ss.server.CloseClientConnections()
+	s.ss.server.Close()
+}
+
+// open requests the events of the wallet as the user, lastEventID is sent
+// unless it is empty.
+func (s *StreamTestSuite) open(t provider.T, walletUID string, userID int, role string, lastEventID string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, s.ss.server.URL+"/api/v1/wallets/"+walletUID+"/events", nil)
+	t.Require().NoError(err)
+	req.Header.Set("Authorization", s.ss.token(t, userID, role))
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	t.Require().NoError(err)
+	t.Cleanup(func() {
+		_ = resp.Body.Close()
+	})
+
+	return resp
+}
+
+// next reads the next event of the stream, comments included.
+func next(t provider.T, r *bufio.Reader) sseEvent {
+	var e sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		t.Require().NoError(err)
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return e
+		case strings.HasPrefix(line, ": "):
+			e.Comment = strings.TrimPrefix(line, ": ")
+		case strings.HasPrefix(line, "id: "):
+			e.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// nextEvent reads the next event of the stream, skipping the heartbeats.
+func nextEvent(t provider.T, r *bufio.Reader) sseEvent {
+	for {
+		if e := next(t, r); e.Comment == "" {
+			return e
+		}
+	}
+}
+
+func (s *StreamTestSuite) balance(t provider.T, e sseEvent) *models.Wallet {
+	t.Require().Equal("balance", e.Name)
+	t.Assert().Empty(e.ID)
+
+	wallet := &models.Wallet{}
+	t.Require().NoError(json.Unmarshal([]byte(e.Data), wallet))
+
+	return wallet
+}
+
+func (s *StreamTestSuite) transaction(t provider.T, e sseEvent) *models.Transaction {
+	t.Require().Equal("transaction", e.Name)
+
+	transaction := &models.Transaction{}
+	t.Require().NoError(json.Unmarshal([]byte(e.Data), transaction))
+	t.Assert().Equal(strconv.Itoa(transaction.ID), e.ID)
+
+	return transaction
+}
+
+func (s *StreamTestSuite) TestStartsWithBalance(t provider.T) {
+	wallet := s.ss.createWallet(t, testOwnerID, 100)
+
+	resp := s.open(t, wallet.UID, testOwnerID, "user", "")
+	t.Require().Equal(http.StatusOK, resp.StatusCode)
+	t.Assert().Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	r := bufio.NewReader(resp.Body)
+
+	// The deposit made before the stream opened is not sent.
+	t.Assert().Equal(100, s.balance(t, nextEvent(t, r)).Amount)
+
+	_, err := s.ss.wallets.ChangeAmount(wallet.UID, -30)
+	t.Require().NoError(err)
+	s.ss.hub.Notify(wallet.UID)
+
+	t.Assert().Equal(-30, s.transaction(t, nextEvent(t, r)).Amount)
+	t.Assert().Equal(70, s.balance(t, nextEvent(t, r)).Amount)
+}
+
+func (s *StreamTestSuite) TestResumeFromLastEventID(t provider.T) {
+	wallet := s.ss.createWallet(t, testOwnerID, 10)
+
+	resp := s.open(t, wallet.UID, testOwnerID, "user", "")
+	r := bufio.NewReader(resp.Body)
+	t.Assert().Equal(10, s.balance(t, nextEvent(t, r)).Amount)
+
+	_, err := s.ss.wallets.ChangeAmount(wallet.UID, 20)
+	t.Require().NoError(err)
+	s.ss.hub.Notify(wallet.UID)
+	seen := nextEvent(t, r)
+	t.Assert().Equal(20, s.transaction(t, seen).Amount)
+	_ = resp.Body.Close()
+
+	// Changes made while the client was away.
+	_, err = s.ss.wallets.ChangeAmount(wallet.UID, 30)
+	t.Require().NoError(err)
+	_, err = s.ss.wallets.ChangeAmount(wallet.UID, 40)
+	t.Require().NoError(err)
+
+	resp = s.open(t, wallet.UID, testOwnerID, "user", seen.ID)
+	t.Require().Equal(http.StatusOK, resp.StatusCode)
+	r = bufio.NewReader(resp.Body)
+
+	t.Assert().Equal(30, s.transaction(t, nextEvent(t, r)).Amount)
+	t.Assert().Equal(40, s.transaction(t, nextEvent(t, r)).Amount)
+	t.Assert().Equal(100, s.balance(t, nextEvent(t, r)).Amount)
+}
+
+func (s *StreamTestSuite) TestBadLastEventID(t provider.T) {
+	wallet := s.ss.createWallet(t, testOwnerID)
+
+	resp := s.open(t, wallet.UID, testOwnerID, "user", "first")
+
+	t.Assert().Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *StreamTestSuite) TestHeartbeat(t provider.T) {
+	wallet := s.ss.createWallet(t, testOwnerID)
+
+	resp := s.open(t, wallet.UID, testOwnerID, "user", "")
+	r := bufio.NewReader(resp.Body)
+	t.Assert().Equal(0, s.balance(t, next(t, r)).Amount)
+
+	t.Assert().Equal("heartbeat", next(t, r).Comment)
+
+	// The hub is not told about the change, the next heartbeat finds it.
+	_, err := s.ss.wallets.ChangeAmount(wallet.UID, 5)
+	t.Require().NoError(err)
+
+	t.Assert().Equal(5, s.transaction(t, nextEvent(t, r)).Amount)
+	t.Assert().Equal(5, s.balance(t, nextEvent(t, r)).Amount)
+}
+
+func (s *StreamTestSuite) TestOwnerCheck(t provider.T) {
+	wallet := s.ss.createWallet(t, testOwnerID, 10)
+
+	other := s.open(t, wallet.UID, testOtherID, "user", "")
+	t.Assert().Equal(http.StatusNotFound, other.StatusCode)
+
+	missing := s.open(t, uuid.NewString(), testOwnerID, "user", "")
+	t.Assert().Equal(http.StatusNotFound, missing.StatusCode)
+
+	admin := s.open(t, wallet.UID, testAdminID, session.AdminRole, "")
+	t.Require().Equal(http.StatusOK, admin.StatusCode)
+	t.Assert().Equal(10, s.balance(t, nextEvent(t, bufio.NewReader(admin.Body))).Amount)
+}
+
+func (s *StreamTestSuite) TestShardedDeposits(t provider.T) {
+	s.ss.server.Close()
+	s.ss = newSQLiteStreamServer(t, testHeartbeat)
+
+	wallet := s.ss.createWallet(t, testOwnerID)
+	_, err := s.ss.wallets.SetShards(wallet.UID, 4)
+	t.Require().NoError(err)
+
+	resp := s.open(t, wallet.UID, testOwnerID, "user", "")
+	r := bufio.NewReader(resp.Body)
+	t.Assert().Equal(0, s.balance(t, nextEvent(t, r)).Amount)
+
+	// The deposits go into the shards at once, their entries may be numbered
+	// in one order and committed in another.
+	const deposits = 20
+	errs := make([]error, deposits)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.ss.wallets.ChangeAmount(wallet.UID, i+1)
+			s.ss.hub.Notify(wallet.UID)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		t.Require().NoError(err)
+	}
+
+	// Every entry is sent once, in the order of the ids.
+	var sent []sseEvent
+	amounts := map[int]bool{}
+	for len(sent) < deposits {
+		e := nextEvent(t, r)
+		if e.Name == "balance" {
+			continue
+		}
+
+		transaction := s.transaction(t, e)
+		t.Require().False(amounts[transaction.Amount], "entry %d is sent twice", transaction.ID)
+		amounts[transaction.Amount] = true
+		if len(sent) > 0 {
+			last, _ := strconv.Atoi(sent[len(sent)-1].ID)
+			t.Require().Greater(transaction.ID, last)
+		}
+		sent = append(sent, e)
+	}
+	_ = resp.Body.Close()
+
+	// A client resuming from the middle gets the rest in the same order.
+	resp = s.open(t, wallet.UID, testOwnerID, "user", sent[deposits/2-1].ID)
+	r = bufio.NewReader(resp.Body)
+	for _, e := range sent[deposits/2:] {
+		t.Assert().Equal(e, nextEvent(t, r))
+	}
+	t.Assert().Equal(deposits*(deposits+1)/2, s.balance(t, nextEvent(t, r)).Amount)
+}
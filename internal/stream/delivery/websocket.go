@@ -0,0 +1,252 @@
+package delivery
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/stream"
+	"github.com/Davmie/javaCode/models"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxSubscriptions is how many wallets a WebSocket connection may watch.
+	MaxSubscriptions = 1000
+	// maxMessageSize bounds the messages of the clients.
+	maxMessageSize = 64 << 10
+	// replyQueue is how many replies to subscribe and unsubscribe requests may
+	// wait for a slow client without holding up the reading of its messages.
+	// When it is full the reading waits up to replyTimeout, then the connection
+	// is closed.
+	replyQueue = 64
+)
+
+// replyTimeout bounds the wait for room in the reply queue.
+var replyTimeout = writeTimeout
+
+// Types of the WebSocket messages.
+const (
+	MessageSubscribe    = "subscribe"
+	MessageUnsubscribe  = "unsubscribe"
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageBalance      = "balance"
+	MessageError        = "error"
+)
+
+// ClientMessage subscribes the connection to the wallets or unsubscribes it.
+type ClientMessage struct {
+	Type       string   `json:"type"`
+	WalletUIDs []string `json:"walletIds"`
+}
+
+// ServerMessage is a reply to a ClientMessage, one per wallet, or the balance
+// of a watched wallet.
+type ServerMessage struct {
+	Type      string         `json:"type"`
+	WalletUID string         `json:"walletId,omitempty"`
+	Wallet    *models.Wallet `json:"wallet,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// Subscriptions serves a WebSocket connection multiplexing the balances of the
+// wallets the client subscribes to. A wallet is sent right after it is
+// subscribed to and after every change. A slow client gets only the latest
+// balance of the wallets changed while it was reading, a client not reading at
+// all is disconnected.
+func (sh *StreamHandler) Subscriptions(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		sh.Logger.Infow("can`t upgrade to websocket",
+			"err:", err.Error())
+		return
+	}
+	defer conn.Close()
+
+	// The connection outlives the deadlines set by the server for the request.
+	if err = conn.NetConn().SetDeadline(time.Time{}); err != nil {
+		sh.Logger.Errorw("can`t reset websocket deadlines",
+			"err:", err.Error())
+		return
+	}
+
+	sub := sh.Hub.Subscribe()
+	defer sub.Close()
+
+	replies := make(chan ServerMessage, replyQueue)
+	done := make(chan struct{})
+	defer close(done)
+	stopped := make(chan struct{})
+
+	go sh.writeLoop(conn, sub, replies, done, stopped)
+
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * sh.Heartbeat))
+	})
+	if err = conn.SetReadDeadline(time.Now().Add(2 * sh.Heartbeat)); err != nil {
+		return
+	}
+
+	for {
+		msg := ClientMessage{}
+		if err = conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				sh.Logger.Infow("websocket closed",
+					"err:", err.Error())
+			}
+			return
+		}
+
+		replied, added := sh.handle(r, sub, msg)
+		if !sendReplies(replies, replied, stopped) {
+			sh.Logger.Infow("websocket client too slow, closing")
+			return
+		}
+
+		// The balances go out like any change, after the replies.
+		sub.Touch(added...)
+	}
+}
+
+// handle applies a client message to the subscription and returns the replies
+// and the wallets subscribed to.
+func (sh *StreamHandler) handle(r *http.Request, sub *stream.Subscription, msg ClientMessage) ([]ServerMessage, []string) {
+	var (
+		replies []ServerMessage
+		added   []string
+	)
+	switch msg.Type {
+	case MessageSubscribe:
+		for _, uid := range msg.WalletUIDs {
+			if sub.Watches(uid) {
+				replies = append(replies, ServerMessage{Type: MessageSubscribed, WalletUID: uid})
+				continue
+			}
+
+			if sub.Len() >= MaxSubscriptions {
+				replies = append(replies, ServerMessage{Type: MessageError, WalletUID: uid, Error: "too many subscriptions"})
+				continue
+			}
+
+			wallet, err := sh.WalletUseCase.GetByUID(uid)
+			if err == nil && !sh.mayFollow(r, wallet) {
+				err = errors.Wrap(models.ErrNotFound, "wallet of another user")
+			}
+			if err != nil {
+				sh.Logger.Infow("can`t subscribe to wallet",
+					"err:", err.Error())
+				replies = append(replies, ServerMessage{Type: MessageError, WalletUID: uid, Error: errorMessage(err)})
+				continue
+			}
+
+			sub.Add(uid)
+			added = append(added, uid)
+			replies = append(replies, ServerMessage{Type: MessageSubscribed, WalletUID: uid})
+		}
+	case MessageUnsubscribe:
+		sub.Remove(msg.WalletUIDs...)
+		for _, uid := range msg.WalletUIDs {
+			replies = append(replies, ServerMessage{Type: MessageUnsubscribed, WalletUID: uid})
+		}
+	default:
+		replies = append(replies, ServerMessage{Type: MessageError, Error: "unknown message type"})
+	}
+
+	return replies, added
+}
+
+// sendReplies queues the replies for the write loop. It fails when the queue
+// stays full for replyTimeout or the write loop stopped.
+func sendReplies(replies chan<- ServerMessage, replied []ServerMessage, stopped <-chan struct{}) bool {
+	timeout := time.NewTimer(replyTimeout)
+	defer timeout.Stop()
+
+	for _, reply := range replied {
+		select {
+		case replies <- reply:
+		case <-stopped:
+			return false
+		case <-timeout.C:
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeLoop is the only writer of the connection: it sends the replies, the
+// balances of the changed wallets and the pings until done is closed. It
+// closes stopped when it returns.
+func (sh *StreamHandler) writeLoop(conn *websocket.Conn, sub *stream.Subscription, replies <-chan ServerMessage, done <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ping := time.NewTicker(sh.Heartbeat)
+	defer ping.Stop()
+
+	// A failed write closes the connection, so the read loop ends as well.
+	defer conn.Close()
+
+	write := func(msg ServerMessage) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(msg)
+	}
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case reply := <-replies:
+			err = write(reply)
+		case <-sub.C:
+			// Replies go first, so a subscription is confirmed before its balance.
+			for len(replies) > 0 && err == nil {
+				err = write(<-replies)
+			}
+
+			for _, uid := range sub.Changed() {
+				if err != nil {
+					break
+				}
+
+				wallet, getErr := sh.WalletUseCase.GetByUID(uid)
+				// Unsubscribed meanwhile.
+				if !sub.Watches(uid) {
+					continue
+				}
+				if getErr != nil {
+					sub.Remove(uid)
+					err = write(ServerMessage{Type: MessageError, WalletUID: uid, Error: errorMessage(getErr)})
+					continue
+				}
+
+				err = write(ServerMessage{Type: MessageBalance, WalletUID: uid, Wallet: wallet})
+			}
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		}
+
+		if err != nil {
+			sh.Logger.Infow("can`t write to websocket",
+				"err:", err.Error())
+			return
+		}
+	}
+}
+
+func errorMessage(err error) string {
+	if errors.Is(err, models.ErrNotFound) {
+		return "wallet not found"
+	}
+
+	return "internal error"
+}
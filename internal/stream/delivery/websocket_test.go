@@ -0,0 +1,156 @@
+package delivery
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type WebSocketTestSuite struct {
+	suite.Suite
+	ss *streamServer
+}
+
+func TestWebSocketSuite(t *testing.T) {
+	// A client filling the reply queue is closed without waiting for long.
+	replyTimeout = 100 * time.Millisecond
+	suite.RunSuite(t, new(WebSocketTestSuite))
+}
+
+func (s *WebSocketTestSuite) BeforeEach(t provider.T) {
+	// No pings in the tests, a client that doesn't read would miss them and be
+	// closed for that instead.
+	s.ss = newStreamServer(t, time.Hour)
+}
+
+func (s *WebSocketTestSuite) AfterEach(t provider.T) {
+	s.ss.server.CloseClientConnections()
+	s.ss.server.Close()
+}
+
+func (s *WebSocketTestSuite) dial(t provider.T, userID int, role string) *websocket.Conn {
+	header := http.Header{}
+	header.Set("Authorization", s.ss.token(t, userID, role))
+
+	url := "ws" + strings.TrimPrefix(s.ss.server.URL, "http") + "/api/v1/stream"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	t.Require().NoError(err)
+	_ = resp.Body.Close()
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func (s *WebSocketTestSuite) send(t provider.T, conn *websocket.Conn, msgType string, walletUIDs ...string) {
+	t.Require().NoError(conn.WriteJSON(ClientMessage{Type: msgType, WalletUIDs: walletUIDs}))
+}
+
+func (s *WebSocketTestSuite) read(t provider.T, conn *websocket.Conn) ServerMessage {
+	t.Require().NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+
+	msg := ServerMessage{}
+	t.Require().NoError(conn.ReadJSON(&msg))
+
+	return msg
+}
+
+func (s *WebSocketTestSuite) TestSubscribe(t provider.T) {
+	owned := s.ss.createWallet(t, testOwnerID, 10)
+	foreign := s.ss.createWallet(t, testOtherID, 20)
+	missing := uuid.NewString()
+	conn := s.dial(t, testOwnerID, "user")
+
+	s.send(t, conn, MessageSubscribe, owned.UID, foreign.UID, missing)
+
+	t.Assert().Equal(ServerMessage{Type: MessageSubscribed, WalletUID: owned.UID}, s.read(t, conn))
+	t.Assert().Equal(ServerMessage{Type: MessageError, WalletUID: foreign.UID, Error: "wallet not found"}, s.read(t, conn))
+	t.Assert().Equal(ServerMessage{Type: MessageError, WalletUID: missing, Error: "wallet not found"}, s.read(t, conn))
+
+	balance := s.read(t, conn)
+	t.Assert().Equal(MessageBalance, balance.Type)
+	t.Assert().Equal(owned.UID, balance.WalletUID)
+	t.Require().NotNil(balance.Wallet)
+	t.Assert().Equal(10, balance.Wallet.Amount)
+
+	_, err := s.ss.wallets.ChangeAmount(owned.UID, 5)
+	t.Require().NoError(err)
+	s.ss.hub.Notify(owned.UID)
+
+	balance = s.read(t, conn)
+	t.Assert().Equal(owned.UID, balance.WalletUID)
+	t.Assert().Equal(15, balance.Wallet.Amount)
+
+	s.send(t, conn, "watch", owned.UID)
+	t.Assert().Equal(ServerMessage{Type: MessageError, Error: "unknown message type"}, s.read(t, conn))
+}
+
+func (s *WebSocketTestSuite) TestAdminSubscribesToAnyWallet(t provider.T) {
+	foreign := s.ss.createWallet(t, testOtherID, 20)
+	conn := s.dial(t, testAdminID, session.AdminRole)
+
+	s.send(t, conn, MessageSubscribe, foreign.UID)
+
+	t.Assert().Equal(ServerMessage{Type: MessageSubscribed, WalletUID: foreign.UID}, s.read(t, conn))
+	balance := s.read(t, conn)
+	t.Assert().Equal(MessageBalance, balance.Type)
+	t.Assert().Equal(20, balance.Wallet.Amount)
+}
+
+func (s *WebSocketTestSuite) TestUnsubscribe(t provider.T) {
+	first := s.ss.createWallet(t, testOwnerID)
+	second := s.ss.createWallet(t, testOwnerID)
+	conn := s.dial(t, testOwnerID, "user")
+
+	s.send(t, conn, MessageSubscribe, first.UID)
+	t.Assert().Equal(MessageSubscribed, s.read(t, conn).Type)
+	t.Assert().Equal(MessageBalance, s.read(t, conn).Type)
+
+	s.send(t, conn, MessageUnsubscribe, first.UID)
+	t.Assert().Equal(ServerMessage{Type: MessageUnsubscribed, WalletUID: first.UID}, s.read(t, conn))
+
+	s.send(t, conn, MessageSubscribe, second.UID)
+	t.Assert().Equal(MessageSubscribed, s.read(t, conn).Type)
+	t.Assert().Equal(MessageBalance, s.read(t, conn).Type)
+
+	for _, uid := range []string{first.UID, second.UID} {
+		_, err := s.ss.wallets.ChangeAmount(uid, 5)
+		t.Require().NoError(err)
+	}
+	s.ss.hub.Notify(first.UID, second.UID)
+
+	// Only the wallet still watched is sent.
+	balance := s.read(t, conn)
+	t.Assert().Equal(second.UID, balance.WalletUID)
+	t.Assert().Equal(5, balance.Wallet.Amount)
+}
+
+func (s *WebSocketTestSuite) TestSlowClientIsClosed(t provider.T) {
+	conn := s.dial(t, testOwnerID, "user")
+
+	// Every id gets a reply. The client never reads them, so they fill the
+	// socket buffers, then the reply queue, and the server gives up on it.
+	walletUIDs := make([]string, 1000)
+	for i := range walletUIDs {
+		walletUIDs[i] = uuid.NewString()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	t.Require().NoError(conn.SetWriteDeadline(deadline))
+
+	var err error
+	for err == nil && time.Now().Before(deadline) {
+		err = conn.WriteJSON(ClientMessage{Type: MessageUnsubscribe, WalletUIDs: walletUIDs})
+	}
+
+	t.Require().Error(err)
+	t.Assert().True(time.Now().Before(deadline), "the connection is closed before the write deadline")
+}
@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+// Hub tells the subscribers which of their wallets changed. It learns about the
+// changes from the outbox relay of the instance and, with Postgres, from the
+// wallet_changed notifications, so the subscribers of every instance hear
+// about every change.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[*Subscription]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscription gets a signal on C after a wallet it watches changes. Signals
+// are merged while the previous one is not taken, so a slow subscriber never
+// blocks the hub, and Changed returns all the wallets changed meanwhile.
+type Subscription struct {
+	C chan struct{}
+
+	hub     *Hub
+	mu      sync.Mutex
+	wallets map[string]struct{}
+	changed map[string]struct{}
+}
+
+// Subscribe returns a subscription watching the wallets, it has to be closed.
+func (h *Hub) Subscribe(walletUIDs ...string) *Subscription {
+	s := &Subscription{
+		C:       make(chan struct{}, 1),
+		hub:     h,
+		wallets: make(map[string]struct{}),
+		changed: make(map[string]struct{}),
+	}
+	s.Add(walletUIDs...)
+
+	return s
+}
+
+// Notify signals the subscribers of the wallets.
+func (h *Hub) Notify(walletUIDs ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, uid := range walletUIDs {
+		for s := range h.subs[uid] {
+			s.signal(uid)
+		}
+	}
+}
+
+// NotifyAll signals every subscriber about all its wallets, after changes may
+// have been missed.
+func (h *Hub) NotifyAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for uid, subs := range h.subs {
+		for s := range subs {
+			s.signal(uid)
+		}
+	}
+}
+
+// Publish lets the hub follow the outbox relay.
+func (h *Hub) Publish(_ context.Context, e *models.OutboxEvent) error {
+	h.Notify(e.WalletUID)
+	return nil
+}
+
+func (h *Hub) Close() error {
+	return nil
+}
+
+// Add starts watching the wallets.
+func (s *Subscription) Add(walletUIDs ...string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, uid := range walletUIDs {
+		if s.hub.subs[uid] == nil {
+			s.hub.subs[uid] = make(map[*Subscription]struct{})
+		}
+		s.hub.subs[uid][s] = struct{}{}
+		s.wallets[uid] = struct{}{}
+	}
+}
+
+// Remove stops watching the wallets.
+func (s *Subscription) Remove(walletUIDs ...string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, uid := range walletUIDs {
+		delete(s.hub.subs[uid], s)
+		if len(s.hub.subs[uid]) == 0 {
+			delete(s.hub.subs, uid)
+		}
+		delete(s.wallets, uid)
+		delete(s.changed, uid)
+	}
+}
+
+// Close stops watching all the wallets.
+func (s *Subscription) Close() {
+	s.mu.Lock()
+	wallets := make([]string, 0, len(s.wallets))
+	for uid := range s.wallets {
+		wallets = append(wallets, uid)
+	}
+	s.mu.Unlock()
+
+	s.Remove(wallets...)
+}
+
+// Touch signals the subscription alone, as if the wallets it watches changed.
+func (s *Subscription) Touch(walletUIDs ...string) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	for _, uid := range walletUIDs {
+		if s.Watches(uid) {
+			s.signal(uid)
+		}
+	}
+}
+
+// Watches tells whether the subscription watches the wallet.
+func (s *Subscription) Watches(walletUID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.wallets[walletUID]
+	return ok
+}
+
+// Len returns the number of wallets watched.
+func (s *Subscription) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.wallets)
+}
+
+// Changed returns the wallets changed since the previous call.
+func (s *Subscription) Changed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := make([]string, 0, len(s.changed))
+	for uid := range s.changed {
+		changed = append(changed, uid)
+	}
+	clear(s.changed)
+
+	return changed
+}
+
+func (s *Subscription) signal(walletUID string) {
+	s.mu.Lock()
+	s.changed[walletUID] = struct{}{}
+	s.mu.Unlock()
+
+	select {
+	case s.C <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type HubTestSuite struct {
+	suite.Suite
+	hub *Hub
+}
+
+func TestHubSuite(t *testing.T) {
+	suite.RunSuite(t, new(HubTestSuite))
+}
+
+func (s *HubTestSuite) BeforeEach(t provider.T) {
+	s.hub = NewHub()
+}
+
+// signaled tells whether the subscription has a signal waiting.
+func signaled(sub *Subscription) bool {
+	select {
+	case <-sub.C:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *HubTestSuite) TestNotify(t provider.T) {
+	sub := s.hub.Subscribe("first", "second")
+	defer sub.Close()
+
+	s.hub.Notify("other")
+	t.Assert().False(signaled(sub))
+
+	s.hub.Notify("first")
+	t.Require().NoError(s.hub.Publish(context.Background(), &models.OutboxEvent{WalletUID: "second"}))
+
+	t.Assert().True(signaled(sub))
+	t.Assert().False(signaled(sub))
+	t.Assert().ElementsMatch([]string{"first", "second"}, sub.Changed())
+	t.Assert().Empty(sub.Changed())
+}
+
+func (s *HubTestSuite) TestNotifyAll(t provider.T) {
+	sub := s.hub.Subscribe("first", "second")
+	defer sub.Close()
+
+	s.hub.NotifyAll()
+
+	t.Assert().True(signaled(sub))
+	t.Assert().ElementsMatch([]string{"first", "second"}, sub.Changed())
+}
+
+func (s *HubTestSuite) TestRemove(t provider.T) {
+	sub := s.hub.Subscribe("first")
+	sub.Add("second")
+	sub.Remove("first")
+
+	s.hub.Notify("first")
+	t.Assert().False(signaled(sub))
+
+	s.hub.Notify("second")
+	t.Assert().True(signaled(sub))
+
+	sub.Close()
+	s.hub.Notify("second")
+
+	t.Assert().False(signaled(sub))
+	t.Assert().Empty(s.hub.subs)
+}
+
+func (s *HubTestSuite) TestTouch(t provider.T) {
+	sub := s.hub.Subscribe("first")
+	defer sub.Close()
+	other := s.hub.Subscribe("first")
+	defer other.Close()
+
+	sub.Touch("first", "unwatched")
+
+	t.Assert().True(signaled(sub))
+	t.Assert().Equal([]string{"first"}, sub.Changed())
+	t.Assert().False(signaled(other))
+	t.Assert().True(sub.Watches("first"))
+	t.Assert().False(sub.Watches("unwatched"))
+	t.Assert().Equal(1, sub.Len())
+}
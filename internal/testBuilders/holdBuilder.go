@@ -0,0 +1,49 @@
+package testBuilders
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+type HoldBuilder struct {
+	hold models.Hold
+}
+
+func NewHoldBuilder() *HoldBuilder {
+	return &HoldBuilder{}
+}
+
+func (b *HoldBuilder) WithID(id int) *HoldBuilder {
+	b.hold.ID = id
+	return b
+}
+
+func (b *HoldBuilder) WithUID(uid string) *HoldBuilder {
+	b.hold.UID = uid
+	return b
+}
+
+func (b *HoldBuilder) WithWalletUID(walletUID string) *HoldBuilder {
+	b.hold.WalletUID = walletUID
+	return b
+}
+
+func (b *HoldBuilder) WithAmount(amount int) *HoldBuilder {
+	b.hold.Amount = amount
+	return b
+}
+
+func (b *HoldBuilder) WithStatus(status string) *HoldBuilder {
+	b.hold.Status = status
+	return b
+}
+
+func (b *HoldBuilder) WithExpiresAt(expiresAt time.Time) *HoldBuilder {
+	b.hold.ExpiresAt = expiresAt
+	return b
+}
+
+func (b *HoldBuilder) Build() models.Hold {
+	return b.hold
+}
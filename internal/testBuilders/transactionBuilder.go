@@ -0,0 +1,52 @@
+package testBuilders
+
+import (
+	"github.com/Davmie/javaCode/models"
+)
+
+type TransactionBuilder struct {
+	transaction models.Transaction
+}
+
+func NewTransactionBuilder() *TransactionBuilder {
+	return &TransactionBuilder{}
+}
+
+func (b *TransactionBuilder) WithID(id int) *TransactionBuilder {
+	b.transaction.ID = id
+	return b
+}
+
+func (b *TransactionBuilder) WithUID(uid string) *TransactionBuilder {
+	b.transaction.UID = uid
+	return b
+}
+
+func (b *TransactionBuilder) WithWalletUID(walletUID string) *TransactionBuilder {
+	b.transaction.WalletUID = walletUID
+	return b
+}
+
+func (b *TransactionBuilder) WithType(transactionType string) *TransactionBuilder {
+	b.transaction.Type = transactionType
+	return b
+}
+
+func (b *TransactionBuilder) WithAmount(amount int) *TransactionBuilder {
+	b.transaction.Amount = amount
+	return b
+}
+
+func (b *TransactionBuilder) WithReversed(reversed int) *TransactionBuilder {
+	b.transaction.Reversed = reversed
+	return b
+}
+
+func (b *TransactionBuilder) WithReversalOf(uid string) *TransactionBuilder {
+	b.transaction.ReversalOf = &uid
+	return b
+}
+
+func (b *TransactionBuilder) Build() models.Transaction {
+	return b.transaction
+}
@@ -22,11 +22,26 @@ func (b *WalletBuilder) WithUID(uid string) *WalletBuilder {
 	return b
 }
 
+func (b *WalletBuilder) WithName(name string) *WalletBuilder {
+	b.wallet.Name = name
+	return b
+}
+
 func (b *WalletBuilder) WithAmount(amount int) *WalletBuilder {
 	b.wallet.Amount = amount
 	return b
 }
 
+func (b *WalletBuilder) WithHeld(held int) *WalletBuilder {
+	b.wallet.Held = held
+	return b
+}
+
+func (b *WalletBuilder) WithStatus(status string) *WalletBuilder {
+	b.wallet.Status = status
+	return b
+}
+
 func (b *WalletBuilder) Build() models.Wallet {
 	return b.wallet
 }
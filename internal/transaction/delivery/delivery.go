@@ -0,0 +1,148 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type TransactionHandler struct {
+	TransactionUseCase transactionUseCase.TransactionUseCaseI
+	Logger             logger.Logger
+}
+
+type ReverseRequest struct {
+	Amount int `json:"amount"`
+}
+
+func (th *TransactionHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
+	transactionUID := r.PathValue("TRANSACTION_UUID")
+	if transactionUID == "" {
+		th.Logger.Errorw("no TRANSACTION_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	transaction, err := th.TransactionUseCase.GetByUID(transactionUID)
+	if err != nil {
+		th.Logger.Infow("can`t get transaction",
+			"err:", err.Error())
+		http.Error(w, "can`t get transaction", errorStatus(err))
+		return
+	}
+
+	th.writeJSON(w, http.StatusOK, transaction)
+}
+
+func (th *TransactionHandler) GetByWalletUID(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		th.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	transactions, err := th.TransactionUseCase.GetByWalletUID(walletUID)
+	if err != nil {
+		th.Logger.Infow("can`t get transactions",
+			"err:", err.Error())
+		http.Error(w, "can`t get transactions", errorStatus(err))
+		return
+	}
+
+	th.writeJSON(w, http.StatusOK, transactions)
+}
+
+func (th *TransactionHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	transactionUID := r.PathValue("TRANSACTION_UUID")
+	if transactionUID == "" {
+		th.Logger.Errorw("no TRANSACTION_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	reverseReq := ReverseRequest{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		th.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		th.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &reverseReq)
+		if err != nil {
+			th.Logger.Infow("can`t unmarshal form",
+				"err:", err.Error())
+			http.Error(w, "bad data", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if reverseReq.Amount < 0 {
+		th.Logger.Infow("can`t reverse transaction",
+			"err", "amount must not be negative")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	reversal, err := th.TransactionUseCase.Reverse(transactionUID, reverseReq.Amount)
+	if err != nil {
+		th.Logger.Infow("can`t reverse transaction",
+			"err:", err.Error())
+		http.Error(w, "can`t reverse transaction", errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/transactions/%s", reversal.UID))
+	th.writeJSON(w, http.StatusCreated, reversal)
+}
+
+func (th *TransactionHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		th.Logger.Errorw("can`t marshal transaction",
+			"err:", err.Error())
+		http.Error(w, "can`t make transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		th.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInsufficientFunds),
+		errors.Is(err, models.ErrWalletFrozen),
+		errors.Is(err, models.ErrWalletClosed),
+		errors.Is(err, models.ErrNotReversible),
+		errors.Is(err, models.ErrAlreadyReversed),
+		errors.Is(err, models.ErrReversalExceeds):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
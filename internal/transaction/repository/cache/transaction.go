@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"github.com/Davmie/javaCode/internal/transaction/repository"
+	walletCache "github.com/Davmie/javaCode/internal/wallet/repository/cache"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+)
+
+// cachedTransactionRepo evicts the cached wallets whose balance is changed by a reversal.
+type cachedTransactionRepo struct {
+	Logger logger.Logger
+	Repo   repository.TransactionRepositoryI
+	Cache  *walletCache.WalletCache
+}
+
+func New(logger logger.Logger, repo repository.TransactionRepositoryI, cache *walletCache.WalletCache) repository.TransactionRepositoryI {
+	return &cachedTransactionRepo{
+		Logger: logger,
+		Repo:   repo,
+		Cache:  cache,
+	}
+}
+
+func (cr *cachedTransactionRepo) GetByUID(uid string) (*models.Transaction, error) {
+	return cr.Repo.GetByUID(uid)
+}
+
+func (cr *cachedTransactionRepo) GetByWalletUID(walletUID string) ([]*models.Transaction, error) {
+	return cr.Repo.GetByWalletUID(walletUID)
+}
+
+func (cr *cachedTransactionRepo) GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error) {
+	return cr.Repo.GetByWalletUIDAfter(walletUID, afterID, limit)
+}
+
+func (cr *cachedTransactionRepo) LastID(walletUID string) (int, error) {
+	return cr.Repo.LastID(walletUID)
+}
+
+func (cr *cachedTransactionRepo) Reverse(r *models.Transaction) error {
+	if err := cr.Repo.Reverse(r); err != nil {
+		return err
+	}
+
+	cr.Cache.Invalidate(r.WalletUID)
+
+	return nil
+}
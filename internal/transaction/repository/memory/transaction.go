@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/internal/transaction/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type memTransactionRepo struct {
+	Logger logger.Logger
+	Store  *memstore.Store
+}
+
+func New(logger logger.Logger, store *memstore.Store) repository.TransactionRepositoryI {
+	return &memTransactionRepo{
+		Logger: logger,
+		Store:  store,
+	}
+}
+
+func (mr *memTransactionRepo) GetByUID(uid string) (*models.Transaction, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	t := mr.byUID(uid)
+	if t == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memTransactionRepo.GetByUID error")
+	}
+
+	res := *t
+	return &res, nil
+}
+
+func (mr *memTransactionRepo) GetByWalletUID(walletUID string) ([]*models.Transaction, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var transactions []*models.Transaction
+	for _, t := range mr.Store.Transactions {
+		if t.WalletUID == walletUID {
+			res := *t
+			transactions = append(transactions, &res)
+		}
+	}
+
+	return transactions, nil
+}
+
+func (mr *memTransactionRepo) GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var transactions []*models.Transaction
+	for _, t := range mr.Store.Transactions {
+		if len(transactions) == limit {
+			break
+		}
+		if t.WalletUID == walletUID && t.ID > afterID {
+			res := *t
+			transactions = append(transactions, &res)
+		}
+	}
+
+	return transactions, nil
+}
+
+func (mr *memTransactionRepo) LastID(walletUID string) (int, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	id := 0
+	for _, t := range mr.Store.Transactions {
+		if t.WalletUID == walletUID {
+			id = max(id, t.ID)
+		}
+	}
+
+	return id, nil
+}
+
+// Reverse stores the compensating entry r for the transaction *r.ReversalOf and
+// applies it to the wallet balance.
+func (mr *memTransactionRepo) Reverse(r *models.Transaction) error {
+	if r.ReversalOf == nil {
+		return errors.Wrap(models.ErrNotReversible, "memTransactionRepo.Reverse error")
+	}
+
+	amount := r.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	original := mr.byUID(*r.ReversalOf)
+	if original == nil {
+		return errors.Wrap(models.ErrNotFound, "memTransactionRepo.Reverse error")
+	}
+
+	total := original.Amount
+	if total < 0 {
+		total = -total
+	}
+
+	switch {
+	case original.Type == models.TransactionTypeReversal:
+		return errors.Wrap(models.ErrNotReversible, "memTransactionRepo.Reverse error")
+	case original.Reversed == total:
+		return errors.Wrap(models.ErrAlreadyReversed, "memTransactionRepo.Reverse error")
+	case original.Reversed+amount > total:
+		return errors.Wrap(models.ErrReversalExceeds, "memTransactionRepo.Reverse error")
+	case original.WalletUID != r.WalletUID || (original.Amount > 0) == (r.Amount > 0):
+		return errors.Wrap(models.ErrNotReversible, "memTransactionRepo.Reverse error")
+	}
+
+	w, err := mr.Store.ApplyDelta(r.WalletUID, r.Amount)
+	if err != nil {
+		return errors.Wrap(err, "memTransactionRepo.Reverse error")
+	}
+
+	original.Reversed += amount
+	mr.Store.AddTransaction(r)
+	mr.Store.AddEvents(outbox.BalanceChanged(r, w))
+
+	return nil
+}
+
+func (mr *memTransactionRepo) byUID(uid string) *models.Transaction {
+	for _, t := range mr.Store.Transactions {
+		if t.UID == uid {
+			return t
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	transactionRep "github.com/Davmie/javaCode/internal/transaction/repository"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	walletMem "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+type TransactionMemRepoTestSuite struct {
+	suite.Suite
+	repo       transactionRep.TransactionRepositoryI
+	walletRepo walletRep.WalletRepositoryI
+	original   models.Transaction
+}
+
+func TestTransactionMemRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(TransactionMemRepoTestSuite))
+}
+
+func (s *TransactionMemRepoTestSuite) BeforeEach(t provider.T) {
+	var logger logger.Logger
+
+	store := memstore.New()
+	s.repo = New(logger, store)
+	s.walletRepo = walletMem.New(logger, store)
+
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	err := s.walletRepo.Create(&wallet)
+	t.Require().NoError(err)
+
+	s.original = testBuilders.NewTransactionBuilder().
+		WithUID("deposit").
+		WithWalletUID(wallet.UID).
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		Build()
+	_, err = s.walletRepo.ChangeAmount(&s.original)
+	t.Require().NoError(err)
+}
+
+func (s *TransactionMemRepoTestSuite) reversal(amount int) *models.Transaction {
+	reversal := testBuilders.NewTransactionBuilder().
+		WithUID("reversal").
+		WithWalletUID(s.original.WalletUID).
+		WithType(models.TransactionTypeReversal).
+		WithAmount(-amount).
+		WithReversalOf(s.original.UID).
+		Build()
+
+	return &reversal
+}
+
+func (s *TransactionMemRepoTestSuite) TestGetByWalletUID(t provider.T) {
+	transactions, err := s.repo.GetByWalletUID(s.original.WalletUID)
+	t.Assert().NoError(err)
+	t.Assert().Len(transactions, 1)
+
+	_, err = s.repo.GetByUID("unknown")
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *TransactionMemRepoTestSuite) TestGetByWalletUIDAfter(t provider.T) {
+	t.Require().NoError(s.repo.Reverse(s.reversal(30)))
+
+	last, err := s.repo.LastID(s.original.WalletUID)
+	t.Require().NoError(err)
+
+	transactions, err := s.repo.GetByWalletUIDAfter(s.original.WalletUID, 0, 10)
+	t.Require().NoError(err)
+	t.Require().Len(transactions, 2)
+	t.Assert().Equal(last, transactions[1].ID)
+
+	transactions, err = s.repo.GetByWalletUIDAfter(s.original.WalletUID, transactions[0].ID, 10)
+	t.Require().NoError(err)
+	t.Require().Len(transactions, 1)
+	t.Assert().Equal("reversal", transactions[0].UID)
+
+	last, err = s.repo.LastID("unknown")
+	t.Assert().NoError(err)
+	t.Assert().Zero(last)
+}
+
+func (s *TransactionMemRepoTestSuite) TestReverse(t provider.T) {
+	err := s.repo.Reverse(s.reversal(150))
+	t.Assert().ErrorIs(err, models.ErrReversalExceeds)
+
+	err = s.repo.Reverse(s.reversal(40))
+	t.Assert().NoError(err)
+
+	err = s.repo.Reverse(s.reversal(60))
+	t.Assert().NoError(err)
+
+	err = s.repo.Reverse(s.reversal(1))
+	t.Assert().ErrorIs(err, models.ErrAlreadyReversed)
+
+	original, err := s.repo.GetByUID(s.original.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(100, original.Reversed)
+
+	wallet, err := s.walletRepo.GetByUID(s.original.WalletUID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, wallet.Amount)
+}
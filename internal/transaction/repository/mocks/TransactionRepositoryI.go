@@ -0,0 +1,143 @@
+// Code generated by mockery v2.33.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	models "github.com/Davmie/javaCode/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// TransactionRepositoryI is an autogenerated mock type for the TransactionRepositoryI type
+type TransactionRepositoryI struct {
+	mock.Mock
+}
+
+// GetByUID provides a mock function with given fields: uid
+func (_m *TransactionRepositoryI) GetByUID(uid string) (*models.Transaction, error) {
+	ret := _m.Called(uid)
+
+	var r0 *models.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.Transaction, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Transaction); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByWalletUID provides a mock function with given fields: walletUID
+func (_m *TransactionRepositoryI) GetByWalletUID(walletUID string) ([]*models.Transaction, error) {
+	ret := _m.Called(walletUID)
+
+	var r0 []*models.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]*models.Transaction, error)); ok {
+		return rf(walletUID)
+	}
+	if rf, ok := ret.Get(0).(func(string) []*models.Transaction); ok {
+		r0 = rf(walletUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(walletUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByWalletUIDAfter provides a mock function with given fields: walletUID, afterID, limit
+func (_m *TransactionRepositoryI) GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error) {
+	ret := _m.Called(walletUID, afterID, limit)
+
+	var r0 []*models.Transaction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int, int) ([]*models.Transaction, error)); ok {
+		return rf(walletUID, afterID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(string, int, int) []*models.Transaction); ok {
+		r0 = rf(walletUID, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Transaction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int, int) error); ok {
+		r1 = rf(walletUID, afterID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LastID provides a mock function with given fields: walletUID
+func (_m *TransactionRepositoryI) LastID(walletUID string) (int, error) {
+	ret := _m.Called(walletUID)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(walletUID)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(walletUID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(walletUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reverse provides a mock function with given fields: r
+func (_m *TransactionRepositoryI) Reverse(r *models.Transaction) error {
+	ret := _m.Called(r)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Transaction) error); ok {
+		r0 = rf(r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewTransactionRepositoryI creates a new instance of TransactionRepositoryI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewTransactionRepositoryI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *TransactionRepositoryI {
+	mock := &TransactionRepositoryI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/internal/transaction/repository"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pgTransactionRepo struct {
+	Logger logger.Logger
+	DB     *gorm.DB
+}
+
+func New(logger logger.Logger, db *gorm.DB) repository.TransactionRepositoryI {
+	return &pgTransactionRepo{
+		Logger: logger,
+		DB:     db,
+	}
+}
+
+func (pr *pgTransactionRepo) GetByUID(uid string) (*models.Transaction, error) {
+	var t models.Transaction
+	tx := pr.DB.Where("uid = ?", uid).Take(&t)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgTransactionRepo.GetByUID error")
+	}
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgTransactionRepo.GetByUID error")
+	}
+
+	return &t, nil
+}
+
+func (pr *pgTransactionRepo) GetByWalletUID(walletUID string) ([]*models.Transaction, error) {
+	var transactions []*models.Transaction
+
+	tx := pr.DB.Where("wallet_uid = ?", walletUID).Order("id").Find(&transactions)
+
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgTransactionRepo.GetByWalletUID error")
+	}
+
+	return transactions, nil
+}
+
+// GetByWalletUIDAfter reads the entries once the deposits still running into
+// the shards of the wallet are over, see walletPg.WaitForShards.
+func (pr *pgTransactionRepo) GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error) {
+	var transactions []*models.Transaction
+
+	err := dbtx.Run(pr.DB, "transaction.get_after", func(tx *gorm.DB) error {
+		transactions = nil
+
+		if err := walletPg.WaitForShards(tx, walletUID); err != nil {
+			return err
+		}
+
+		return tx.Where("wallet_uid = ? AND id > ?", walletUID, afterID).Order("id").Limit(limit).Find(&transactions).Error
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgTransactionRepo.GetByWalletUIDAfter error")
+	}
+
+	return transactions, nil
+}
+
+func (pr *pgTransactionRepo) LastID(walletUID string) (int, error) {
+	var id int
+
+	tx := pr.DB.Model(&models.Transaction{}).Select("COALESCE(MAX(id), 0)").Where("wallet_uid = ?", walletUID).Scan(&id)
+
+	if tx.Error != nil {
+		return 0, errors.Wrap(tx.Error, "pgTransactionRepo.LastID error")
+	}
+
+	return id, nil
+}
+
+// Reverse stores the compensating entry r for the transaction *r.ReversalOf.
+// The reversed amount of the original is bumped in the same statement that checks
+// it, so concurrent reversals can't refund more than the original amount.
+func (pr *pgTransactionRepo) Reverse(r *models.Transaction) error {
+	if r.ReversalOf == nil {
+		return errors.Wrap(models.ErrNotReversible, "pgTransactionRepo.Reverse error")
+	}
+
+	amount := r.Amount
+	if amount < 0 {
+		amount = -amount
+	}
+
+	err := dbtx.Run(pr.DB, "transaction.reverse", func(tx *gorm.DB) error {
+		// A failed attempt leaves the id of its insert behind.
+		r.ID = 0
+
+		var original models.Transaction
+		res := tx.Model(&original).Clauses(clause.Returning{}).
+			Where("uid = ? AND type <> ? AND reversed + ? <= ABS(amount)", *r.ReversalOf, models.TransactionTypeReversal, amount).
+			Update("reversed", gorm.Expr("reversed + ?", amount))
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return pr.explain(tx, *r.ReversalOf)
+		}
+
+		if original.WalletUID != r.WalletUID || (original.Amount > 0) == (r.Amount > 0) {
+			return models.ErrNotReversible
+		}
+
+		w, err := walletPg.ApplyDelta(tx, r.WalletUID, r.Amount)
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Create(r).Error; err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.BalanceChanged(r, w))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgTransactionRepo.Reverse error")
+	}
+
+	return nil
+}
+
+// explain finds out why the original transaction matched no rows on reversal.
+func (pr *pgTransactionRepo) explain(tx *gorm.DB, uid string) error {
+	var t models.Transaction
+	res := tx.Where("uid = ?", uid).Take(&t)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return models.ErrNotFound
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	switch {
+	case t.Type == models.TransactionTypeReversal:
+		return models.ErrNotReversible
+	case t.Amount == t.Reversed || t.Amount == -t.Reversed:
+		return models.ErrAlreadyReversed
+	default:
+		return models.ErrReversalExceeds
+	}
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	transactionRep "github.com/Davmie/javaCode/internal/transaction/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type TransactionRepoTestSuite struct {
+	suite.Suite
+	db                 *sql.DB
+	gormDB             *gorm.DB
+	mock               sqlmock.Sqlmock
+	repo               transactionRep.TransactionRepositoryI
+	transactionBuilder *testBuilders.TransactionBuilder
+}
+
+func TestTransactionRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(TransactionRepoTestSuite))
+}
+
+func (s *TransactionRepoTestSuite) BeforeEach(t provider.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal("error while creating sql mock")
+	}
+
+	dialector := postgres.New(postgres.Config{
+		DSN:                  "sqlmock_db_0",
+		DriverName:           "postgres",
+		Conn:                 db,
+		PreferSimpleProtocol: true,
+	})
+	gormDB, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		t.Fatal("error gorm open")
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.gormDB = gormDB
+	s.mock = mock
+
+	s.repo = New(logger, gormDB)
+	s.transactionBuilder = testBuilders.NewTransactionBuilder()
+}
+
+func (s *TransactionRepoTestSuite) AfterEach(t provider.T) {
+	err := s.mock.ExpectationsWereMet()
+	t.Assert().NoError(err)
+	s.db.Close()
+}
+
+func (s *TransactionRepoTestSuite) TestGetByWalletUID(t provider.T) {
+	transaction := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		Build()
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "type", "amount", "reversed"}).
+		AddRow(transaction.ID, transaction.UID, transaction.WalletUID, transaction.Type, transaction.Amount, transaction.Reversed)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "transaction" WHERE wallet_uid = $1 ORDER BY id`)).
+		WithArgs(transaction.WalletUID).
+		WillReturnRows(rows)
+
+	transactions, err := s.repo.GetByWalletUID(transaction.WalletUID)
+	t.Assert().NoError(err)
+	t.Assert().Equal([]*models.Transaction{&transaction}, transactions)
+}
+
+func (s *TransactionRepoTestSuite) TestGetByWalletUIDAfter(t provider.T) {
+	rows := sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "type", "amount"}).
+		AddRow(5, "deposit", "wallet", models.TransactionTypeDeposit, 100)
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT "shard" FROM "wallet_shard" WHERE wallet_uid = $1 ORDER BY shard FOR SHARE`)).
+		WithArgs("wallet").
+		WillReturnRows(sqlmock.NewRows([]string{"shard"}).AddRow(0).AddRow(1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "transaction" WHERE wallet_uid = $1 AND id > $2 ORDER BY id LIMIT $3`)).
+		WithArgs("wallet", 4, 100).
+		WillReturnRows(rows)
+	s.mock.ExpectCommit()
+
+	transactions, err := s.repo.GetByWalletUIDAfter("wallet", 4, 100)
+	t.Assert().NoError(err)
+	t.Require().Len(transactions, 1)
+	t.Assert().Equal(5, transactions[0].ID)
+}
+
+func (s *TransactionRepoTestSuite) TestLastID(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT COALESCE(MAX(id), 0) FROM "transaction" WHERE wallet_uid = $1`)).
+		WithArgs("wallet").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(7))
+
+	id, err := s.repo.LastID("wallet")
+	t.Assert().NoError(err)
+	t.Assert().Equal(7, id)
+}
+
+func (s *TransactionRepoTestSuite) TestReverse(t provider.T) {
+	original := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		Build()
+
+	reversal := testBuilders.NewTransactionBuilder().
+		WithUID("reversal").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeReversal).
+		WithAmount(-40).
+		WithReversalOf(original.UID).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "transaction" SET "reversed"=reversed + $1 WHERE uid = $2 AND type <> $3 AND reversed + $4 <= ABS(amount) RETURNING *`)).
+		WithArgs(40, original.UID, models.TransactionTypeReversal, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "type", "amount", "reversed"}).
+			AddRow(original.ID, original.UID, original.WalletUID, original.Type, original.Amount, 40))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(-40, original.WalletUID, models.WalletStatusActive, -40, -40).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held"}).AddRow(1, original.WalletUID, 60, 0))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "transaction" ("uid","wallet_uid","type","amount","reversed","reversal_of","reason_code","comment","actor_id","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+		WithArgs(reversal.UID, reversal.WalletUID, reversal.Type, reversal.Amount, 0, original.UID, "", "", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "outbox_event" ("uid","type","wallet_uid","owner_id","payload","created_at","published_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(sqlmock.AnyArg(), models.EventWalletBalanceChanged, reversal.WalletUID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	s.mock.ExpectCommit()
+
+	err := s.repo.Reverse(&reversal)
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, reversal.ID)
+}
+
+func (s *TransactionRepoTestSuite) TestReverseAlreadyReversed(t provider.T) {
+	original := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		WithReversed(100).
+		Build()
+
+	reversal := testBuilders.NewTransactionBuilder().
+		WithUID("reversal").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeReversal).
+		WithAmount(-100).
+		WithReversalOf(original.UID).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "transaction" SET "reversed"=reversed + $1 WHERE uid = $2 AND type <> $3 AND reversed + $4 <= ABS(amount) RETURNING *`)).
+		WithArgs(100, original.UID, models.TransactionTypeReversal, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "transaction" WHERE uid = $1 LIMIT $2`)).
+		WithArgs(original.UID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "wallet_uid", "type", "amount", "reversed"}).
+			AddRow(original.ID, original.UID, original.WalletUID, original.Type, original.Amount, original.Reversed))
+
+	s.mock.ExpectRollback()
+
+	err := s.repo.Reverse(&reversal)
+	t.Assert().ErrorIs(err, models.ErrAlreadyReversed)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import "github.com/Davmie/javaCode/models"
+
+type TransactionRepositoryI interface {
+	GetByUID(uid string) (*models.Transaction, error)
+	GetByWalletUID(walletUID string) ([]*models.Transaction, error)
+	// GetByWalletUIDAfter returns at most limit entries of the wallet with ids
+	// above afterID, in the order of their ids. Entries committed later never
+	// get lower ids than the ones returned, so following the ledger by the last
+	// id misses nothing.
+	GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error)
+	// LastID returns the id of the latest entry of the wallet, 0 without entries.
+	LastID(walletUID string) (int, error)
+	Reverse(r *models.Transaction) error
+}
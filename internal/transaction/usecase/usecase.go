@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"time"
+
+	transactionRep "github.com/Davmie/javaCode/internal/transaction/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type TransactionUseCaseI interface {
+	GetByUID(uid string) (*models.Transaction, error)
+	GetByWalletUID(walletUID string) ([]*models.Transaction, error)
+	GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error)
+	LastID(walletUID string) (int, error)
+	Reverse(uid string, amount int) (*models.Transaction, error)
+}
+
+type transactionUseCase struct {
+	transactionRepository transactionRep.TransactionRepositoryI
+	now                   func() time.Time
+}
+
+func New(tRep transactionRep.TransactionRepositoryI) TransactionUseCaseI {
+	return &transactionUseCase{
+		transactionRepository: tRep,
+		now:                   time.Now,
+	}
+}
+
+func (tUC *transactionUseCase) GetByUID(uid string) (*models.Transaction, error) {
+	t, err := tUC.transactionRepository.GetByUID(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "transactionUseCase.GetByUID error")
+	}
+
+	return t, nil
+}
+
+func (tUC *transactionUseCase) GetByWalletUID(walletUID string) ([]*models.Transaction, error) {
+	transactions, err := tUC.transactionRepository.GetByWalletUID(walletUID)
+	if err != nil {
+		return nil, errors.Wrap(err, "transactionUseCase.GetByWalletUID error")
+	}
+
+	return transactions, nil
+}
+
+func (tUC *transactionUseCase) GetByWalletUIDAfter(walletUID string, afterID int, limit int) ([]*models.Transaction, error) {
+	transactions, err := tUC.transactionRepository.GetByWalletUIDAfter(walletUID, afterID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "transactionUseCase.GetByWalletUIDAfter error")
+	}
+
+	return transactions, nil
+}
+
+func (tUC *transactionUseCase) LastID(walletUID string) (int, error) {
+	id, err := tUC.transactionRepository.LastID(walletUID)
+	if err != nil {
+		return 0, errors.Wrap(err, "transactionUseCase.LastID error")
+	}
+
+	return id, nil
+}
+
+// Reverse compensates amount of the transaction uid, zero means whatever is
+// not reversed yet. Reversing a deposit debits the wallet, so it is subject to
+// the usual insufficient funds check.
+func (tUC *transactionUseCase) Reverse(uid string, amount int) (*models.Transaction, error) {
+	original, err := tUC.transactionRepository.GetByUID(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "transactionUseCase.Reverse error: Transaction not found")
+	}
+
+	if original.Type == models.TransactionTypeReversal {
+		return nil, errors.Wrap(models.ErrNotReversible, "transactionUseCase.Reverse error")
+	}
+
+	remaining := original.Amount
+	if remaining < 0 {
+		remaining = -remaining
+	}
+	remaining -= original.Reversed
+
+	if remaining == 0 {
+		return nil, errors.Wrap(models.ErrAlreadyReversed, "transactionUseCase.Reverse error")
+	}
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return nil, errors.Wrap(models.ErrReversalExceeds, "transactionUseCase.Reverse error")
+	}
+
+	if original.Amount > 0 {
+		amount = -amount
+	}
+
+	r := &models.Transaction{
+		UID:        uuid.NewString(),
+		WalletUID:  original.WalletUID,
+		Type:       models.TransactionTypeReversal,
+		Amount:     amount,
+		ReversalOf: &original.UID,
+		CreatedAt:  tUC.now(),
+	}
+
+	err = tUC.transactionRepository.Reverse(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "transactionUseCase.Reverse error")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	transactionMocks "github.com/Davmie/javaCode/internal/transaction/repository/mocks"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+)
+
+type TransactionTestSuite struct {
+	suite.Suite
+	uc                  TransactionUseCaseI
+	transactionRepoMock *transactionMocks.TransactionRepositoryI
+	transactionBuilder  *testBuilders.TransactionBuilder
+}
+
+func TestTransactionTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(TransactionTestSuite))
+}
+
+func (s *TransactionTestSuite) BeforeEach(t provider.T) {
+	s.transactionRepoMock = transactionMocks.NewTransactionRepositoryI(t)
+	s.uc = &transactionUseCase{
+		transactionRepository: s.transactionRepoMock,
+		now:                   time.Now,
+	}
+	s.transactionBuilder = testBuilders.NewTransactionBuilder()
+}
+
+func (s *TransactionTestSuite) TestGetByWalletUID(t provider.T) {
+	deposit := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		Build()
+
+	s.transactionRepoMock.On("GetByWalletUID", "wallet").Return([]*models.Transaction{&deposit}, nil)
+	result, err := s.uc.GetByWalletUID("wallet")
+
+	t.Assert().NoError(err)
+	t.Assert().Equal([]*models.Transaction{&deposit}, result)
+}
+
+func (s *TransactionTestSuite) TestReverseDeposit(t provider.T) {
+	deposit := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		WithReversed(30).
+		Build()
+
+	s.transactionRepoMock.On("GetByUID", deposit.UID).Return(&deposit, nil)
+	s.transactionRepoMock.On("Reverse", mock.MatchedBy(func(r *models.Transaction) bool {
+		return r.Type == models.TransactionTypeReversal && r.WalletUID == "wallet" &&
+			r.ReversalOf != nil && *r.ReversalOf == deposit.UID
+	})).Return(nil)
+
+	cases := map[string]struct {
+		Amount   int
+		Expected int
+		Error    error
+	}{
+		"partial": {
+			Amount:   20,
+			Expected: -20,
+		},
+		"remaining": {
+			Amount:   0,
+			Expected: -70,
+		},
+		"exceeds": {
+			Amount: 80,
+			Error:  models.ErrReversalExceeds,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			reversal, err := s.uc.Reverse(deposit.UID, test.Amount)
+			t.Assert().ErrorIs(err, test.Error)
+			if test.Error == nil {
+				t.Assert().Equal(test.Expected, reversal.Amount)
+			}
+		})
+	}
+}
+
+func (s *TransactionTestSuite) TestReverseWithdraw(t provider.T) {
+	withdraw := s.transactionBuilder.
+		WithID(1).
+		WithUID("withdraw").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeWithdraw).
+		WithAmount(-100).
+		Build()
+
+	s.transactionRepoMock.On("GetByUID", withdraw.UID).Return(&withdraw, nil)
+	s.transactionRepoMock.On("Reverse", mock.Anything).Return(nil)
+
+	reversal, err := s.uc.Reverse(withdraw.UID, 0)
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(100, reversal.Amount)
+}
+
+func (s *TransactionTestSuite) TestReverseAlreadyReversed(t provider.T) {
+	deposit := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		WithReversed(100).
+		Build()
+
+	s.transactionRepoMock.On("GetByUID", deposit.UID).Return(&deposit, nil)
+
+	_, err := s.uc.Reverse(deposit.UID, 0)
+
+	t.Assert().ErrorIs(err, models.ErrAlreadyReversed)
+}
+
+func (s *TransactionTestSuite) TestReverseReversal(t provider.T) {
+	reversal := s.transactionBuilder.
+		WithID(2).
+		WithUID("reversal").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeReversal).
+		WithAmount(-100).
+		WithReversalOf("deposit").
+		Build()
+
+	s.transactionRepoMock.On("GetByUID", reversal.UID).Return(&reversal, nil)
+
+	_, err := s.uc.Reverse(reversal.UID, 0)
+
+	t.Assert().ErrorIs(err, models.ErrNotReversible)
+}
+
+func (s *TransactionTestSuite) TestReverseInsufficientFunds(t provider.T) {
+	deposit := s.transactionBuilder.
+		WithID(1).
+		WithUID("deposit").
+		WithWalletUID("wallet").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(100).
+		Build()
+
+	s.transactionRepoMock.On("GetByUID", deposit.UID).Return(&deposit, nil)
+	s.transactionRepoMock.On("Reverse", mock.Anything).Return(errors.Wrap(models.ErrInsufficientFunds, "pgTransactionRepo.Reverse error"))
+
+	_, err := s.uc.Reverse(deposit.UID, 0)
+
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
@@ -1,25 +1,56 @@
 package delivery
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Davmie/javaCode/models"
 	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/Davmie/javaCode/pkg/session"
 	"github.com/asaskevich/govalidator"
+	"github.com/pkg/errors"
 )
 
+type UserContextManager interface {
+	UserIDFromContext(context.Context) (int, error)
+	UserRoleFromContext(context.Context) (string, error)
+	PrimaryReadFromContext(context.Context) bool
+}
+
 type WalletHandler struct {
 	WalletUseCase walletUseCase.WalletUseCaseI
-	Logger        logger.Logger
+	// ReplicaUseCase serves the reads when replicas are configured, nil otherwise.
+	ReplicaUseCase walletUseCase.WalletUseCaseI
+	Logger         logger.Logger
+	ContextManager UserContextManager
+}
+
+// readUseCase picks the use case for a read. Clients who wrote recently read
+// from the primary, so they see their own writes despite the replication lag.
+func (ah *WalletHandler) readUseCase(r *http.Request) walletUseCase.WalletUseCaseI {
+	if ah.ReplicaUseCase == nil || ah.ContextManager.PrimaryReadFromContext(r.Context()) {
+		return ah.WalletUseCase
+	}
+
+	return ah.ReplicaUseCase
+}
+
+// CreateWalletRequest is all a client chooses about a new wallet, the balance,
+// version and status are set by the service.
+type CreateWalletRequest struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
 }
 
 func (ah *WalletHandler) Create(w http.ResponseWriter, r *http.Request) {
-	wallet := models.Wallet{}
+	createReq := CreateWalletRequest{}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -36,7 +67,7 @@ func (ah *WalletHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = json.Unmarshal(body, &wallet)
+	err = json.Unmarshal(body, &createReq)
 	if err != nil {
 		ah.Logger.Infow("can`t unmarshal form",
 			"err:", err.Error())
@@ -44,6 +75,13 @@ func (ah *WalletHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wallet := models.Wallet{UID: createReq.UID, Name: createReq.Name}
+
+	// The owner comes from the session only, anonymous wallets have none.
+	if userID, err := ah.ContextManager.UserIDFromContext(r.Context()); err == nil {
+		wallet.OwnerID = userID
+	}
+
 	//_, err = govalidator.ValidateStruct(wallet)
 	//if err != nil {
 	//	ah.Logger.Infow("can`t validate form",
@@ -97,7 +135,7 @@ func (ah *WalletHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wallet, err := ah.WalletUseCase.Get(walletId)
+	wallet, err := ah.readUseCase(r).Get(walletId)
 	if err != nil {
 		ah.Logger.Infow("can`t get wallet",
 			"err:", err.Error())
@@ -126,6 +164,12 @@ func (ah *WalletHandler) Get(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// UpdateWalletRequest lists the fields PATCH may change. The balance is changed
+// only through ChangeAmount, holds, reversals and adjustments.
+type UpdateWalletRequest struct {
+	Name string `json:"name"`
+}
+
 func (ah *WalletHandler) Update(w http.ResponseWriter, r *http.Request) {
 	walletIdString := r.PathValue("walletId")
 	if walletIdString == "" {
@@ -142,7 +186,12 @@ func (ah *WalletHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wallet := &models.Wallet{}
+	version, ok := ah.versionFromIfMatch(w, r, walletId)
+	if !ok {
+		return
+	}
+
+	updateReq := UpdateWalletRequest{}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		ah.Logger.Errorw("can`t read body of request",
@@ -158,24 +207,28 @@ func (ah *WalletHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = json.Unmarshal(body, wallet)
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&updateReq)
 	if err != nil {
 		ah.Logger.Infow("can`t unmarshal form",
 			"err:", err.Error())
-		http.Error(w, "bad data", http.StatusBadRequest)
+		http.Error(w, "bad data: only metadata fields can be updated", http.StatusBadRequest)
 		return
 	}
 
-	//_, err = govalidator.ValidateStruct(wallet)
-	//if err != nil {
-	//	ah.Logger.Infow("can`t validate form",
-	//		"err:", err.Error())
-	//	http.Error(w, "bad data", http.StatusBadRequest)
-	//	return
-	//}
-
-	wallet.ID = walletId
+	wallet := &models.Wallet{
+		ID:      walletId,
+		Name:    updateReq.Name,
+		Version: version,
+	}
 	err = ah.WalletUseCase.Update(wallet)
+	if errors.Is(err, models.ErrVersionMismatch) {
+		ah.Logger.Infow("can`t update wallet",
+			"err:", err.Error())
+		http.Error(w, "wallet was changed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		ah.Logger.Infow("can`t update wallet",
 			"err:", err.Error())
@@ -193,6 +246,7 @@ func (ah *WalletHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", walletETag(wallet))
 
 	w.WriteHeader(http.StatusOK)
 
@@ -221,7 +275,18 @@ func (ah *WalletHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = ah.WalletUseCase.Delete(walletId)
+	version, ok := ah.versionFromIfMatch(w, r, walletId)
+	if !ok {
+		return
+	}
+
+	err = ah.WalletUseCase.Delete(walletId, version)
+	if errors.Is(err, models.ErrVersionMismatch) {
+		ah.Logger.Infow("can`t delete wallet",
+			"err:", err.Error())
+		http.Error(w, "wallet was changed", http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		ah.Logger.Infow("can`t delete wallet",
 			"err:", err.Error())
@@ -232,8 +297,57 @@ func (ah *WalletHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (ah *WalletHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	walletIdString := r.PathValue("walletId")
+	if walletIdString == "" {
+		ah.Logger.Errorw("no walletId var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	walletId, err := strconv.Atoi(walletIdString)
+	if err != nil {
+		ah.Logger.Errorw("fail to convert id to int",
+			"err:", err.Error())
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	err = ah.WalletUseCase.Restore(walletId)
+	if err != nil {
+		ah.Logger.Infow("can`t restore wallet",
+			"err:", err.Error())
+		http.Error(w, "can`t restore wallet", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (ah *WalletHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	wallets, err := ah.WalletUseCase.GetAll()
+	includeDeleted := false
+	if includeDeletedString := r.URL.Query().Get("include_deleted"); includeDeletedString != "" {
+		var err error
+		includeDeleted, err = strconv.ParseBool(includeDeletedString)
+		if err != nil {
+			ah.Logger.Infow("fail to parse include_deleted",
+				"err:", err.Error())
+			http.Error(w, "bad data", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if includeDeleted {
+		role, err := ah.ContextManager.UserRoleFromContext(r.Context())
+		if err != nil || role != session.AdminRole {
+			ah.Logger.Infow("can`t list deleted wallets",
+				"err", "admin role required")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	wallets, err := ah.readUseCase(r).GetAll(includeDeleted)
 	if err != nil {
 		ah.Logger.Infow("can`t get all wallets",
 			"err:", err.Error())
@@ -261,15 +375,17 @@ func (ah *WalletHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetByUID is routed by the same path as Update and Delete, the segment is
+// a UID here.
 func (ah *WalletHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
-	walletUID := r.PathValue("WALLET_UUID")
+	walletUID := r.PathValue("walletId")
 	if walletUID == "" {
-		ah.Logger.Errorw("no WALLET_UUID var")
+		ah.Logger.Errorw("no walletId var")
 		http.Error(w, "unknown error", http.StatusInternalServerError)
 		return
 	}
 
-	wallet, err := ah.WalletUseCase.GetByUID(walletUID)
+	wallet, err := ah.readUseCase(r).GetByUID(walletUID)
 	if err != nil {
 		ah.Logger.Infow("can`t get wallet",
 			"err:", err.Error())
@@ -277,6 +393,14 @@ func (ah *WalletHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag := walletETag(wallet)
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	resp, err := json.Marshal(wallet)
 
 	if err != nil {
@@ -352,31 +476,420 @@ func (ah *WalletHandler) ChangeAmount(w http.ResponseWriter, r *http.Request) {
 		amountReq = -changeAmountReq.Amount
 	}
 
-	err = ah.WalletUseCase.ChangeAmount(changeAmountReq.WalletUID, amountReq)
+	transaction, err := ah.WalletUseCase.ChangeAmount(changeAmountReq.WalletUID, amountReq)
 	if err != nil {
-		ah.Logger.Infow("can`t create wallet",
+		ah.Logger.Infow("can`t change amount",
 			"err:", err.Error())
-		http.Error(w, "can`t create wallet", http.StatusBadRequest)
+		status, msg := balanceErrorResponse(err, "can`t create wallet", http.StatusBadRequest)
+		http.Error(w, msg, status)
 		return
 	}
 
-	//resp, err := json.Marshal(wallet)
-	//
-	//if err != nil {
-	//	ah.Logger.Errorw("can`t marshal wallet",
-	//		"err:", err.Error())
-	//	http.Error(w, "can`t make wallet", http.StatusInternalServerError)
-	//	return
-	//}
+	resp, err := json.Marshal(transaction)
+	if err != nil {
+		ah.Logger.Errorw("can`t marshal transaction",
+			"err:", err.Error())
+		http.Error(w, "can`t make transaction", http.StatusInternalServerError)
+		return
+	}
 
-	//w.Header().Set("Location", fmt.Sprintf("/api/v1/wallets/%d", changeAmountReq.ID))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/transactions/%s", transaction.UID))
 	w.WriteHeader(http.StatusOK)
 
-	//_, err = w.Write(resp)
-	//if err != nil {
-	//	ah.Logger.Errorw("can`t write response",
-	//		"err:", err.Error())
-	//	http.Error(w, "can`t write response", http.StatusInternalServerError)
-	//	return
-	//}
+	_, err = w.Write(resp)
+	if err != nil {
+		ah.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+type AdjustRequest struct {
+	Amount     int    `valid:"int" json:"amount"`
+	ReasonCode string `valid:"in(CORRECTION|CHARGEBACK|FEE|GOODWILL|MIGRATION),required" json:"reasonCode"`
+	Comment    string `valid:"required" json:"comment"`
+}
+
+func (ah *WalletHandler) Adjust(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		ah.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	actorID, err := ah.ContextManager.UserIDFromContext(r.Context())
+	if err != nil {
+		ah.Logger.Errorw("can`t get user from context",
+			"err:", err.Error())
+		http.Error(w, "no auth", http.StatusUnauthorized)
+		return
+	}
+
+	adjustReq := AdjustRequest{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		ah.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(body, &adjustReq)
+	if err != nil {
+		ah.Logger.Infow("can`t unmarshal form",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	_, err = govalidator.ValidateStruct(adjustReq)
+	if err != nil || adjustReq.Amount == 0 {
+		ah.Logger.Infow("can`t validate form",
+			"err:", fmt.Sprint(err))
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := ah.WalletUseCase.Adjust(walletUID, adjustReq.Amount, adjustReq.ReasonCode, adjustReq.Comment, actorID)
+	if err != nil {
+		ah.Logger.Infow("can`t adjust wallet",
+			"err:", err.Error())
+		status, msg := balanceErrorResponse(err, "can`t adjust wallet", http.StatusInternalServerError)
+		http.Error(w, msg, status)
+		return
+	}
+
+	ah.Logger.Infow("wallet adjusted",
+		"walletUID", walletUID,
+		"amount", adjustReq.Amount,
+		"reasonCode", adjustReq.ReasonCode,
+		"actorID", actorID)
+
+	resp, err := json.Marshal(transaction)
+	if err != nil {
+		ah.Logger.Errorw("can`t marshal transaction",
+			"err:", err.Error())
+		http.Error(w, "can`t make transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/transactions/%s", transaction.UID))
+	w.WriteHeader(http.StatusCreated)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		ah.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+type ChangeStatusRequest struct {
+	Status string `valid:"in(ACTIVE|FROZEN|CLOSED),required" json:"status"`
+	Reason string `valid:"required" json:"reason"`
+}
+
+func (ah *WalletHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		ah.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	actorID, err := ah.ContextManager.UserIDFromContext(r.Context())
+	if err != nil {
+		ah.Logger.Errorw("can`t get user from context",
+			"err:", err.Error())
+		http.Error(w, "no auth", http.StatusUnauthorized)
+		return
+	}
+
+	changeStatusReq := ChangeStatusRequest{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		ah.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(body, &changeStatusReq)
+	if err != nil {
+		ah.Logger.Infow("can`t unmarshal form",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	_, err = govalidator.ValidateStruct(changeStatusReq)
+	if err != nil {
+		ah.Logger.Infow("can`t validate form",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := ah.WalletUseCase.ChangeStatus(walletUID, changeStatusReq.Status, changeStatusReq.Reason, actorID)
+	if err != nil {
+		ah.Logger.Infow("can`t change wallet status",
+			"err:", err.Error())
+		status, msg := balanceErrorResponse(err, "can`t change wallet status", http.StatusInternalServerError)
+		http.Error(w, msg, status)
+		return
+	}
+
+	ah.Logger.Infow("wallet status changed",
+		"walletUID", walletUID,
+		"status", wallet.Status,
+		"reason", changeStatusReq.Reason,
+		"actorID", actorID)
+
+	resp, err := json.Marshal(wallet)
+	if err != nil {
+		ah.Logger.Errorw("can`t marshal wallet",
+			"err:", err.Error())
+		http.Error(w, "can`t make wallet", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		ah.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+type SetShardsRequest struct {
+	Shards *int `json:"shards"`
+}
+
+// SetShards spreads the deposits into the wallet over the given number of
+// sub-balances, 0 turns sharding off. Meant for wallets too hot for one row.
+func (ah *WalletHandler) SetShards(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		ah.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	setShardsReq := SetShardsRequest{}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		ah.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		ah.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return
+	}
+
+	err = json.Unmarshal(body, &setShardsReq)
+	if err != nil || setShardsReq.Shards == nil {
+		ah.Logger.Infow("can`t unmarshal form",
+			"err:", err)
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := ah.WalletUseCase.SetShards(walletUID, *setShardsReq.Shards)
+	if errors.Is(err, models.ErrBadShards) {
+		ah.Logger.Infow("bad number of shards",
+			"err:", err.Error())
+		http.Error(w, "bad number of shards", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		ah.Logger.Infow("can`t set wallet shards",
+			"err:", err.Error())
+		status, msg := balanceErrorResponse(err, "can`t set wallet shards", http.StatusInternalServerError)
+		http.Error(w, msg, status)
+		return
+	}
+
+	ah.Logger.Infow("wallet shards set",
+		"walletUID", walletUID,
+		"shards", wallet.Shards)
+
+	resp, err := json.Marshal(wallet)
+	if err != nil {
+		ah.Logger.Errorw("can`t marshal wallet",
+			"err:", err.Error())
+		http.Error(w, "can`t make wallet", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		ah.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (ah *WalletHandler) GetStatusChanges(w http.ResponseWriter, r *http.Request) {
+	walletUID := r.PathValue("WALLET_UUID")
+	if walletUID == "" {
+		ah.Logger.Errorw("no WALLET_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	changes, err := ah.WalletUseCase.GetStatusChanges(walletUID)
+	if err != nil {
+		ah.Logger.Infow("can`t get wallet status changes",
+			"err:", err.Error())
+		http.Error(w, "can`t get wallet status changes", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(changes)
+	if err != nil {
+		ah.Logger.Errorw("can`t marshal wallet status changes",
+			"err:", err.Error())
+		http.Error(w, "can`t make wallet status changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		ah.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+		http.Error(w, "can`t write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// walletETag is the version of the wallet. Deposits into the shards of a
+// sharded wallet don't change its version, so its amount is part of the tag.
+func walletETag(wallet *models.Wallet) string {
+	if wallet.Shards > 0 {
+		return `"` + strconv.Itoa(wallet.Version) + "." + strconv.Itoa(wallet.Amount) + `"`
+	}
+
+	return `"` + strconv.Itoa(wallet.Version) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value lists etag.
+// Weak and strong tags are compared the same way.
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// versionFromIfMatch reads the wallet version the client expects to change.
+// Conditional requests are mandatory, so a missing header is answered with 428.
+// Like etagMatches it takes a list of tags, "*" stands for the current version.
+// The update itself still fails with 412 if the wallet changes in between.
+func (ah *WalletHandler) versionFromIfMatch(w http.ResponseWriter, r *http.Request, walletId int) (int, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		ah.Logger.Infow("no If-Match header")
+		http.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+		return 0, false
+	}
+
+	current, err := ah.WalletUseCase.Get(walletId)
+	if err != nil {
+		ah.Logger.Infow("can`t get wallet",
+			"err:", err.Error())
+		http.Error(w, "wallet not found", http.StatusNotFound)
+		return 0, false
+	}
+
+	if !versionMatches(ifMatch, current.Version) {
+		ah.Logger.Infow("If-Match doesn`t match wallet version",
+			"ifMatch", ifMatch,
+			"version", current.Version)
+		http.Error(w, "wallet was changed", http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	return current.Version, true
+}
+
+// versionMatches reports whether the If-Match header value lists a tag of the
+// given version. Tags of sharded wallets carry the amount after the version,
+// only the version is compared.
+func versionMatches(header string, version int) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" {
+			return true
+		}
+
+		tagVersion, _, _ := strings.Cut(strings.Trim(tag, `"`), ".")
+		if v, err := strconv.Atoi(tagVersion); err == nil && v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// balanceErrorResponse maps domain errors of balance and status changes to
+// a response status and message, anything else gets the fallback.
+func balanceErrorResponse(err error, fallbackMsg string, fallbackStatus int) (int, string) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound, "wallet not found"
+	case errors.Is(err, models.ErrInsufficientFunds):
+		return http.StatusConflict, "insufficient funds"
+	case errors.Is(err, models.ErrWalletFrozen):
+		return http.StatusConflict, "wallet is frozen"
+	case errors.Is(err, models.ErrWalletClosed):
+		return http.StatusConflict, "wallet is closed"
+	case errors.Is(err, models.ErrStatusTransition):
+		return http.StatusConflict, "wallet status transition is not allowed"
+	case errors.Is(err, models.ErrNonZeroBalance):
+		return http.StatusConflict, "wallet balance is not zero"
+	default:
+		return fallbackStatus, fallbackMsg
+	}
 }
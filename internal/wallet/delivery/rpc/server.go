@@ -0,0 +1,211 @@
+package rpc
+
+import (
+	"context"
+
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/Davmie/javaCode/pkg/walletpb"
+	"github.com/asaskevich/govalidator"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type UserContextManager interface {
+	UserIDFromContext(context.Context) (int, error)
+	UserRoleFromContext(context.Context) (string, error)
+}
+
+// WalletServer serves walletpb.WalletService on top of the use cases of the
+// REST handlers.
+type WalletServer struct {
+	walletpb.UnimplementedWalletServiceServer
+
+	WalletUseCase      walletUseCase.WalletUseCaseI
+	TransactionUseCase transactionUseCase.TransactionUseCaseI
+	Logger             logger.Logger
+	ContextManager     UserContextManager
+}
+
+func (ws *WalletServer) CreateWallet(ctx context.Context, req *walletpb.CreateWalletRequest) (*walletpb.Wallet, error) {
+	wallet := models.Wallet{
+		UID:  req.GetUid(),
+		Name: req.GetName(),
+	}
+
+	if wallet.UID == "" {
+		wallet.UID = uuid.NewString()
+	} else if !govalidator.IsUUID(wallet.UID) {
+		return nil, status.Error(codes.InvalidArgument, "uid must be a UUID")
+	}
+
+	// The owner comes from the session only, anonymous wallets have none.
+	if userID, err := ws.ContextManager.UserIDFromContext(ctx); err == nil {
+		wallet.OwnerID = userID
+	}
+
+	// Postgres reports a taken uid as a bare constraint violation.
+	if _, err := ws.WalletUseCase.GetByUID(wallet.UID); err == nil {
+		return nil, status.Error(codes.AlreadyExists, "wallet already exists")
+	}
+
+	err := ws.WalletUseCase.Create(&wallet)
+	if err != nil {
+		ws.Logger.Infow("can`t create wallet",
+			"err:", err.Error())
+		return nil, statusError(err, "can`t create wallet")
+	}
+
+	return walletToProto(&wallet), nil
+}
+
+func (ws *WalletServer) GetWallet(ctx context.Context, req *walletpb.GetWalletRequest) (*walletpb.Wallet, error) {
+	wallet, err := ws.WalletUseCase.GetByUID(req.GetUid())
+	if err != nil {
+		ws.Logger.Infow("can`t get wallet",
+			"err:", err.Error())
+		return nil, statusError(err, "can`t get wallet")
+	}
+
+	return walletToProto(wallet), nil
+}
+
+func (ws *WalletServer) ListWallets(ctx context.Context, req *walletpb.ListWalletsRequest) (*walletpb.ListWalletsResponse, error) {
+	if req.GetIncludeDeleted() {
+		role, err := ws.ContextManager.UserRoleFromContext(ctx)
+		if err != nil || role != session.AdminRole {
+			ws.Logger.Infow("can`t list deleted wallets",
+				"err", "admin role required")
+			return nil, status.Error(codes.PermissionDenied, "Forbidden")
+		}
+	}
+
+	wallets, err := ws.WalletUseCase.GetAll(req.GetIncludeDeleted())
+	if err != nil {
+		ws.Logger.Infow("can`t get all wallets",
+			"err:", err.Error())
+		return nil, statusError(err, "can`t get all wallets")
+	}
+
+	resp := &walletpb.ListWalletsResponse{Wallets: make([]*walletpb.Wallet, 0, len(wallets))}
+	for _, w := range wallets {
+		resp.Wallets = append(resp.Wallets, walletToProto(w))
+	}
+
+	return resp, nil
+}
+
+func (ws *WalletServer) ChangeAmount(ctx context.Context, req *walletpb.ChangeAmountRequest) (*walletpb.Transaction, error) {
+	if !govalidator.IsUUID(req.GetWalletUid()) {
+		return nil, status.Error(codes.InvalidArgument, "wallet_uid must be a UUID")
+	}
+
+	if req.GetAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+
+	amount := int(req.GetAmount())
+	switch req.GetOperationType() {
+	case walletpb.OperationType_OPERATION_TYPE_DEPOSIT:
+	case walletpb.OperationType_OPERATION_TYPE_WITHDRAW:
+		amount = -amount
+	default:
+		return nil, status.Error(codes.InvalidArgument, "operation_type must be DEPOSIT or WITHDRAW")
+	}
+
+	transaction, err := ws.WalletUseCase.ChangeAmount(req.GetWalletUid(), amount)
+	if err != nil {
+		ws.Logger.Infow("can`t change amount",
+			"err:", err.Error())
+		return nil, statusError(err, "can`t change amount")
+	}
+
+	return transactionToProto(transaction), nil
+}
+
+func (ws *WalletServer) GetHistory(ctx context.Context, req *walletpb.GetHistoryRequest) (*walletpb.GetHistoryResponse, error) {
+	transactions, err := ws.TransactionUseCase.GetByWalletUID(req.GetWalletUid())
+	if err != nil {
+		ws.Logger.Infow("can`t get transactions",
+			"err:", err.Error())
+		return nil, statusError(err, "can`t get transactions")
+	}
+
+	resp := &walletpb.GetHistoryResponse{Transactions: make([]*walletpb.Transaction, 0, len(transactions))}
+	for _, t := range transactions {
+		resp.Transactions = append(resp.Transactions, transactionToProto(t))
+	}
+
+	return resp, nil
+}
+
+// statusError maps domain errors to gRPC status codes the way
+// balanceErrorResponse maps them to HTTP statuses, anything else is
+// codes.Internal with fallbackMsg.
+func statusError(err error, fallbackMsg string) error {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return status.Error(codes.NotFound, "wallet not found")
+	case errors.Is(err, models.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, "wallet already exists")
+	case errors.Is(err, models.ErrInsufficientFunds):
+		return status.Error(codes.FailedPrecondition, "insufficient funds")
+	case errors.Is(err, models.ErrWalletFrozen):
+		return status.Error(codes.FailedPrecondition, "wallet is frozen")
+	case errors.Is(err, models.ErrWalletClosed):
+		return status.Error(codes.FailedPrecondition, "wallet is closed")
+	case errors.Is(err, models.ErrStatusTransition):
+		return status.Error(codes.FailedPrecondition, "wallet status transition is not allowed")
+	case errors.Is(err, models.ErrNonZeroBalance):
+		return status.Error(codes.FailedPrecondition, "wallet balance is not zero")
+	case errors.Is(err, models.ErrVersionMismatch):
+		return status.Error(codes.Aborted, "wallet version mismatch")
+	case errors.Is(err, models.ErrBadShards):
+		return status.Error(codes.InvalidArgument, "bad number of wallet shards")
+	default:
+		return status.Error(codes.Internal, fallbackMsg)
+	}
+}
+
+func walletToProto(w *models.Wallet) *walletpb.Wallet {
+	return &walletpb.Wallet{
+		Id:      int64(w.ID),
+		Uid:     w.UID,
+		Name:    w.Name,
+		Amount:  int64(w.Amount),
+		Held:    int64(w.Held),
+		Status:  w.Status,
+		Version: int64(w.Version),
+		Shards:  int64(w.Shards),
+		OwnerId: int64(w.OwnerID),
+	}
+}
+
+func transactionToProto(t *models.Transaction) *walletpb.Transaction {
+	resp := &walletpb.Transaction{
+		Id:            int64(t.ID),
+		Uid:           t.UID,
+		WalletUid:     t.WalletUID,
+		OperationType: t.Type,
+		Amount:        int64(t.Amount),
+		Reversed:      int64(t.Reversed),
+		ReasonCode:    t.ReasonCode,
+		Comment:       t.Comment,
+		CreatedAt:     timestamppb.New(t.CreatedAt),
+	}
+
+	if t.ReversalOf != nil {
+		resp.ReversalOf = *t.ReversalOf
+	}
+	if t.ActorID != nil {
+		resp.ActorId = int64(*t.ActorID)
+	}
+
+	return resp
+}
@@ -0,0 +1,269 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	memTransaction "github.com/Davmie/javaCode/internal/transaction/repository/memory"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	memWallet "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	walletMocks "github.com/Davmie/javaCode/internal/wallet/repository/mocks"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/models"
+	ctxManager "github.com/Davmie/javaCode/pkg/context"
+	"github.com/Davmie/javaCode/pkg/middleware"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/Davmie/javaCode/pkg/walletpb"
+	"github.com/google/uuid"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testUserID = 7
+
+// WalletServerTestSuite calls the server over an in-memory connection, through
+// the interceptors of the service, with wallets kept in memory.
+type WalletServerTestSuite struct {
+	suite.Suite
+	server  *grpc.Server
+	conn    *grpc.ClientConn
+	client  walletpb.WalletServiceClient
+	wallets walletUseCase.WalletUseCaseI
+}
+
+func TestWalletServerSuite(t *testing.T) {
+	suite.RunSuite(t, new(WalletServerTestSuite))
+}
+
+func (s *WalletServerTestSuite) BeforeEach(t provider.T) {
+	logger := zap.NewNop().Sugar()
+	store := memstore.New()
+	s.wallets = walletUseCase.New(memWallet.New(logger, store))
+
+	authManager := middleware.AuthManager{
+		SessionManager: session.JWTSessionsManager{},
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(
+		middleware.UnaryPanic(logger),
+		authManager.UnaryAuth(nil),
+	))
+	walletpb.RegisterWalletServiceServer(s.server, &WalletServer{
+		WalletUseCase:      s.wallets,
+		TransactionUseCase: transactionUseCase.New(memTransaction.New(logger, store)),
+		Logger:             logger,
+		ContextManager:     ctxManager.Manager{},
+	})
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = s.server.Serve(lis)
+	}()
+
+	var err error
+	s.conn, err = grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	t.Require().NoError(err)
+	s.client = walletpb.NewWalletServiceClient(s.conn)
+}
+
+func (s *WalletServerTestSuite) AfterEach(t provider.T) {
+	_ = s.conn.Close()
+	s.server.Stop()
+}
+
+// as returns a context carrying the session of the user.
+func (s *WalletServerTestSuite) as(t provider.T, userID int, role string) context.Context {
+	token, err := session.JWTSessionsManager{}.CreateSession(userID, role)
+	t.Require().NoError(err)
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", token)
+}
+
+func (s *WalletServerTestSuite) createWallet(t provider.T, amount int) *models.Wallet {
+	wallet := &models.Wallet{UID: uuid.NewString()}
+	t.Require().NoError(s.wallets.Create(wallet))
+	if amount != 0 {
+		_, err := s.wallets.ChangeAmount(wallet.UID, amount)
+		t.Require().NoError(err)
+	}
+
+	return wallet
+}
+
+func (s *WalletServerTestSuite) TestCreateWallet(t provider.T) {
+	uid := uuid.NewString()
+
+	created, err := s.client.CreateWallet(s.as(t, testUserID, "user"), &walletpb.CreateWalletRequest{Uid: uid, Name: "savings"})
+
+	t.Require().NoError(err)
+	t.Assert().Equal(uid, created.GetUid())
+	t.Assert().Equal("savings", created.GetName())
+	t.Assert().Equal(models.WalletStatusActive, created.GetStatus())
+	t.Assert().Equal(int64(testUserID), created.GetOwnerId())
+
+	_, err = s.client.CreateWallet(context.Background(), &walletpb.CreateWalletRequest{Uid: uid})
+	t.Assert().Equal(codes.AlreadyExists, status.Code(err))
+}
+
+func (s *WalletServerTestSuite) TestCreateAnonymousWallet(t provider.T) {
+	created, err := s.client.CreateWallet(context.Background(), &walletpb.CreateWalletRequest{})
+
+	t.Require().NoError(err)
+	t.Assert().NotEmpty(created.GetUid())
+	t.Assert().Zero(created.GetOwnerId())
+	t.Assert().Zero(created.GetAmount())
+}
+
+func (s *WalletServerTestSuite) TestCreateWalletBadUID(t provider.T) {
+	_, err := s.client.CreateWallet(context.Background(), &walletpb.CreateWalletRequest{Uid: "wallet"})
+
+	t.Assert().Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (s *WalletServerTestSuite) TestGetWallet(t provider.T) {
+	wallet := s.createWallet(t, 10)
+
+	got, err := s.client.GetWallet(context.Background(), &walletpb.GetWalletRequest{Uid: wallet.UID})
+	t.Require().NoError(err)
+	t.Assert().Equal(wallet.UID, got.GetUid())
+	t.Assert().Equal(int64(10), got.GetAmount())
+
+	_, err = s.client.GetWallet(context.Background(), &walletpb.GetWalletRequest{Uid: uuid.NewString()})
+	t.Assert().Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *WalletServerTestSuite) TestGetWalletFailure(t provider.T) {
+	walletRepoMock := walletMocks.NewWalletRepositoryI(t)
+	walletRepoMock.On("GetByUID", "wallet").Return(nil, errors.New("connection refused"))
+	server := &WalletServer{
+		WalletUseCase: walletUseCase.New(walletRepoMock),
+		Logger:        zap.NewNop().Sugar(),
+	}
+
+	_, err := server.GetWallet(context.Background(), &walletpb.GetWalletRequest{Uid: "wallet"})
+
+	t.Assert().Equal(codes.Internal, status.Code(err))
+}
+
+func (s *WalletServerTestSuite) TestListWallets(t provider.T) {
+	kept := s.createWallet(t, 0)
+	deleted := s.createWallet(t, 0)
+	t.Require().NoError(s.wallets.Delete(deleted.ID, deleted.Version))
+
+	listed, err := s.client.ListWallets(context.Background(), &walletpb.ListWalletsRequest{})
+	t.Require().NoError(err)
+	t.Require().Len(listed.GetWallets(), 1)
+	t.Assert().Equal(kept.UID, listed.GetWallets()[0].GetUid())
+
+	_, err = s.client.ListWallets(s.as(t, testUserID, "user"), &walletpb.ListWalletsRequest{IncludeDeleted: true})
+	t.Assert().Equal(codes.PermissionDenied, status.Code(err))
+
+	listed, err = s.client.ListWallets(s.as(t, 1, session.AdminRole), &walletpb.ListWalletsRequest{IncludeDeleted: true})
+	t.Require().NoError(err)
+	t.Assert().Len(listed.GetWallets(), 2)
+}
+
+func (s *WalletServerTestSuite) TestChangeAmount(t provider.T) {
+	wallet := s.createWallet(t, 0)
+
+	deposit, err := s.client.ChangeAmount(context.Background(), &walletpb.ChangeAmountRequest{
+		WalletUid:     wallet.UID,
+		OperationType: walletpb.OperationType_OPERATION_TYPE_DEPOSIT,
+		Amount:        100,
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(100), deposit.GetAmount())
+	t.Assert().Equal(models.TransactionTypeDeposit, deposit.GetOperationType())
+
+	withdraw, err := s.client.ChangeAmount(context.Background(), &walletpb.ChangeAmountRequest{
+		WalletUid:     wallet.UID,
+		OperationType: walletpb.OperationType_OPERATION_TYPE_WITHDRAW,
+		Amount:        40,
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal(int64(-40), withdraw.GetAmount())
+
+	_, err = s.client.ChangeAmount(context.Background(), &walletpb.ChangeAmountRequest{
+		WalletUid:     wallet.UID,
+		OperationType: walletpb.OperationType_OPERATION_TYPE_WITHDRAW,
+		Amount:        100,
+	})
+	t.Assert().Equal(codes.FailedPrecondition, status.Code(err))
+
+	_, err = s.client.ChangeAmount(context.Background(), &walletpb.ChangeAmountRequest{
+		WalletUid:     uuid.NewString(),
+		OperationType: walletpb.OperationType_OPERATION_TYPE_DEPOSIT,
+		Amount:        100,
+	})
+	t.Assert().Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *WalletServerTestSuite) TestChangeAmountBadRequest(t provider.T) {
+	wallet := s.createWallet(t, 0)
+
+	for _, req := range []*walletpb.ChangeAmountRequest{
+		{WalletUid: "wallet", OperationType: walletpb.OperationType_OPERATION_TYPE_DEPOSIT, Amount: 1},
+		{WalletUid: wallet.UID, OperationType: walletpb.OperationType_OPERATION_TYPE_DEPOSIT, Amount: 0},
+		{WalletUid: wallet.UID, OperationType: walletpb.OperationType_OPERATION_TYPE_UNSPECIFIED, Amount: 1},
+	} {
+		_, err := s.client.ChangeAmount(context.Background(), req)
+		t.Assert().Equal(codes.InvalidArgument, status.Code(err), req.String())
+	}
+}
+
+func (s *WalletServerTestSuite) TestGetHistory(t provider.T) {
+	wallet := s.createWallet(t, 10)
+	_, err := s.wallets.ChangeAmount(wallet.UID, -3)
+	t.Require().NoError(err)
+
+	history, err := s.client.GetHistory(context.Background(), &walletpb.GetHistoryRequest{WalletUid: wallet.UID})
+
+	t.Require().NoError(err)
+	t.Require().Len(history.GetTransactions(), 2)
+	t.Assert().Equal(int64(10), history.GetTransactions()[0].GetAmount())
+	t.Assert().Equal(int64(-3), history.GetTransactions()[1].GetAmount())
+	t.Assert().Equal(wallet.UID, history.GetTransactions()[1].GetWalletUid())
+	t.Assert().NotNil(history.GetTransactions()[1].GetCreatedAt())
+}
+
+func (s *WalletServerTestSuite) TestStatusError(t provider.T) {
+	for _, tc := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{models.ErrNotFound, codes.NotFound},
+		{models.ErrAlreadyExists, codes.AlreadyExists},
+		{models.ErrInsufficientFunds, codes.FailedPrecondition},
+		{models.ErrWalletFrozen, codes.FailedPrecondition},
+		{models.ErrWalletClosed, codes.FailedPrecondition},
+		{models.ErrStatusTransition, codes.FailedPrecondition},
+		{models.ErrNonZeroBalance, codes.FailedPrecondition},
+		{models.ErrVersionMismatch, codes.Aborted},
+		{models.ErrBadShards, codes.InvalidArgument},
+		{errors.Wrap(models.ErrNotFound, "walletUseCase.GetByUID error"), codes.NotFound},
+		{errors.New("connection refused"), codes.Internal},
+	} {
+		st := status.Convert(statusError(tc.err, "fallback"))
+
+		t.Assert().Equal(tc.code, st.Code(), tc.err.Error())
+		if tc.code == codes.Internal {
+			t.Assert().Equal("fallback", st.Message())
+		}
+	}
+}
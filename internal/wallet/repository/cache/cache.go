@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/cache"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+var lookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "wallet_cache_lookups_total",
+	Help: "Wallet lookups by UID served from the cache (hit) or from the repository (miss).",
+}, []string{"result"})
+
+// WalletCache keeps wallets by UID. It is shared by the decorators of every
+// repository that changes wallet rows, so each of them can evict what it changed.
+type WalletCache struct {
+	Logger  logger.Logger
+	Backend cache.Cache
+
+	group singleflight.Group
+
+	mu          sync.Mutex
+	generations map[string]*generation
+}
+
+// generation counts the invalidations of a wallet while it is fetched. A fetch
+// overtaken by an invalidation may have read the wallet before the change, so
+// its result is not cached. Only wallets being fetched have a generation.
+type generation struct {
+	mu      sync.Mutex
+	n       uint64
+	fetches int
+}
+
+func NewWalletCache(logger logger.Logger, backend cache.Cache) *WalletCache {
+	return &WalletCache{
+		Logger:      logger,
+		Backend:     backend,
+		generations: make(map[string]*generation),
+	}
+}
+
+// Load returns the cached wallet or the result of fetch. Concurrent misses for
+// the same UID share a single fetch. Cache failures are logged and served from
+// fetch, the cache never makes a lookup fail.
+func (wc *WalletCache) Load(uid string, fetch func() (*models.Wallet, error)) (*models.Wallet, error) {
+	value, ok, err := wc.Backend.Get(uid)
+	if err != nil {
+		wc.Logger.Errorw("can`t read wallet cache", "err:", err.Error())
+	}
+	if ok {
+		var w models.Wallet
+		if err = json.Unmarshal(value, &w); err == nil {
+			lookups.WithLabelValues("hit").Inc()
+			return &w, nil
+		}
+		wc.Logger.Errorw("can`t unmarshal cached wallet", "err:", err.Error())
+	}
+
+	lookups.WithLabelValues("miss").Inc()
+
+	res, err, _ := wc.group.Do(uid, func() (interface{}, error) {
+		gen, n := wc.beginFetch(uid)
+		defer wc.endFetch(uid, gen)
+
+		w, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(w)
+		if err != nil {
+			return w, nil
+		}
+
+		// The generation is locked until the wallet is written, so an
+		// invalidation either stops the write or deletes what was written.
+		gen.mu.Lock()
+		defer gen.mu.Unlock()
+		if gen.n != n {
+			return w, nil
+		}
+		if err = wc.Backend.Set(uid, value); err != nil {
+			wc.Logger.Errorw("can`t write wallet cache", "err:", err.Error())
+		}
+
+		return w, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers sharing the fetch must not share the wallet.
+	w := *res.(*models.Wallet)
+	return &w, nil
+}
+
+// Invalidate evicts the wallets with the given UIDs. Lookups already in flight
+// are forgotten, so the next lookup reads the repository again, and don't
+// cache what they read.
+func (wc *WalletCache) Invalidate(uids ...string) {
+	for _, uid := range uids {
+		wc.group.Forget(uid)
+
+		wc.mu.Lock()
+		gen := wc.generations[uid]
+		wc.mu.Unlock()
+		if gen != nil {
+			gen.mu.Lock()
+			gen.n++
+			gen.mu.Unlock()
+		}
+	}
+
+	if err := wc.Backend.Delete(uids...); err != nil {
+		wc.Logger.Errorw("can`t invalidate wallet cache", "err:", err.Error())
+	}
+}
+
+// beginFetch returns the generation of the wallet and its current value.
+func (wc *WalletCache) beginFetch(uid string) (*generation, uint64) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	gen, ok := wc.generations[uid]
+	if !ok {
+		gen = &generation{}
+		wc.generations[uid] = gen
+	}
+	gen.fetches++
+
+	gen.mu.Lock()
+	defer gen.mu.Unlock()
+
+	return gen, gen.n
+}
+
+// endFetch drops the generation of the wallet after its last fetch.
+func (wc *WalletCache) endFetch(uid string, gen *generation) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	gen.fetches--
+	if gen.fetches == 0 {
+		delete(wc.generations, uid)
+	}
+}
+
+// Flush evicts every cached wallet. Lookups in flight don't cache what they read.
+func (wc *WalletCache) Flush() {
+	wc.mu.Lock()
+	for _, gen := range wc.generations {
+		gen.mu.Lock()
+		gen.n++
+		gen.mu.Unlock()
+	}
+	wc.mu.Unlock()
+
+	if err := wc.Backend.Clear(); err != nil {
+		wc.Logger.Errorw("can`t flush wallet cache", "err:", err.Error())
+	}
+}
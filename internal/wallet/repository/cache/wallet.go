@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+// cachedWalletRepo serves GetByUID from the cache and evicts the wallets
+// changed by every write.
+type cachedWalletRepo struct {
+	Logger logger.Logger
+	Repo   repository.WalletRepositoryI
+	Cache  *WalletCache
+}
+
+func New(logger logger.Logger, repo repository.WalletRepositoryI, cache *WalletCache) repository.WalletRepositoryI {
+	return &cachedWalletRepo{
+		Logger: logger,
+		Repo:   repo,
+		Cache:  cache,
+	}
+}
+
+func (cr *cachedWalletRepo) Create(w *models.Wallet) error {
+	return cr.Repo.Create(w)
+}
+
+func (cr *cachedWalletRepo) Get(id int) (*models.Wallet, error) {
+	return cr.Repo.Get(id)
+}
+
+func (cr *cachedWalletRepo) Update(w *models.Wallet) error {
+	err := cr.Repo.Update(w)
+	if err != nil {
+		return err
+	}
+
+	cr.Cache.Invalidate(w.UID)
+
+	return nil
+}
+
+func (cr *cachedWalletRepo) Delete(id int, version int) error {
+	// The wallet can't be looked up by id once it is deleted.
+	w, err := cr.Repo.Get(id)
+	if err != nil {
+		return errors.Wrap(err, "cachedWalletRepo.Delete error")
+	}
+
+	if err = cr.Repo.Delete(id, version); err != nil {
+		return err
+	}
+
+	cr.Cache.Invalidate(w.UID)
+
+	return nil
+}
+
+func (cr *cachedWalletRepo) GetAll(includeDeleted bool) ([]*models.Wallet, error) {
+	return cr.Repo.GetAll(includeDeleted)
+}
+
+func (cr *cachedWalletRepo) GetByUID(uid string) (*models.Wallet, error) {
+	return cr.Cache.Load(uid, func() (*models.Wallet, error) {
+		return cr.Repo.GetByUID(uid)
+	})
+}
+
+func (cr *cachedWalletRepo) ChangeAmount(t *models.Transaction) (*models.Wallet, error) {
+	w, err := cr.Repo.ChangeAmount(t)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Cache.Invalidate(t.WalletUID)
+
+	return w, nil
+}
+
+func (cr *cachedWalletRepo) ChangeStatus(c *models.WalletStatusChange) (*models.Wallet, error) {
+	w, err := cr.Repo.ChangeStatus(c)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Cache.Invalidate(c.WalletUID)
+
+	return w, nil
+}
+
+func (cr *cachedWalletRepo) GetStatusChanges(uid string) ([]*models.WalletStatusChange, error) {
+	return cr.Repo.GetStatusChanges(uid)
+}
+
+func (cr *cachedWalletRepo) Restore(id int) error {
+	if err := cr.Repo.Restore(id); err != nil {
+		return err
+	}
+
+	// A deleted wallet is never cached, this only guards against a lookup that
+	// raced with the restore.
+	if w, err := cr.Repo.Get(id); err == nil {
+		cr.Cache.Invalidate(w.UID)
+	}
+
+	return nil
+}
+
+// Purge only removes deleted wallets, which are not cached.
+func (cr *cachedWalletRepo) Purge(before time.Time) (int, error) {
+	return cr.Repo.Purge(before)
+}
+
+func (cr *cachedWalletRepo) SetShards(uid string, shards int) (*models.Wallet, error) {
+	w, err := cr.Repo.SetShards(uid, shards)
+	if err != nil {
+		return nil, err
+	}
+
+	cr.Cache.Invalidate(uid)
+
+	return w, nil
+}
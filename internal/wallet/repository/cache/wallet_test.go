@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/internal/wallet/repository/conformance"
+	walletMem "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	walletMocks "github.com/Davmie/javaCode/internal/wallet/repository/mocks"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type CachedWalletRepoTestSuite struct {
+	suite.Suite
+	repo           walletRep.WalletRepositoryI
+	walletRepoMock *walletMocks.WalletRepositoryI
+	wallet         models.Wallet
+}
+
+func TestCachedWalletRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(CachedWalletRepoTestSuite))
+}
+
+func TestCachedWalletRepoLRUConformance(t *testing.T) {
+	suite.RunSuite(t, conformance.New(func(t provider.T) walletRep.WalletRepositoryI {
+		logger := zap.NewNop().Sugar()
+		wallets := NewWalletCache(logger, cache.NewLRU(100, time.Minute))
+
+		return New(logger, walletMem.New(logger, memstore.New()), wallets)
+	}))
+}
+
+func TestCachedWalletRepoRedisConformance(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	suite.RunSuite(t, conformance.New(func(t provider.T) walletRep.WalletRepositoryI {
+		server.FlushAll()
+
+		logger := zap.NewNop().Sugar()
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		wallets := NewWalletCache(logger, cache.NewRedis(client, "wallets:", time.Minute))
+
+		return New(logger, walletMem.New(logger, memstore.New()), wallets)
+	}))
+}
+
+func (s *CachedWalletRepoTestSuite) BeforeEach(t provider.T) {
+	logger := zap.NewNop().Sugar()
+
+	s.walletRepoMock = walletMocks.NewWalletRepositoryI(t)
+	s.repo = New(logger, s.walletRepoMock, NewWalletCache(logger, cache.NewLRU(100, time.Minute)))
+	s.wallet = testBuilders.NewWalletBuilder().
+		WithID(1).
+		WithUID("uid").
+		WithAmount(20).
+		Build()
+}
+
+func (s *CachedWalletRepoTestSuite) TestGetByUIDHit(t provider.T) {
+	s.walletRepoMock.On("GetByUID", s.wallet.UID).Return(&s.wallet, nil).Once()
+
+	for i := 0; i < 3; i++ {
+		resWallet, err := s.repo.GetByUID(s.wallet.UID)
+		t.Assert().NoError(err)
+		t.Assert().Equal(s.wallet.Amount, resWallet.Amount)
+	}
+}
+
+func (s *CachedWalletRepoTestSuite) TestGetByUIDNotFoundIsNotCached(t provider.T) {
+	s.walletRepoMock.On("GetByUID", s.wallet.UID).Return(nil, models.ErrNotFound).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := s.repo.GetByUID(s.wallet.UID)
+		t.Assert().ErrorIs(err, models.ErrNotFound)
+	}
+}
+
+func (s *CachedWalletRepoTestSuite) TestChangeAmountInvalidates(t provider.T) {
+	changed := s.wallet
+	changed.Amount = 25
+
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID(s.wallet.UID).
+		WithAmount(5).
+		Build()
+
+	s.walletRepoMock.On("GetByUID", s.wallet.UID).Return(&s.wallet, nil).Once()
+	s.walletRepoMock.On("ChangeAmount", &transaction).Return(&changed, nil).Once()
+	s.walletRepoMock.On("GetByUID", s.wallet.UID).Return(&changed, nil).Once()
+
+	resWallet, err := s.repo.GetByUID(s.wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(20, resWallet.Amount)
+
+	_, err = s.repo.ChangeAmount(&transaction)
+	t.Assert().NoError(err)
+
+	resWallet, err = s.repo.GetByUID(s.wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(25, resWallet.Amount)
+}
+
+func (s *CachedWalletRepoTestSuite) TestConcurrentMissesShareFetch(t provider.T) {
+	s.walletRepoMock.On("GetByUID", s.wallet.UID).
+		After(50*time.Millisecond).
+		Return(&s.wallet, nil).
+		Once()
+
+	var wg sync.WaitGroup
+	wallets := make([]*models.Wallet, 10)
+	for i := range wallets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			wallets[i], _ = s.repo.GetByUID(s.wallet.UID)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, resWallet := range wallets {
+		t.Require().NotNil(resWallet)
+		t.Assert().Equal(s.wallet.UID, resWallet.UID)
+	}
+}
+
+func (s *CachedWalletRepoTestSuite) TestInvalidateDuringFetchIsNotCached(t provider.T) {
+	wallets := NewWalletCache(zap.NewNop().Sugar(), cache.NewLRU(100, time.Minute))
+
+	stale := s.wallet
+	resWallet, err := wallets.Load(s.wallet.UID, func() (*models.Wallet, error) {
+		// The wallet changes after it was read.
+		wallets.Invalidate(s.wallet.UID)
+		return &stale, nil
+	})
+	t.Require().NoError(err)
+	t.Assert().Equal(s.wallet.Amount, resWallet.Amount)
+
+	_, ok, err := wallets.Backend.Get(s.wallet.UID)
+	t.Require().NoError(err)
+	t.Assert().False(ok)
+
+	_, err = wallets.Load(s.wallet.UID, func() (*models.Wallet, error) {
+		return &s.wallet, nil
+	})
+	t.Require().NoError(err)
+
+	_, ok, err = wallets.Backend.Get(s.wallet.UID)
+	t.Require().NoError(err)
+	t.Assert().True(ok)
+	t.Assert().Empty(wallets.generations)
+}
@@ -0,0 +1,360 @@
+package conformance
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+// Factory returns an empty repository for a single test.
+type Factory func(t provider.T) repository.WalletRepositoryI
+
+// Suite checks the behaviour every WalletRepositoryI implementation has to
+// share. Backends run it with suite.RunSuite(t, conformance.New(factory)).
+type Suite struct {
+	suite.Suite
+	factory       Factory
+	repo          repository.WalletRepositoryI
+	walletBuilder *testBuilders.WalletBuilder
+}
+
+func New(factory Factory) *Suite {
+	return &Suite{factory: factory}
+}
+
+func (s *Suite) BeforeEach(t provider.T) {
+	s.repo = s.factory(t)
+	s.walletBuilder = testBuilders.NewWalletBuilder()
+}
+
+func (s *Suite) createWallet(t provider.T, amount int) models.Wallet {
+	wallet := s.walletBuilder.
+		WithUID(uuid.NewString()).
+		WithAmount(amount).
+		WithStatus(models.WalletStatusActive).
+		Build()
+
+	err := s.repo.Create(&wallet)
+	t.Require().NoError(err)
+	t.Require().NotZero(wallet.ID)
+
+	return wallet
+}
+
+func (s *Suite) transaction(walletUID string, amount int) *models.Transaction {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID(uuid.NewString()).
+		WithWalletUID(walletUID).
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(amount).
+		Build()
+	if amount < 0 {
+		transaction.Type = models.TransactionTypeWithdraw
+	}
+
+	return &transaction
+}
+
+func (s *Suite) TestCreateAndGet(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	resWallet, err := s.repo.Get(wallet.ID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(wallet.UID, resWallet.UID)
+	t.Assert().Equal(20, resWallet.Amount)
+	t.Assert().Equal(models.WalletStatusActive, resWallet.Status)
+
+	resWallet, err = s.repo.GetByUID(wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(wallet.ID, resWallet.ID)
+}
+
+func (s *Suite) TestUniqueUID(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	duplicate := s.walletBuilder.WithUID(wallet.UID).Build()
+	err := s.repo.Create(&duplicate)
+	t.Assert().Error(err)
+
+	wallets, err := s.repo.GetAll(true)
+	t.Assert().NoError(err)
+	t.Assert().Len(wallets, 1)
+}
+
+func (s *Suite) TestNotFound(t provider.T) {
+	missing := s.walletBuilder.WithID(1000).WithUID(uuid.NewString()).Build()
+
+	_, err := s.repo.Get(missing.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	_, err = s.repo.GetByUID(missing.UID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	err = s.repo.Update(&missing)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	err = s.repo.Delete(missing.ID, 0)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	err = s.repo.Restore(missing.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	_, err = s.repo.ChangeAmount(s.transaction(missing.UID, 5))
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *Suite) TestUpdateVersion(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	wallet.Name = "savings"
+	err := s.repo.Update(&wallet)
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, wallet.Version)
+
+	stale := wallet
+	stale.Name = "stale"
+	stale.Version = 0
+	err = s.repo.Update(&stale)
+	t.Assert().ErrorIs(err, models.ErrVersionMismatch)
+
+	resWallet, err := s.repo.Get(wallet.ID)
+	t.Assert().NoError(err)
+	t.Assert().Equal("savings", resWallet.Name)
+	t.Assert().Equal(1, resWallet.Version)
+}
+
+func (s *Suite) TestDeleteAndRestore(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	err := s.repo.Delete(wallet.ID, wallet.Version+1)
+	t.Assert().ErrorIs(err, models.ErrVersionMismatch)
+
+	err = s.repo.Delete(wallet.ID, wallet.Version)
+	t.Assert().NoError(err)
+
+	_, err = s.repo.Get(wallet.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	wallets, err := s.repo.GetAll(false)
+	t.Assert().NoError(err)
+	t.Assert().Empty(wallets)
+
+	wallets, err = s.repo.GetAll(true)
+	t.Assert().NoError(err)
+	t.Assert().Len(wallets, 1)
+
+	err = s.repo.Restore(wallet.ID)
+	t.Assert().NoError(err)
+
+	err = s.repo.Restore(wallet.ID)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	_, err = s.repo.Get(wallet.ID)
+	t.Assert().NoError(err)
+}
+
+func (s *Suite) TestGetAllOrder(t provider.T) {
+	for i := 0; i < 5; i++ {
+		s.createWallet(t, i)
+	}
+
+	wallets, err := s.repo.GetAll(false)
+	t.Assert().NoError(err)
+	t.Require().Len(wallets, 5)
+
+	for i := 1; i < len(wallets); i++ {
+		t.Assert().Less(wallets[i-1].ID, wallets[i].ID)
+	}
+}
+
+func (s *Suite) TestChangeAmount(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	_, err := s.repo.ChangeAmount(s.transaction(wallet.UID, -30))
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+
+	transaction := s.transaction(wallet.UID, -15)
+	resWallet, err := s.repo.ChangeAmount(transaction)
+	t.Assert().NoError(err)
+	t.Assert().Equal(5, resWallet.Amount)
+	t.Assert().NotZero(transaction.ID)
+
+	resWallet, err = s.repo.ChangeAmount(s.transaction(wallet.UID, 10))
+	t.Assert().NoError(err)
+	t.Assert().Equal(15, resWallet.Amount)
+}
+
+func (s *Suite) TestChangeAmountConcurrently(t provider.T) {
+	wallet := s.createWallet(t, 50)
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.repo.ChangeAmount(s.transaction(wallet.UID, -1)); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	t.Assert().Equal(50, succeeded)
+
+	resWallet, err := s.repo.GetByUID(wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, resWallet.Amount)
+}
+
+func (s *Suite) TestChangeStatus(t provider.T) {
+	wallet := s.createWallet(t, 20)
+
+	change := &models.WalletStatusChange{
+		WalletUID:  wallet.UID,
+		FromStatus: models.WalletStatusActive,
+		ToStatus:   models.WalletStatusClosed,
+	}
+
+	_, err := s.repo.ChangeStatus(change)
+	t.Assert().ErrorIs(err, models.ErrNonZeroBalance)
+
+	change.ToStatus = models.WalletStatusFrozen
+	resWallet, err := s.repo.ChangeStatus(change)
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.WalletStatusFrozen, resWallet.Status)
+
+	_, err = s.repo.ChangeStatus(change)
+	t.Assert().ErrorIs(err, models.ErrStatusTransition)
+
+	_, err = s.repo.ChangeAmount(s.transaction(wallet.UID, 5))
+	t.Assert().ErrorIs(err, models.ErrWalletFrozen)
+
+	changes, err := s.repo.GetStatusChanges(wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Len(changes, 1)
+}
+
+func (s *Suite) TestShards(t provider.T) {
+	wallet := s.createWallet(t, 10)
+
+	_, err := s.repo.SetShards(uuid.NewString(), 4)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+
+	resWallet, err := s.repo.SetShards(wallet.UID, 4)
+	t.Require().NoError(err)
+	t.Assert().Equal(4, resWallet.Shards)
+	t.Assert().Equal(10, resWallet.Amount)
+
+	for i := 0; i < 20; i++ {
+		_, err = s.repo.ChangeAmount(s.transaction(wallet.UID, 5))
+		t.Require().NoError(err)
+	}
+
+	resWallet, err = s.repo.GetByUID(wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(110, resWallet.Amount)
+
+	// More than any single shard can hold.
+	resWallet, err = s.repo.ChangeAmount(s.transaction(wallet.UID, -105))
+	t.Assert().NoError(err)
+	t.Assert().Equal(5, resWallet.Amount)
+
+	_, err = s.repo.ChangeAmount(s.transaction(wallet.UID, -6))
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+
+	_, err = s.repo.ChangeAmount(s.transaction(wallet.UID, 7))
+	t.Assert().NoError(err)
+
+	wallets, err := s.repo.GetAll(false)
+	t.Assert().NoError(err)
+	t.Require().Len(wallets, 1)
+	t.Assert().Equal(12, wallets[0].Amount)
+
+	_, err = s.repo.ChangeStatus(&models.WalletStatusChange{
+		WalletUID:  wallet.UID,
+		FromStatus: models.WalletStatusActive,
+		ToStatus:   models.WalletStatusClosed,
+	})
+	t.Assert().ErrorIs(err, models.ErrNonZeroBalance)
+
+	resWallet, err = s.repo.SetShards(wallet.UID, 0)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, resWallet.Shards)
+	t.Assert().Equal(12, resWallet.Amount)
+
+	resWallet, err = s.repo.Get(wallet.ID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(12, resWallet.Amount)
+}
+
+func (s *Suite) TestShardsConcurrently(t provider.T) {
+	wallet := s.createWallet(t, 0)
+
+	_, err := s.repo.SetShards(wallet.UID, 4)
+	t.Require().NoError(err)
+
+	for i := 0; i < 50; i++ {
+		_, err = s.repo.ChangeAmount(s.transaction(wallet.UID, 1))
+		t.Require().NoError(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.repo.ChangeAmount(s.transaction(wallet.UID, -1)); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	t.Assert().Equal(50, succeeded)
+
+	resWallet, err := s.repo.GetByUID(wallet.UID)
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, resWallet.Amount)
+}
+
+func (s *Suite) TestPurge(t provider.T) {
+	wallet := s.createWallet(t, 0)
+	kept := s.createWallet(t, 0)
+
+	_, err := s.repo.ChangeAmount(s.transaction(wallet.UID, 5))
+	t.Assert().NoError(err)
+
+	err = s.repo.Delete(wallet.ID, 1)
+	t.Assert().NoError(err)
+
+	purged, err := s.repo.Purge(time.Now().Add(-time.Hour))
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, purged)
+
+	purged, err = s.repo.Purge(time.Now().Add(time.Hour))
+	t.Assert().NoError(err)
+	t.Assert().Equal(1, purged)
+
+	wallets, err := s.repo.GetAll(true)
+	t.Assert().NoError(err)
+	t.Require().Len(wallets, 1)
+	t.Assert().Equal(kept.UID, wallets[0].UID)
+}
@@ -0,0 +1,282 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type memWalletRepo struct {
+	Logger logger.Logger
+	Store  *memstore.Store
+}
+
+func New(logger logger.Logger, store *memstore.Store) repository.WalletRepositoryI {
+	return &memWalletRepo{
+		Logger: logger,
+		Store:  store,
+	}
+}
+
+func (mr *memWalletRepo) Create(w *models.Wallet) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	for _, stored := range mr.Store.Wallets {
+		if stored.UID == w.UID || stored.ID == w.ID {
+			return errors.Wrap(models.ErrAlreadyExists, "memWalletRepo.Create error while inserting in repo")
+		}
+	}
+
+	if w.ID == 0 {
+		w.ID = mr.Store.NextID(w.TableName())
+	}
+
+	stored := *w
+	mr.Store.Wallets = append(mr.Store.Wallets, &stored)
+	mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletCreated, &stored))
+
+	return nil
+}
+
+func (mr *memWalletRepo) Get(id int) (*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w := mr.byID(id, false)
+	if w == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWalletRepo.Get error")
+	}
+
+	res := *w
+	return &res, nil
+}
+
+// Update changes the metadata of the wallet if it still has version w.Version.
+func (mr *memWalletRepo) Update(w *models.Wallet) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	stored := mr.byID(w.ID, false)
+	if stored == nil {
+		return errors.Wrap(models.ErrNotFound, "memWalletRepo.Update error")
+	}
+	if stored.Version != w.Version {
+		return errors.Wrap(models.ErrVersionMismatch, "memWalletRepo.Update error")
+	}
+
+	stored.Name = w.Name
+	stored.Version++
+	*w = *stored
+	mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletUpdated, stored))
+
+	return nil
+}
+
+// Delete is a soft delete, the wallet stays in the store until it is purged.
+// It only succeeds while the wallet still has the given version.
+func (mr *memWalletRepo) Delete(id int, version int) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	stored := mr.byID(id, false)
+	if stored == nil {
+		return errors.Wrap(models.ErrNotFound, "memWalletRepo.Delete error")
+	}
+	if stored.Version != version {
+		return errors.Wrap(models.ErrVersionMismatch, "memWalletRepo.Delete error")
+	}
+
+	stored.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	stored.Version++
+	mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletDeleted, stored))
+
+	return nil
+}
+
+func (mr *memWalletRepo) Restore(id int) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	stored := mr.byID(id, true)
+	if stored == nil || !stored.DeletedAt.Valid {
+		return errors.Wrap(models.ErrNotFound, "memWalletRepo.Restore error")
+	}
+
+	stored.DeletedAt = gorm.DeletedAt{}
+	stored.Version++
+	mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletRestored, stored))
+
+	return nil
+}
+
+// Purge permanently removes wallets soft deleted before the given time together
+// with their ledger, holds and status history.
+func (mr *memWalletRepo) Purge(before time.Time) (int, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	purged := make(map[string]bool)
+	wallets := mr.Store.Wallets[:0]
+	for _, w := range mr.Store.Wallets {
+		if w.DeletedAt.Valid && w.DeletedAt.Time.Before(before) {
+			purged[w.UID] = true
+			mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletPurged, w))
+			continue
+		}
+		wallets = append(wallets, w)
+	}
+	mr.Store.Wallets = wallets
+
+	transactions := mr.Store.Transactions[:0]
+	for _, t := range mr.Store.Transactions {
+		if !purged[t.WalletUID] {
+			transactions = append(transactions, t)
+		}
+	}
+	mr.Store.Transactions = transactions
+
+	holds := mr.Store.Holds[:0]
+	for _, h := range mr.Store.Holds {
+		if !purged[h.WalletUID] {
+			holds = append(holds, h)
+		}
+	}
+	mr.Store.Holds = holds
+
+	changes := mr.Store.StatusChanges[:0]
+	for _, c := range mr.Store.StatusChanges {
+		if !purged[c.WalletUID] {
+			changes = append(changes, c)
+		}
+	}
+	mr.Store.StatusChanges = changes
+
+	return len(purged), nil
+}
+
+func (mr *memWalletRepo) GetAll(includeDeleted bool) ([]*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var wallets []*models.Wallet
+	for _, w := range mr.Store.Wallets {
+		if includeDeleted || !w.DeletedAt.Valid {
+			res := *w
+			wallets = append(wallets, &res)
+		}
+	}
+
+	return wallets, nil
+}
+
+func (mr *memWalletRepo) GetByUID(uid string) (*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w := mr.Store.WalletByUID(uid, false)
+	if w == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWalletRepo.GetByUID error")
+	}
+
+	res := *w
+	return &res, nil
+}
+
+// ChangeAmount applies t.Amount to the wallet balance and stores t as a ledger
+// entry.
+func (mr *memWalletRepo) ChangeAmount(t *models.Transaction) (*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w, err := mr.Store.ApplyDelta(t.WalletUID, t.Amount)
+	if err != nil {
+		return nil, errors.Wrap(err, "memWalletRepo.ChangeAmount error")
+	}
+
+	mr.Store.AddTransaction(t)
+	mr.Store.AddEvents(outbox.BalanceChanged(t, w))
+
+	return w, nil
+}
+
+// ChangeStatus moves the wallet from c.FromStatus to c.ToStatus and records c.
+// A wallet is closed only while nothing is left on it.
+func (mr *memWalletRepo) ChangeStatus(c *models.WalletStatusChange) (*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w := mr.Store.WalletByUID(c.WalletUID, false)
+	switch {
+	case w == nil:
+		return nil, errors.Wrap(models.ErrNotFound, "memWalletRepo.ChangeStatus error")
+	case w.Status != c.FromStatus:
+		return nil, errors.Wrap(models.ErrStatusTransition, "memWalletRepo.ChangeStatus error")
+	case c.ToStatus == models.WalletStatusClosed && (w.Amount != 0 || w.Held != 0):
+		return nil, errors.Wrap(models.ErrNonZeroBalance, "memWalletRepo.ChangeStatus error")
+	}
+
+	w.Status = c.ToStatus
+	w.Version++
+
+	c.ID = mr.Store.NextID(c.TableName())
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	stored := *c
+	mr.Store.StatusChanges = append(mr.Store.StatusChanges, &stored)
+
+	res := *w
+	mr.Store.AddEvents(outbox.StatusChanged(&stored, &res))
+	return &res, nil
+}
+
+func (mr *memWalletRepo) GetStatusChanges(uid string) ([]*models.WalletStatusChange, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var changes []*models.WalletStatusChange
+	for _, c := range mr.Store.StatusChanges {
+		if c.WalletUID == uid {
+			res := *c
+			changes = append(changes, &res)
+		}
+	}
+
+	return changes, nil
+}
+
+func (mr *memWalletRepo) byID(id int, includeDeleted bool) *models.Wallet {
+	for _, w := range mr.Store.Wallets {
+		if w.ID == id && (includeDeleted || !w.DeletedAt.Valid) {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// SetShards only records the number of shards. The store changes one wallet at
+// a time anyway, so the balance stays in the wallet itself.
+func (mr *memWalletRepo) SetShards(uid string, shards int) (*models.Wallet, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	w := mr.Store.WalletByUID(uid, false)
+	if w == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWalletRepo.SetShards error")
+	}
+
+	w.Shards = shards
+	w.Version++
+
+	res := *w
+	mr.Store.AddEvents(outbox.WalletEvent(models.EventWalletShardsChanged, &res))
+	return &res, nil
+}
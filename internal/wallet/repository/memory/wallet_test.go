@@ -0,0 +1,20 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/internal/wallet/repository/conformance"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+)
+
+func TestWalletMemRepoConformance(t *testing.T) {
+	suite.RunSuite(t, conformance.New(func(t provider.T) repository.WalletRepositoryI {
+		var logger logger.Logger
+
+		return New(logger, memstore.New())
+	}))
+}
@@ -5,6 +5,8 @@ package mocks
 import (
 	models "github.com/Davmie/javaCode/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // WalletRepositoryI is an autogenerated mock type for the WalletRepositoryI type
@@ -12,6 +14,58 @@ type WalletRepositoryI struct {
 	mock.Mock
 }
 
+// ChangeAmount provides a mock function with given fields: t
+func (_m *WalletRepositoryI) ChangeAmount(t *models.Transaction) (*models.Wallet, error) {
+	ret := _m.Called(t)
+
+	var r0 *models.Wallet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*models.Transaction) (*models.Wallet, error)); ok {
+		return rf(t)
+	}
+	if rf, ok := ret.Get(0).(func(*models.Transaction) *models.Wallet); ok {
+		r0 = rf(t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Wallet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*models.Transaction) error); ok {
+		r1 = rf(t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeStatus provides a mock function with given fields: c
+func (_m *WalletRepositoryI) ChangeStatus(c *models.WalletStatusChange) (*models.Wallet, error) {
+	ret := _m.Called(c)
+
+	var r0 *models.Wallet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*models.WalletStatusChange) (*models.Wallet, error)); ok {
+		return rf(c)
+	}
+	if rf, ok := ret.Get(0).(func(*models.WalletStatusChange) *models.Wallet); ok {
+		r0 = rf(c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Wallet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*models.WalletStatusChange) error); ok {
+		r1 = rf(c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: w
 func (_m *WalletRepositoryI) Create(w *models.Wallet) error {
 	ret := _m.Called(w)
@@ -26,13 +80,13 @@ func (_m *WalletRepositoryI) Create(w *models.Wallet) error {
 	return r0
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *WalletRepositoryI) Delete(id int) error {
-	ret := _m.Called(id)
+// Delete provides a mock function with given fields: id, version
+func (_m *WalletRepositoryI) Delete(id int, version int) error {
+	ret := _m.Called(id, version)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(int, int) error); ok {
+		r0 = rf(id, version)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -66,25 +120,25 @@ func (_m *WalletRepositoryI) Get(id int) (*models.Wallet, error) {
 	return r0, r1
 }
 
-// GetAll provides a mock function with given fields:
-func (_m *WalletRepositoryI) GetAll() ([]*models.Wallet, error) {
-	ret := _m.Called()
+// GetAll provides a mock function with given fields: includeDeleted
+func (_m *WalletRepositoryI) GetAll(includeDeleted bool) ([]*models.Wallet, error) {
+	ret := _m.Called(includeDeleted)
 
 	var r0 []*models.Wallet
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*models.Wallet, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(bool) ([]*models.Wallet, error)); ok {
+		return rf(includeDeleted)
 	}
-	if rf, ok := ret.Get(0).(func() []*models.Wallet); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(bool) []*models.Wallet); ok {
+		r0 = rf(includeDeleted)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Wallet)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(bool) error); ok {
+		r1 = rf(includeDeleted)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -118,6 +172,96 @@ func (_m *WalletRepositoryI) GetByUID(uid string) (*models.Wallet, error) {
 	return r0, r1
 }
 
+// GetStatusChanges provides a mock function with given fields: uid
+func (_m *WalletRepositoryI) GetStatusChanges(uid string) ([]*models.WalletStatusChange, error) {
+	ret := _m.Called(uid)
+
+	var r0 []*models.WalletStatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]*models.WalletStatusChange, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) []*models.WalletStatusChange); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WalletStatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Purge provides a mock function with given fields: before
+func (_m *WalletRepositoryI) Purge(before time.Time) (int, error) {
+	ret := _m.Called(before)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time) (int, error)); ok {
+		return rf(before)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time) int); ok {
+		r0 = rf(before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Restore provides a mock function with given fields: id
+func (_m *WalletRepositoryI) Restore(id int) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetShards provides a mock function with given fields: uid, shards
+func (_m *WalletRepositoryI) SetShards(uid string, shards int) (*models.Wallet, error) {
+	ret := _m.Called(uid, shards)
+
+	var r0 *models.Wallet
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, int) (*models.Wallet, error)); ok {
+		return rf(uid, shards)
+	}
+	if rf, ok := ret.Get(0).(func(string, int) *models.Wallet); ok {
+		r0 = rf(uid, shards)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Wallet)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(uid, shards)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: w
 func (_m *WalletRepositoryI) Update(w *models.Wallet) error {
 	ret := _m.Called(w)
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"sync"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// batchedWalletRepo queues concurrent ChangeAmount calls per wallet and applies
+// each queue in batches: the wallet row is locked once, changed by a single
+// UPDATE and the ledger entries are inserted by a single INSERT. Meant for hot
+// wallets, where waiting for the row lock costs more than the batching.
+type batchedWalletRepo struct {
+	*pgWalletRepo
+	MaxBatch int
+
+	mu     sync.Mutex
+	queues map[string][]*balanceChange
+}
+
+// balanceChange is a queued ChangeAmount call waiting for its result.
+type balanceChange struct {
+	t      *models.Transaction
+	wallet *models.Wallet
+	err    error
+	done   chan struct{}
+}
+
+// NewBatched returns the repository of New whose ChangeAmount applies at most
+// maxBatch queued changes of a wallet per transaction.
+func NewBatched(logger logger.Logger, db *gorm.DB, maxBatch int) repository.WalletRepositoryI {
+	return &batchedWalletRepo{
+		pgWalletRepo: &pgWalletRepo{
+			Logger: logger,
+			DB:     db,
+		},
+		MaxBatch: maxBatch,
+		queues:   make(map[string][]*balanceChange),
+	}
+}
+
+// ChangeAmount waits until t is applied together with the changes queued for
+// the same wallet. A withdrawal that doesn't fit into the available balance
+// fails alone, the rest of its batch is applied. The returned wallet has the
+// balance right after t.
+func (br *batchedWalletRepo) ChangeAmount(t *models.Transaction) (*models.Wallet, error) {
+	c := &balanceChange{t: t, done: make(chan struct{})}
+
+	br.mu.Lock()
+	queue, draining := br.queues[t.WalletUID]
+	br.queues[t.WalletUID] = append(queue, c)
+	br.mu.Unlock()
+
+	if !draining {
+		go br.drain(t.WalletUID)
+	}
+
+	<-c.done
+	if c.err != nil {
+		return nil, errors.Wrap(c.err, "batchedWalletRepo.ChangeAmount error")
+	}
+
+	return c.wallet, nil
+}
+
+// drain applies the queue of the wallet until it is empty. There is a single
+// drain per wallet at a time, it runs while the wallet has a queue.
+func (br *batchedWalletRepo) drain(uid string) {
+	for {
+		br.mu.Lock()
+		queue := br.queues[uid]
+		if len(queue) == 0 {
+			delete(br.queues, uid)
+			br.mu.Unlock()
+			return
+		}
+
+		n := min(len(queue), br.MaxBatch)
+		batch := queue[:n:n]
+		br.queues[uid] = queue[n:]
+		br.mu.Unlock()
+
+		br.apply(uid, batch)
+	}
+}
+
+// apply changes the wallet by the batch in one transaction and hands out the
+// results. The changes of a sharded wallet are already spread over its shards,
+// they are applied one by one.
+func (br *batchedWalletRepo) apply(uid string, batch []*balanceChange) {
+	sharded := false
+	err := dbtx.Run(br.DB, "wallet.change_amount_batch", func(tx *gorm.DB) error {
+		var w models.Wallet
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("uid = ?", uid).Take(&w)
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return models.ErrNotFound
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if sharded = w.Shards > 0; sharded {
+			return nil
+		}
+
+		switch w.Status {
+		case models.WalletStatusFrozen:
+			return models.ErrWalletFrozen
+		case models.WalletStatusClosed:
+			return models.ErrWalletClosed
+		}
+
+		var (
+			accepted []*models.Transaction
+			applied  []*balanceChange
+			delta    int
+		)
+		for _, c := range batch {
+			c.wallet, c.err = nil, nil
+			c.t.ID = 0
+
+			if c.t.Amount < 0 && w.Amount-w.Held+delta+c.t.Amount < 0 {
+				c.err = models.ErrInsufficientFunds
+				continue
+			}
+
+			delta += c.t.Amount
+			snapshot := w
+			snapshot.Amount += delta
+			c.wallet = &snapshot
+
+			accepted = append(accepted, c.t)
+			applied = append(applied, c)
+		}
+
+		if len(accepted) == 0 {
+			return nil
+		}
+
+		res = tx.Model(&w).Clauses(clause.Returning{}).Where("uid = ?", uid).
+			Updates(map[string]interface{}{"amount": gorm.Expr("amount + ?", delta), "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if err := tx.Create(&accepted).Error; err != nil {
+			return err
+		}
+
+		events := make([]*models.OutboxEvent, 0, len(applied))
+		for _, c := range applied {
+			c.wallet.Version = w.Version
+			events = append(events, outbox.BalanceChanged(c.t, c.wallet))
+		}
+
+		return outbox.Record(tx, events...)
+	})
+
+	if err == nil && sharded {
+		br.applyEach(batch)
+		return
+	}
+
+	for _, c := range batch {
+		if err != nil {
+			c.wallet, c.err = nil, err
+		}
+		close(c.done)
+	}
+}
+
+func (br *batchedWalletRepo) applyEach(batch []*balanceChange) {
+	var wg sync.WaitGroup
+	for _, c := range batch {
+		wg.Add(1)
+		go func(c *balanceChange) {
+			defer wg.Done()
+			c.wallet, c.err = br.pgWalletRepo.ChangeAmount(c.t)
+			close(c.done)
+		}(c)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,191 @@
+package postgres
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/gorm"
+)
+
+type BatchedWalletRepoTestSuite struct {
+	suite.Suite
+	db   *gorm.DB
+	repo *batchedWalletRepo
+}
+
+func TestBatchedWalletRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(BatchedWalletRepoTestSuite))
+}
+
+func (s *BatchedWalletRepoTestSuite) BeforeEach(t provider.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal("error while opening sqlite", err)
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.repo = NewBatched(logger, db, 10).(*batchedWalletRepo)
+}
+
+func (s *BatchedWalletRepoTestSuite) createWallet(t provider.T, amount int) models.Wallet {
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID(uuid.NewString()).
+		WithAmount(amount).
+		WithStatus(models.WalletStatusActive).
+		Build()
+
+	t.Require().NoError(s.repo.Create(&wallet))
+
+	return wallet
+}
+
+func (s *BatchedWalletRepoTestSuite) change(walletUID string, amount int) *balanceChange {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID(uuid.NewString()).
+		WithWalletUID(walletUID).
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(amount).
+		Build()
+
+	return &balanceChange{t: &transaction, done: make(chan struct{})}
+}
+
+func (s *BatchedWalletRepoTestSuite) TestApplyBatch(t provider.T) {
+	wallet := s.createWallet(t, 10)
+
+	batch := []*balanceChange{
+		s.change(wallet.UID, 5),
+		s.change(wallet.UID, -20),
+		s.change(wallet.UID, -15),
+		s.change(wallet.UID, 7),
+	}
+	s.repo.apply(wallet.UID, batch)
+
+	t.Require().NoError(batch[0].err)
+	t.Assert().Equal(15, batch[0].wallet.Amount)
+	t.Assert().ErrorIs(batch[1].err, models.ErrInsufficientFunds)
+	t.Assert().Nil(batch[1].wallet)
+	t.Require().NoError(batch[2].err)
+	t.Assert().Equal(0, batch[2].wallet.Amount)
+	t.Require().NoError(batch[3].err)
+	t.Assert().Equal(7, batch[3].wallet.Amount)
+	t.Assert().Equal(batch[0].wallet.Version, batch[3].wallet.Version)
+
+	resWallet, err := s.repo.GetByUID(wallet.UID)
+	t.Require().NoError(err)
+	t.Assert().Equal(7, resWallet.Amount)
+	t.Assert().Equal(wallet.Version+1, resWallet.Version)
+
+	var entries int64
+	t.Require().NoError(s.db.Model(&models.Transaction{}).Where("wallet_uid = ?", wallet.UID).Count(&entries).Error)
+	t.Assert().EqualValues(3, entries)
+}
+
+func (s *BatchedWalletRepoTestSuite) TestApplyBatchFrozen(t provider.T) {
+	wallet := s.createWallet(t, 10)
+	t.Require().NoError(s.db.Model(&models.Wallet{}).Where("uid = ?", wallet.UID).Update("status", models.WalletStatusFrozen).Error)
+
+	batch := []*balanceChange{s.change(wallet.UID, 5), s.change(wallet.UID, 1)}
+	s.repo.apply(wallet.UID, batch)
+
+	for _, c := range batch {
+		t.Assert().ErrorIs(c.err, models.ErrWalletFrozen)
+		t.Assert().Nil(c.wallet)
+	}
+}
+
+func (s *BatchedWalletRepoTestSuite) TestConcurrentDeposits(t provider.T) {
+	wallet := s.createWallet(t, 0)
+
+	const deposits = 50
+	errs := make([]error, deposits)
+	var wg sync.WaitGroup
+	for i := 0; i < deposits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.repo.ChangeAmount(s.change(wallet.UID, 1).t)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		t.Assert().NoError(err)
+	}
+
+	resWallet, err := s.repo.GetByUID(wallet.UID)
+	t.Require().NoError(err)
+	t.Assert().Equal(deposits, resWallet.Amount)
+}
+
+// BenchmarkChangeAmount compares concurrent deposits into a single wallet made
+// one by one and in batches. Against SQLite it always runs, against Postgres
+// when it is available (see openTestPostgres).
+func BenchmarkChangeAmount(b *testing.B) {
+	var logger logger.Logger
+
+	backends := map[string]func(b *testing.B) *gorm.DB{
+		"sqlite": func(b *testing.B) *gorm.DB {
+			db, err := database.OpenSQLite(b.TempDir() + "/bench.db")
+			if err != nil {
+				b.Fatal(err)
+			}
+			return db
+		},
+		"postgres": func(b *testing.B) *gorm.DB {
+			db, _ := openTestPostgres(b)
+			return db
+		},
+	}
+
+	for name, open := range backends {
+		b.Run(name, func(b *testing.B) {
+			db := open(b)
+
+			b.Run("naive", func(b *testing.B) {
+				benchmarkDeposits(b, New(logger, db))
+			})
+			b.Run("batched", func(b *testing.B) {
+				benchmarkDeposits(b, NewBatched(logger, db, 100))
+			})
+		})
+	}
+}
+
+func benchmarkDeposits(b *testing.B, repo repository.WalletRepositoryI) {
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID(uuid.NewString()).
+		WithStatus(models.WalletStatusActive).
+		Build()
+	if err := repo.Create(&wallet); err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			transaction := testBuilders.NewTransactionBuilder().
+				WithUID(uuid.NewString()).
+				WithWalletUID(wallet.UID).
+				WithType(models.TransactionTypeDeposit).
+				WithAmount(1).
+				Build()
+
+			if _, err := repo.ChangeAmount(&transaction); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/migrations"
+	"github.com/Davmie/javaCode/internal/wallet/repository"
+	"github.com/Davmie/javaCode/internal/wallet/repository/conformance"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// defaultTestPostgresDSN points to the instance of docker-compose.yml. Set
+// WALLETS_TEST_POSTGRES_DSN to use another one.
+const defaultTestPostgresDSN = "host=localhost user=program password=test dbname=wallets port=5432 connect_timeout=2"
+
+func TestWalletSQLiteConformance(t *testing.T) {
+	suite.RunSuite(t, conformance.New(func(t provider.T) repository.WalletRepositoryI {
+		db, err := database.OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatal("error while opening sqlite", err)
+		}
+
+		var logger logger.Logger
+
+		return New(logger, db)
+	}))
+}
+
+func TestWalletBatchedSQLiteConformance(t *testing.T) {
+	suite.RunSuite(t, conformance.New(func(t provider.T) repository.WalletRepositoryI {
+		db, err := database.OpenSQLite(":memory:")
+		if err != nil {
+			t.Fatal("error while opening sqlite", err)
+		}
+
+		var logger logger.Logger
+
+		return NewBatched(logger, db, 10)
+	}))
+}
+
+// openTestPostgres returns a database whose search path is a freshly migrated
+// schema of its own, dropped when the test ends. The test is skipped when no
+// Postgres instance is reachable.
+func openTestPostgres(t testing.TB) (*gorm.DB, string) {
+	dsn := os.Getenv("WALLETS_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		dsn = defaultTestPostgresDSN
+	}
+
+	// gorm pings the database on open.
+	admin, err := gorm.Open(postgres.New(postgres.Config{DSN: dsn}), &gorm.Config{})
+	if err != nil {
+		t.Skipf("postgres is not available: %v", err)
+	}
+
+	schema := fmt.Sprintf("conformance_%d", time.Now().UnixNano())
+	if err = admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+	})
+
+	dsn += " search_path=" + schema
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: dsn}), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrations.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, dsn
+}
+
+func TestWalletPostgresConformance(t *testing.T) {
+	db, _ := openTestPostgres(t)
+
+	suite.RunSuite(t, conformance.New(func(t provider.T) repository.WalletRepositoryI {
+		err := db.Exec(`TRUNCATE "outbox_event", "wallet_shard", "transaction", "hold", "wallet_status_change", "wallet" RESTART IDENTITY`).Error
+		if err != nil {
+			t.Fatal("error while cleaning tables", err)
+		}
+
+		var logger logger.Logger
+
+		return New(logger, db)
+	}))
+}
+
+func TestWalletBatchedPostgresConformance(t *testing.T) {
+	db, _ := openTestPostgres(t)
+
+	suite.RunSuite(t, conformance.New(func(t provider.T) repository.WalletRepositoryI {
+		err := db.Exec(`TRUNCATE "outbox_event", "wallet_shard", "transaction", "hold", "wallet_status_change", "wallet" RESTART IDENTITY`).Error
+		if err != nil {
+			t.Fatal("error while cleaning tables", err)
+		}
+
+		var logger logger.Logger
+
+		return NewBatched(logger, db, 10)
+	}))
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/lib/pq"
+)
+
+// WalletChangedChannel is notified with the uid of every changed wallet row by
+// the trigger installed with the schema.
+const WalletChangedChannel = "wallet_changed"
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	listenerPingInterval = 90 * time.Second
+)
+
+// ListenChanges calls evict with the uid of every wallet changed in the database,
+// by this or any other instance, until ctx is done. The connection is restored
+// automatically. Notifications sent while it was down are lost, so flush is
+// called once listening starts and after every reconnect.
+func ListenChanges(ctx context.Context, dsn string, logger logger.Logger, evict func(uids ...string), flush func()) {
+	listener := pq.NewListener(dsn, minReconnectInterval, maxReconnectInterval, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			logger.Errorw("wallet change listener error", "err:", err.Error())
+		}
+	})
+	defer listener.Close()
+
+	if !listen(ctx, listener, logger) {
+		return
+	}
+	flush()
+
+	ping := time.NewTicker(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			if n == nil {
+				logger.Infow("wallet change listener reconnected, flushing cache")
+				flush()
+				continue
+			}
+			evict(n.Extra)
+		case <-ping.C:
+			// Detects a dead connection even when nothing changes.
+			if err := listener.Ping(); err != nil {
+				logger.Errorw("wallet change listener ping failed", "err:", err.Error())
+			}
+		}
+	}
+}
+
+// listen subscribes the listener to WalletChangedChannel and reports whether it
+// did before ctx was done. Listen waits for the connection by itself, it fails
+// when the server refuses LISTEN, a standby for one, and is retried then.
+func listen(ctx context.Context, listener *pq.Listener, logger logger.Logger) bool {
+	// Closing the listener is the only way to stop a waiting Listen.
+	stop := context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+	})
+	defer stop()
+
+	wait := minReconnectInterval
+	for {
+		err := listener.Listen(WalletChangedChannel)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		logger.Errorw("can`t listen to wallet changes",
+			"err:", err.Error(),
+			"retry in:", wait.String())
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxReconnectInterval)
+	}
+}
@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestListenChanges(t *testing.T) {
+	db, dsn := openTestPostgres(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evicted := make(chan string, 10)
+	go ListenChanges(ctx, dsn, zap.NewNop().Sugar(), func(uids ...string) {
+		for _, uid := range uids {
+			evicted <- uid
+		}
+	}, func() {})
+
+	expectEviction(t, db, evicted)
+}
+
+func TestListenChangesUnreachableAtFirst(t *testing.T) {
+	db, dsn := openTestPostgres(t)
+
+	// The database is behind a proxy dropping connections until it is up.
+	var host, port string
+	var fields []string
+	for _, field := range strings.Fields(dsn) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "host":
+			host = value
+		case "port":
+			port = value
+		default:
+			fields = append(fields, field)
+		}
+	}
+	if host == "" || port == "" {
+		t.Skipf("no host and port in %q", dsn)
+	}
+	target := net.JoinHostPort(host, port)
+
+	var up atomic.Bool
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go func() {
+		for {
+			conn, err := proxy.Accept()
+			if err != nil {
+				return
+			}
+			if !up.Load() {
+				_ = conn.Close()
+				continue
+			}
+			go forward(conn, target)
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(proxy.Addr().String())
+	fields = append(fields, "host="+host, "port="+port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evicted := make(chan string, 10)
+	flushed := make(chan struct{}, 10)
+	go ListenChanges(ctx, strings.Join(fields, " "), zap.NewNop().Sugar(), func(uids ...string) {
+		for _, uid := range uids {
+			evicted <- uid
+		}
+	}, func() {
+		flushed <- struct{}{}
+	})
+
+	time.Sleep(2 * minReconnectInterval)
+	up.Store(true)
+
+	select {
+	case <-flushed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no flush once listening started")
+	}
+
+	expectEviction(t, db, evicted)
+}
+
+func TestListenChangesStopsWhileUnreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		ListenChanges(ctx, "host=127.0.0.1 port=1 connect_timeout=1", zap.NewNop().Sugar(), func(...string) {}, func() {
+			t.Error("flushed without listening")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still listening after the context is done")
+	}
+}
+
+// forward copies the bytes between conn and the target until either side
+// closes.
+func forward(conn net.Conn, target string) {
+	defer conn.Close()
+
+	upstream, err := net.Dial("tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+	}()
+	_, _ = io.Copy(conn, upstream)
+}
+
+// expectEviction changes a new wallet until its uid comes out of evicted.
+// Listening starts in the background, so the first changes may go unnoticed.
+func expectEviction(t *testing.T, db *gorm.DB, evicted <-chan string) {
+	t.Helper()
+
+	repo := New(zap.NewNop().Sugar(), db)
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID(uuid.NewString()).
+		WithStatus(models.WalletStatusActive).
+		Build()
+	if err := repo.Create(&wallet); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(10 * time.Second)
+	for {
+		if _, err := repo.ChangeAmount(&models.Transaction{
+			UID:       uuid.NewString(),
+			WalletUID: wallet.UID,
+			Type:      models.TransactionTypeDeposit,
+			Amount:    1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case uid := <-evicted:
+			if uid == wallet.UID {
+				return
+			}
+		case <-time.After(200 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("no wallet change notification")
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"math/rand/v2"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/outbox"
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Shards of a wallet are always locked before its row, so transactions locking
+// both don't deadlock.
+
+// SetShards moves the whole balance of the wallet into its row and spreads the
+// further deposits over the given number of shards, 0 stops sharding.
+func (pr *pgWalletRepo) SetShards(uid string, shards int) (*models.Wallet, error) {
+	var w models.Wallet
+	err := dbtx.Run(pr.DB, "wallet.set_shards", func(tx *gorm.DB) error {
+		w = models.Wallet{}
+
+		if err := SweepShards(tx, uid); err != nil {
+			return err
+		}
+
+		if err := tx.Where("wallet_uid = ?", uid).Delete(&models.WalletShard{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Model(&w).Clauses(clause.Returning{}).Where("uid = ?", uid).
+			Updates(map[string]interface{}{"shards": shards, "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrNotFound
+		}
+
+		if shards > 0 {
+			rows := make([]models.WalletShard, shards)
+			for i := range rows {
+				rows[i] = models.WalletShard{WalletUID: uid, Shard: i}
+			}
+
+			if err := tx.Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+
+		return outbox.Record(tx, outbox.WalletEvent(models.EventWalletShardsChanged, &w))
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.SetShards error")
+	}
+
+	return &w, nil
+}
+
+// SweepShards moves the balance of the shards of the wallet into its row, so
+// the row alone holds the whole balance until tx ends.
+func SweepShards(tx *gorm.DB, uid string) error {
+	var shards []models.WalletShard
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("wallet_uid = ?", uid).Find(&shards).Error
+	if err != nil {
+		return err
+	}
+
+	swept := 0
+	for _, s := range shards {
+		swept += s.Amount
+	}
+
+	if swept == 0 {
+		return nil
+	}
+
+	err = tx.Model(&models.WalletShard{}).Where("wallet_uid = ? AND amount <> 0", uid).Update("amount", 0).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Wallet{}).Where("uid = ?", uid).
+		Updates(map[string]interface{}{"amount": gorm.Expr("amount + ?", swept), "version": gorm.Expr("version + 1")}).Error
+}
+
+// WaitForShards waits until the transactions holding the shards of the wallet
+// are over and keeps new ones out until tx ends. Deposits into a shard number
+// their ledger entry without the wallet row lock, so they may commit out of
+// the order of the ids. Every transaction writing the ledger of a sharded
+// wallet holds one of its shards, and those of other wallets commit in the
+// order of the ids under the wallet row lock, so a statement after the wait
+// sees every entry below the highest id it sees.
+func WaitForShards(tx *gorm.DB, uid string) error {
+	var shards []int
+	return tx.Model(&models.WalletShard{}).Clauses(clause.Locking{Strength: "SHARE"}).Select("shard").
+		Where("wallet_uid = ?", uid).Order("shard").Find(&shards).Error
+}
+
+// SweepIfSharded sweeps the shards of a sharded wallet and reports whether it
+// did. Operations that check the balance in the wallet row retry after a sweep.
+func SweepIfSharded(tx *gorm.DB, uid string) (bool, error) {
+	var w models.Wallet
+	res := tx.Select("shards").Where("uid = ?", uid).Take(&w)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if res.Error != nil {
+		return false, res.Error
+	}
+
+	if w.Shards == 0 {
+		return false, nil
+	}
+
+	return true, SweepShards(tx, uid)
+}
+
+// applyShardDelta changes a random shard of the sharded wallet w by delta.
+// Withdrawals that don't fit into the shard are taken from the wallet row after
+// a sweep. The wallet row is not locked, so concurrent deposits record their
+// ledger entries and events in any order, and the returned balance may already
+// include some of them. Events of sharded wallets carry no balance, readers of
+// the ledger by id wait for the deposits with WaitForShards.
+func applyShardDelta(tx *gorm.DB, w *models.Wallet, delta int) (*models.Wallet, error) {
+	if w.Status != models.WalletStatusActive {
+		return nil, rejection(w)
+	}
+
+	active := tx.Model(&models.Wallet{}).Select("uid").Where("uid = ? AND status = ?", w.UID, models.WalletStatusActive)
+	res := tx.Model(&models.WalletShard{}).
+		Where("wallet_uid IN (?) AND shard = ? AND amount + ? >= 0", active, rand.IntN(w.Shards), delta).
+		Update("amount", gorm.Expr("amount + ?", delta))
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		if err := SweepShards(tx, w.UID); err != nil {
+			return nil, err
+		}
+
+		return applyRowDelta(tx, w.UID, delta, false)
+	}
+
+	return loadWallet(tx, w.UID)
+}
+
+// loadWallet reads the wallet with the balance of its shards.
+func loadWallet(tx *gorm.DB, uid string) (*models.Wallet, error) {
+	w, err := takeWithShards(tx.Where("uid = ?", uid))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, models.ErrNotFound
+	}
+
+	return w, err
+}
+
+// shardAmount is the balance of the shards of the wallet read by the statement.
+// Reading it in the same statement as the row gives both the same snapshot, so
+// a concurrent sweep moving the shards into the row is counted exactly once.
+const shardAmount = "(SELECT COALESCE(SUM(wallet_shard.amount), 0) FROM wallet_shard WHERE wallet_shard.wallet_uid = wallet.uid) AS shard_amount"
+
+type walletWithShards struct {
+	models.Wallet
+	ShardAmount int
+}
+
+func (w *walletWithShards) wallet() *models.Wallet {
+	w.Wallet.Amount += w.ShardAmount
+	return &w.Wallet
+}
+
+// takeWithShards reads the first wallet matched by db with the balance of its
+// shards.
+func takeWithShards(db *gorm.DB) (*models.Wallet, error) {
+	var w walletWithShards
+	if err := db.Model(&models.Wallet{}).Select("*, " + shardAmount).Take(&w).Error; err != nil {
+		return nil, err
+	}
+
+	return w.wallet(), nil
+}
+
+// findWithShards reads the wallets matched by db with the balance of their
+// shards.
+func findWithShards(db *gorm.DB) ([]*models.Wallet, error) {
+	var rows []*walletWithShards
+	if err := db.Model(&models.Wallet{}).Select("*, " + shardAmount).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	wallets := make([]*models.Wallet, 0, len(rows))
+	for _, w := range rows {
+		wallets = append(wallets, w.wallet())
+	}
+
+	return wallets, nil
+}
+
+// AddShards adds the balance of the shards to the amount of the sharded wallets.
+// The wallets must be locked by tx, otherwise a sweep may commit between the
+// reads of the rows and of the shards.
+func AddShards(db *gorm.DB, wallets ...*models.Wallet) error {
+	var uids []string
+	for _, w := range wallets {
+		if w.Shards > 0 {
+			uids = append(uids, w.UID)
+		}
+	}
+
+	if len(uids) == 0 {
+		return nil
+	}
+
+	var sums []models.WalletShard
+	err := db.Model(&models.WalletShard{}).Select("wallet_uid, SUM(amount) AS amount").
+		Where("wallet_uid IN ?", uids).Group("wallet_uid").Scan(&sums).Error
+	if err != nil {
+		return err
+	}
+
+	byUID := make(map[string]int, len(sums))
+	for _, s := range sums {
+		byUID[s.WalletUID] = s.Amount
+	}
+
+	for _, w := range wallets {
+		w.Amount += byUID[w.UID]
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestWaitForShards(t *testing.T) {
+	db, _ := openTestPostgres(t)
+
+	repo := New(zap.NewNop().Sugar(), db)
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("b1eebc99-9c0b-4ef8-bb6d-6bb9bd380a12").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	if err := repo.Create(&wallet); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.SetShards(wallet.UID, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	deposit := func(tx *gorm.DB, shard int, uid string) {
+		err := tx.Model(&models.WalletShard{}).Where("wallet_uid = ? AND shard = ?", wallet.UID, shard).
+			Update("amount", gorm.Expr("amount + 1")).Error
+		if err == nil {
+			err = tx.Create(&models.Transaction{UID: uid, WalletUID: wallet.UID, Type: models.TransactionTypeDeposit, Amount: 1}).Error
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	slowUID, fastUID := uuid.NewString(), uuid.NewString()
+
+	// The first deposit gets the lower id but commits after the second one.
+	slow := db.Begin()
+	defer slow.Rollback()
+	deposit(slow, 0, slowUID)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		deposit(tx, 1, fastUID)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := make(chan []models.Transaction)
+	go func() {
+		var transactions []models.Transaction
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := WaitForShards(tx, wallet.UID); err != nil {
+				return err
+			}
+			return tx.Where("wallet_uid = ?", wallet.UID).Order("id").Find(&transactions).Error
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		read <- transactions
+	}()
+
+	select {
+	case transactions := <-read:
+		t.Fatalf("read %d entries while a deposit into a shard was running", len(transactions))
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := slow.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+
+	transactions := <-read
+	if len(transactions) != 2 || transactions[0].UID != slowUID || transactions[1].UID != fastUID {
+		t.Fatalf("read %+v, want the slow deposit before the fast one", transactions)
+	}
+}
@@ -1,6 +1,10 @@
 package postgres
 
 import (
+	"time"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/outbox"
 	"github.com/Davmie/javaCode/internal/wallet/repository"
 	"github.com/Davmie/javaCode/models"
 	"github.com/Davmie/javaCode/pkg/logger"
@@ -22,65 +26,375 @@ func New(logger logger.Logger, db *gorm.DB) repository.WalletRepositoryI {
 }
 
 func (pr *pgWalletRepo) Create(w *models.Wallet) error {
-	tx := pr.DB.Create(w)
+	id := w.ID
+	err := dbtx.Run(pr.DB, "wallet.create", func(tx *gorm.DB) error {
+		// A failed attempt leaves the id of its insert behind.
+		w.ID = id
+		if err := tx.Create(w).Error; err != nil {
+			return err
+		}
 
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgWalletRepo.Create error while inserting in repo")
+		return outbox.Record(tx, outbox.WalletEvent(models.EventWalletCreated, w))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgWalletRepo.Create error while inserting in repo")
 	}
 
 	return nil
 }
 
 func (pr *pgWalletRepo) Get(id int) (*models.Wallet, error) {
-	var w models.Wallet
-	tx := pr.DB.Where("id = ?", id).Take(&w)
+	w, err := takeWithShards(pr.DB.Where("id = ?", id))
 
-	if tx.Error != nil {
-		return nil, errors.Wrap(tx.Error, "pgWalletRepo.Get error")
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgWalletRepo.Get error")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.Get error")
 	}
 
-	return &w, nil
+	return w, nil
 }
 
+// Update changes the metadata of the wallet if it still has version w.Version.
 func (pr *pgWalletRepo) Update(w *models.Wallet) error {
-	tx := pr.DB.Clauses(clause.Returning{}).Omit("id").Select("amount", "uid").Updates(w)
+	version := w.Version
+	err := dbtx.Run(pr.DB, "wallet.update", func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Returning{}).Model(w).Where("version = ?", version).
+			Updates(map[string]interface{}{"name": w.Name, "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
 
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgWalletRepo.Update error while inserting in repo")
+		if res.RowsAffected == 0 {
+			return versionMismatch(tx, w.ID)
+		}
+
+		if err := AddShards(tx, w); err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.WalletEvent(models.EventWalletUpdated, w))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgWalletRepo.Update error")
 	}
 
 	return nil
 }
 
-func (pr *pgWalletRepo) Delete(id int) error {
-	tx := pr.DB.Delete(&models.Wallet{}, id)
+// Delete is a soft delete, the wallet stays in the table until it is purged.
+// It only succeeds while the wallet still has the given version.
+func (pr *pgWalletRepo) Delete(id int, version int) error {
+	err := dbtx.Run(pr.DB, "wallet.delete", func(tx *gorm.DB) error {
+		var w models.Wallet
+		res := tx.Model(&w).Clauses(clause.Returning{}).Where("id = ? AND version = ?", id, version).
+			Updates(map[string]interface{}{"deleted_at": time.Now(), "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
 
-	if tx.Error != nil {
-		return errors.Wrap(tx.Error, "pgWalletRepo.Delete error")
+		if res.RowsAffected == 0 {
+			return versionMismatch(tx, id)
+		}
+
+		if err := AddShards(tx, &w); err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.WalletEvent(models.EventWalletDeleted, &w))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgWalletRepo.Delete error")
 	}
 
 	return nil
 }
 
-func (pr *pgWalletRepo) GetAll() ([]*models.Wallet, error) {
-	var wallets []*models.Wallet
+// versionMismatch tells why a conditional update by id in tx matched no rows.
+func versionMismatch(tx *gorm.DB, id int) error {
+	var w models.Wallet
+	res := tx.Where("id = ?", id).Take(&w)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return models.ErrNotFound
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	return models.ErrVersionMismatch
+}
+
+func (pr *pgWalletRepo) Restore(id int) error {
+	err := dbtx.Run(pr.DB, "wallet.restore", func(tx *gorm.DB) error {
+		var w models.Wallet
+		res := tx.Unscoped().Model(&w).Clauses(clause.Returning{}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
 
-	tx := pr.DB.Find(&wallets)
+		if res.RowsAffected == 0 {
+			return models.ErrNotFound
+		}
 
-	if tx.Error != nil {
-		return nil, errors.Wrap(tx.Error, "pgWalletRepo.GetAll error")
+		if err := AddShards(tx, &w); err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.WalletEvent(models.EventWalletRestored, &w))
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgWalletRepo.Restore error")
+	}
+
+	return nil
+}
+
+// Purge permanently removes wallets soft deleted before the given time together
+// with their ledger, holds and status history.
+func (pr *pgWalletRepo) Purge(before time.Time) (int, error) {
+	var purged int64
+	err := dbtx.Run(pr.DB, "wallet.purge", func(tx *gorm.DB) error {
+		deleted := tx.Unscoped().Model(&models.Wallet{}).Select("uid").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+
+		dependent := []interface{}{&models.Hold{}, &models.WalletStatusChange{}, &models.WalletShard{}}
+		for _, model := range dependent {
+			if err := tx.Where("wallet_uid IN (?)", deleted).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// Reversals reference the entries they compensate, so they go first.
+		err := tx.Where("wallet_uid IN (?) AND reversal_of IS NOT NULL", deleted).Delete(&models.Transaction{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("wallet_uid IN (?)", deleted).Delete(&models.Transaction{}).Error
+		if err != nil {
+			return err
+		}
+
+		var wallets []*models.Wallet
+		res := tx.Unscoped().Clauses(clause.Returning{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&wallets)
+		if res.Error != nil {
+			return res.Error
+		}
+		purged = res.RowsAffected
+
+		events := make([]*models.OutboxEvent, 0, len(wallets))
+		for _, w := range wallets {
+			events = append(events, outbox.WalletEvent(models.EventWalletPurged, w))
+		}
+
+		return outbox.Record(tx, events...)
+	})
+
+	if err != nil {
+		return 0, errors.Wrap(err, "pgWalletRepo.Purge error")
+	}
+
+	return int(purged), nil
+}
+
+func (pr *pgWalletRepo) GetAll(includeDeleted bool) ([]*models.Wallet, error) {
+	db := pr.DB
+	if includeDeleted {
+		db = db.Unscoped()
+	}
+
+	wallets, err := findWithShards(db.Order("id"))
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.GetAll error")
 	}
 
 	return wallets, nil
 }
 
 func (pr *pgWalletRepo) GetByUID(uid string) (*models.Wallet, error) {
+	w, err := takeWithShards(pr.DB.Where("uid = ?", uid))
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgWalletRepo.GetByUID error")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.GetByUID error")
+	}
+
+	return w, nil
+}
+
+// ChangeAmount applies t.Amount to the wallet balance and stores t as a ledger
+// entry in one transaction.
+func (pr *pgWalletRepo) ChangeAmount(t *models.Transaction) (*models.Wallet, error) {
+	var w *models.Wallet
+	err := dbtx.Run(pr.DB, "wallet.change_amount", func(tx *gorm.DB) error {
+		// A failed attempt leaves the id of its insert behind.
+		t.ID = 0
+
+		var err error
+		w, err = ApplyDelta(tx, t.WalletUID, t.Amount)
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Create(t).Error; err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.BalanceChanged(t, w))
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.ChangeAmount error")
+	}
+
+	return w, nil
+}
+
+// ApplyDelta changes the wallet balance by delta in a single statement. Only
+// active wallets are changed, and withdrawals are only applied while they fit
+// into the available (not held) balance. Sharded wallets are changed in one of
+// their shards instead.
+func ApplyDelta(tx *gorm.DB, uid string, delta int) (*models.Wallet, error) {
+	return applyRowDelta(tx, uid, delta, true)
+}
+
+// applyRowDelta changes the balance in the wallet row. With routeSharded the
+// update skips sharded wallets and hands them over to applyShardDelta.
+func applyRowDelta(tx *gorm.DB, uid string, delta int, routeSharded bool) (*models.Wallet, error) {
 	var w models.Wallet
-	tx := pr.DB.Where("uid = ?", uid).Take(&w)
+	query := tx.Model(&w).Clauses(clause.Returning{}).
+		Where("uid = ? AND status = ? AND (? >= 0 OR amount - held + ? >= 0)", uid, models.WalletStatusActive, delta, delta)
+	if routeSharded {
+		query = query.Where("shards = 0")
+	}
 
-	if tx.Error != nil {
-		return nil, errors.Wrap(tx.Error, "pgWalletRepo.Get error")
+	res := query.Updates(map[string]interface{}{"amount": gorm.Expr("amount + ?", delta), "version": gorm.Expr("version + 1")})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	if res.RowsAffected == 0 {
+		if !routeSharded {
+			return nil, Rejection(tx, uid)
+		}
+
+		var current models.Wallet
+		res = tx.Where("uid = ?", uid).Take(&current)
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return nil, models.ErrNotFound
+		}
+		if res.Error != nil {
+			return nil, res.Error
+		}
+
+		if current.Shards > 0 {
+			return applyShardDelta(tx, &current, delta)
+		}
+
+		return nil, rejection(&current)
+	}
+
+	return &w, nil
+}
+
+// Rejection tells why a conditional balance update matched no wallet row.
+func Rejection(tx *gorm.DB, uid string) error {
+	var w models.Wallet
+	res := tx.Where("uid = ?", uid).Take(&w)
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		return models.ErrNotFound
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	return rejection(&w)
+}
+
+// rejection tells why a balance change of the wallet w can't be applied.
+func rejection(w *models.Wallet) error {
+	switch w.Status {
+	case models.WalletStatusFrozen:
+		return models.ErrWalletFrozen
+	case models.WalletStatusClosed:
+		return models.ErrWalletClosed
+	default:
+		return models.ErrInsufficientFunds
+	}
+}
+
+// ChangeStatus moves the wallet from c.FromStatus to c.ToStatus and records c.
+// A wallet is closed only while nothing is left on it, its shards included.
+func (pr *pgWalletRepo) ChangeStatus(c *models.WalletStatusChange) (*models.Wallet, error) {
+	var w models.Wallet
+	err := dbtx.Run(pr.DB, "wallet.change_status", func(tx *gorm.DB) error {
+		w = models.Wallet{}
+		c.ID = 0
+		query := tx.Model(&w).Clauses(clause.Returning{}).
+			Where("uid = ? AND status = ?", c.WalletUID, c.FromStatus)
+		if c.ToStatus == models.WalletStatusClosed {
+			if _, err := SweepIfSharded(tx, c.WalletUID); err != nil {
+				return err
+			}
+			query = query.Where("amount = 0 AND held = 0")
+		}
+
+		res := query.Updates(map[string]interface{}{"status": c.ToStatus, "version": gorm.Expr("version + 1")})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			var current models.Wallet
+			res = tx.Where("uid = ?", c.WalletUID).Take(&current)
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return models.ErrNotFound
+			}
+			if res.Error != nil {
+				return res.Error
+			}
+			if current.Status != c.FromStatus {
+				return models.ErrStatusTransition
+			}
+			return models.ErrNonZeroBalance
+		}
+
+		if err := AddShards(tx, &w); err != nil {
+			return err
+		}
+
+		if err := tx.Create(c).Error; err != nil {
+			return err
+		}
+
+		return outbox.Record(tx, outbox.StatusChanged(c, &w))
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWalletRepo.ChangeStatus error")
 	}
 
 	return &w, nil
 }
+
+func (pr *pgWalletRepo) GetStatusChanges(uid string) ([]*models.WalletStatusChange, error) {
+	var changes []*models.WalletStatusChange
+
+	tx := pr.DB.Where("wallet_uid = ?", uid).Order("id").Find(&changes)
+
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgWalletRepo.GetStatusChanges error")
+	}
+
+	return changes, nil
+}
@@ -3,17 +3,20 @@ package postgres
 import (
 	"database/sql"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Davmie/javaCode/internal/dbtx"
 	"github.com/Davmie/javaCode/internal/testBuilders"
 	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
 	"github.com/Davmie/javaCode/models"
 	"github.com/Davmie/javaCode/pkg/logger"
 	"github.com/bxcodec/faker"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/suite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"regexp"
 	"testing"
+	"time"
 )
 
 type WalletRepoTestSuite struct {
@@ -62,20 +65,30 @@ func (s *WalletRepoTestSuite) AfterEach(t provider.T) {
 	s.db.Close()
 }
 
+func (s *WalletRepoTestSuite) expectOutboxEvent(eventType string, walletUID string) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "outbox_event" ("uid","type","wallet_uid","owner_id","payload","created_at","published_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+		WithArgs(sqlmock.AnyArg(), eventType, walletUID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
 func (s *WalletRepoTestSuite) TestCreateWallet(t provider.T) {
 	wallet := s.walletBuilder.
 		WithID(1).
 		WithUID("uid").
 		WithAmount(20).
+		WithStatus(models.WalletStatusActive).
 		Build()
 
 	s.mock.ExpectBegin()
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`INSERT INTO "wallet" ("uid","amount","id") VALUES ($1,$2,$3) RETURNING "id"`)).
-		WithArgs(wallet.UID, wallet.Amount, wallet.ID).
+		`INSERT INTO "wallet" ("uid","name","amount","held","status","version","shards","owner_id","deleted_at","id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+		WithArgs(wallet.UID, wallet.Name, wallet.Amount, wallet.Held, wallet.Status, wallet.Version, wallet.Shards, wallet.OwnerID, nil, wallet.ID).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
+	s.expectOutboxEvent(models.EventWalletCreated, wallet.UID)
+
 	s.mock.ExpectCommit()
 
 	err := s.repo.Create(&wallet)
@@ -98,7 +111,7 @@ func (s *WalletRepoTestSuite) TestGetWallet(t provider.T) {
 		)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "wallet" WHERE id = $1 LIMIT $2`)).
+		`SELECT *, `+shardAmount+` FROM "wallet" WHERE id = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
 		WithArgs(wallet.ID, 1).
 		WillReturnRows(rows)
 
@@ -111,6 +124,7 @@ func (s *WalletRepoTestSuite) TestUpdateWallet(t provider.T) {
 	wallet := s.walletBuilder.
 		WithID(1).
 		WithUID("uid").
+		WithName("savings").
 		WithAmount(20).
 		Build()
 
@@ -124,8 +138,10 @@ func (s *WalletRepoTestSuite) TestUpdateWallet(t provider.T) {
 	s.mock.ExpectBegin()
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`UPDATE "wallet" SET "uid"=$1,"amount"=$2 WHERE "id" = $3 RETURNING *`)).
-		WithArgs(wallet.UID, wallet.Amount, wallet.ID).WillReturnRows(rows)
+		`UPDATE "wallet" SET "name"=$1,"version"=version + 1 WHERE version = $2 AND "wallet"."deleted_at" IS NULL AND "id" = $3 RETURNING *`)).
+		WithArgs(wallet.Name, wallet.Version, wallet.ID).WillReturnRows(rows)
+
+	s.expectOutboxEvent(models.EventWalletUpdated, wallet.UID)
 
 	s.mock.ExpectCommit()
 
@@ -142,13 +158,16 @@ func (s *WalletRepoTestSuite) TestDeleteWallet(t provider.T) {
 
 	s.mock.ExpectBegin()
 
-	s.mock.ExpectExec(regexp.QuoteMeta(
-		`DELETE FROM "wallet" WHERE "wallet"."id" = $1`)).
-		WithArgs(wallet.ID).WillReturnResult(sqlmock.NewResult(int64(wallet.ID), 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "deleted_at"=$1,"version"=version + 1 WHERE (id = $2 AND version = $3) AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(sqlmock.AnyArg(), wallet.ID, wallet.Version).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount"}).AddRow(wallet.ID, wallet.UID, wallet.Amount))
+
+	s.expectOutboxEvent(models.EventWalletDeleted, wallet.UID)
 
 	s.mock.ExpectCommit()
 
-	err := s.repo.Delete(wallet.ID)
+	err := s.repo.Delete(wallet.ID, wallet.Version)
 	t.Assert().NoError(err)
 }
 
@@ -171,10 +190,10 @@ func (s *WalletRepoTestSuite) TestGetAll(t provider.T) {
 	}
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "wallet"`)).
+		`SELECT *, ` + shardAmount + ` FROM "wallet" WHERE "wallet"."deleted_at" IS NULL ORDER BY id`)).
 		WillReturnRows(rowsWallets)
 
-	resWallets, err := s.repo.GetAll()
+	resWallets, err := s.repo.GetAll(false)
 	t.Assert().NoError(err)
 	t.Assert().Equal(walletsPtr, resWallets)
 }
@@ -194,7 +213,7 @@ func (s *WalletRepoTestSuite) TestGetByUID(t provider.T) {
 		)
 
 	s.mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT * FROM "wallet" WHERE uid = $1 LIMIT $2`)).
+		`SELECT *, `+shardAmount+` FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
 		WithArgs(wallet.UID, 1).
 		WillReturnRows(rows)
 
@@ -202,3 +221,245 @@ func (s *WalletRepoTestSuite) TestGetByUID(t provider.T) {
 	t.Assert().NoError(err)
 	t.Assert().Equal(wallet, *resWallet)
 }
+
+func (s *WalletRepoTestSuite) TestChangeAmount(t provider.T) {
+	wallet := s.walletBuilder.
+		WithID(1).
+		WithUID("").
+		WithAmount(20).
+		Build()
+
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID(wallet.UID).
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(5).
+		Build()
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "amount", "held"}).
+		AddRow(wallet.ID, wallet.UID, wallet.Amount+5, wallet.Held)
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(5, wallet.UID, models.WalletStatusActive, 5, 5).
+		WillReturnRows(rows)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "transaction" ("uid","wallet_uid","type","amount","reversed","reversal_of","reason_code","comment","actor_id","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+		WithArgs(transaction.UID, wallet.UID, transaction.Type, 5, 0, nil, "", "", nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	s.expectOutboxEvent(models.EventWalletBalanceChanged, wallet.UID)
+
+	s.mock.ExpectCommit()
+
+	resWallet, err := s.repo.ChangeAmount(&transaction)
+	t.Assert().NoError(err)
+	t.Assert().Equal(wallet.Amount+5, resWallet.Amount)
+	t.Assert().Equal(1, transaction.ID)
+}
+
+func (s *WalletRepoTestSuite) TestChangeAmountRetry(t provider.T) {
+	dbtx.BaseBackoff = time.Microsecond
+
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID("uid").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(5).
+		Build()
+
+	// The second attempt inserts the entry again, without the id of the first.
+	for attempt := 1; attempt <= 2; attempt++ {
+		s.mock.ExpectBegin()
+
+		s.mock.ExpectQuery(regexp.QuoteMeta(
+			`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(5, transaction.WalletUID, models.WalletStatusActive, 5, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held"}).AddRow(1, "uid", 25, 0))
+
+		s.mock.ExpectQuery(regexp.QuoteMeta(
+			`INSERT INTO "transaction" ("uid","wallet_uid","type","amount","reversed","reversal_of","reason_code","comment","actor_id","created_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+			WithArgs(transaction.UID, transaction.WalletUID, transaction.Type, 5, 0, nil, "", "", nil, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(attempt))
+
+		if attempt == 1 {
+			s.mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox_event"`)).
+				WillReturnError(&pgconn.PgError{Code: "40001"})
+			s.mock.ExpectRollback()
+			continue
+		}
+
+		s.expectOutboxEvent(models.EventWalletBalanceChanged, transaction.WalletUID)
+		s.mock.ExpectCommit()
+	}
+
+	_, err := s.repo.ChangeAmount(&transaction)
+	t.Require().NoError(err)
+	t.Assert().Equal(2, transaction.ID)
+}
+
+func (s *WalletRepoTestSuite) TestChangeAmountInsufficientFunds(t provider.T) {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID("uid").
+		WithType(models.TransactionTypeWithdraw).
+		WithAmount(-30).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(-30, transaction.WalletUID, models.WalletStatusActive, -30, -30).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(transaction.WalletUID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held", "status"}).AddRow(1, transaction.WalletUID, 20, 0, models.WalletStatusActive))
+
+	s.mock.ExpectRollback()
+
+	_, err := s.repo.ChangeAmount(&transaction)
+	t.Assert().ErrorIs(err, models.ErrInsufficientFunds)
+}
+
+func (s *WalletRepoTestSuite) TestChangeAmountNotFound(t provider.T) {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID("uid").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(5).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(5, transaction.WalletUID, models.WalletStatusActive, 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(transaction.WalletUID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held", "status"}))
+
+	s.mock.ExpectRollback()
+
+	_, err := s.repo.ChangeAmount(&transaction)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
+
+func (s *WalletRepoTestSuite) TestChangeAmountFrozenWallet(t provider.T) {
+	transaction := testBuilders.NewTransactionBuilder().
+		WithUID("transaction").
+		WithWalletUID("uid").
+		WithType(models.TransactionTypeDeposit).
+		WithAmount(5).
+		Build()
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "amount"=amount + $1,"version"=version + 1 WHERE (uid = $2 AND status = $3 AND ($4 >= 0 OR amount - held + $5 >= 0)) AND shards = 0 AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(5, transaction.WalletUID, models.WalletStatusActive, 5, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held"}))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(transaction.WalletUID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held", "status"}).
+			AddRow(1, transaction.WalletUID, 20, 0, models.WalletStatusFrozen))
+
+	s.mock.ExpectRollback()
+
+	_, err := s.repo.ChangeAmount(&transaction)
+	t.Assert().ErrorIs(err, models.ErrWalletFrozen)
+}
+
+func (s *WalletRepoTestSuite) TestChangeStatus(t provider.T) {
+	wallet := s.walletBuilder.
+		WithID(1).
+		WithUID("").
+		WithStatus(models.WalletStatusClosed).
+		Build()
+
+	change := models.WalletStatusChange{
+		WalletUID:  wallet.UID,
+		FromStatus: models.WalletStatusActive,
+		ToStatus:   models.WalletStatusClosed,
+		ActorID:    7,
+		Reason:     "customer request",
+	}
+
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT "shards" FROM "wallet" WHERE uid = $1 AND "wallet"."deleted_at" IS NULL LIMIT $2`)).
+		WithArgs(wallet.UID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"shards"}).AddRow(0))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "status"=$1,"version"=version + 1 WHERE (uid = $2 AND status = $3) AND (amount = 0 AND held = 0) AND "wallet"."deleted_at" IS NULL RETURNING *`)).
+		WithArgs(change.ToStatus, wallet.UID, change.FromStatus).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "held", "status"}).
+			AddRow(wallet.ID, wallet.UID, 0, 0, wallet.Status))
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "wallet_status_change" ("wallet_uid","from_status","to_status","actor_id","reason","created_at") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+		WithArgs(wallet.UID, change.FromStatus, change.ToStatus, change.ActorID, change.Reason, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	s.expectOutboxEvent(models.EventWalletStatusChanged, wallet.UID)
+
+	s.mock.ExpectCommit()
+
+	resWallet, err := s.repo.ChangeStatus(&change)
+	t.Assert().NoError(err)
+	t.Assert().Equal(wallet, *resWallet)
+}
+
+func (s *WalletRepoTestSuite) TestGetAllIncludeDeleted(t provider.T) {
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT *, ` + shardAmount + ` FROM "wallet" ORDER BY id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "amount", "deleted_at"}).
+			AddRow(1, "uid", 20, time.Now()))
+
+	resWallets, err := s.repo.GetAll(true)
+	t.Assert().NoError(err)
+	t.Assert().Len(resWallets, 1)
+	t.Assert().True(resWallets[0].DeletedAt.Valid)
+}
+
+func (s *WalletRepoTestSuite) TestRestoreWallet(t provider.T) {
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "deleted_at"=$1,"version"=version + 1 WHERE id = $2 AND deleted_at IS NOT NULL RETURNING *`)).
+		WithArgs(nil, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid"}).AddRow(1, "uid"))
+
+	s.expectOutboxEvent(models.EventWalletRestored, "uid")
+
+	s.mock.ExpectCommit()
+
+	err := s.repo.Restore(1)
+	t.Assert().NoError(err)
+}
+
+func (s *WalletRepoTestSuite) TestRestoreNotDeletedWallet(t provider.T) {
+	s.mock.ExpectBegin()
+
+	s.mock.ExpectQuery(regexp.QuoteMeta(
+		`UPDATE "wallet" SET "deleted_at"=$1,"version"=version + 1 WHERE id = $2 AND deleted_at IS NOT NULL RETURNING *`)).
+		WithArgs(nil, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid"}))
+
+	s.mock.ExpectRollback()
+
+	err := s.repo.Restore(1)
+	t.Assert().ErrorIs(err, models.ErrNotFound)
+}
@@ -1,12 +1,22 @@
 package repository
 
-import "github.com/Davmie/javaCode/models"
+import (
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+)
 
 type WalletRepositoryI interface {
 	Create(w *models.Wallet) error
 	Get(id int) (*models.Wallet, error)
 	Update(w *models.Wallet) error
-	Delete(id int) error
-	GetAll() ([]*models.Wallet, error)
+	Delete(id int, version int) error
+	GetAll(includeDeleted bool) ([]*models.Wallet, error)
 	GetByUID(uid string) (*models.Wallet, error)
+	ChangeAmount(t *models.Transaction) (*models.Wallet, error)
+	ChangeStatus(c *models.WalletStatusChange) (*models.Wallet, error)
+	GetStatusChanges(uid string) ([]*models.WalletStatusChange, error)
+	Restore(id int) error
+	Purge(before time.Time) (int, error)
+	SetShards(uid string, shards int) (*models.Wallet, error)
 }
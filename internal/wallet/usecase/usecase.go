@@ -1,32 +1,64 @@
 package usecase
 
 import (
+	"time"
+
 	walletRep "github.com/Davmie/javaCode/internal/wallet/repository"
 	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 type WalletUseCaseI interface {
 	Create(w *models.Wallet) error
 	Get(id int) (*models.Wallet, error)
 	Update(w *models.Wallet) error
-	Delete(id int) error
-	GetAll() ([]*models.Wallet, error)
+	Delete(id int, version int) error
+	GetAll(includeDeleted bool) ([]*models.Wallet, error)
 	GetByUID(uid string) (*models.Wallet, error)
-	ChangeAmount(uid string, amount int) error
+	ChangeAmount(uid string, amount int) (*models.Transaction, error)
+	Adjust(uid string, amount int, reasonCode string, comment string, actorID int) (*models.Transaction, error)
+	ChangeStatus(uid string, status string, reason string, actorID int) (*models.Wallet, error)
+	GetStatusChanges(uid string) ([]*models.WalletStatusChange, error)
+	Restore(id int) error
+	Purge(retention time.Duration) (int, error)
+	SetShards(uid string, shards int) (*models.Wallet, error)
+}
+
+// MaxWalletShards limits the number of sub-balances of a sharded wallet.
+const MaxWalletShards = 64
+
+// statusTransitions lists the statuses a wallet may move to from each status.
+var statusTransitions = map[string][]string{
+	models.WalletStatusActive: {models.WalletStatusFrozen, models.WalletStatusClosed},
+	models.WalletStatusFrozen: {models.WalletStatusActive},
 }
 
 type walletUseCase struct {
 	walletRepository walletRep.WalletRepositoryI
+	now              func() time.Time
 }
 
 func New(wRep walletRep.WalletRepositoryI) WalletUseCaseI {
 	return &walletUseCase{
 		walletRepository: wRep,
+		now:              time.Now,
 	}
 }
 
 func (wUC *walletUseCase) Create(w *models.Wallet) error {
+	w.Status = models.WalletStatusActive
+	// A wallet is sharded by an admin with SetShards only.
+	w.Shards = 0
+	// A new wallet is empty, money comes in through ledger entries only, so
+	// the balance always equals the sum of the ledger. Nothing is reserved on
+	// it and it starts at the first version.
+	w.Amount = 0
+	w.Held = 0
+	w.Version = 0
+	w.DeletedAt = gorm.DeletedAt{}
+
 	err := wUC.walletRepository.Create(w)
 
 	if err != nil {
@@ -46,13 +78,18 @@ func (wUC *walletUseCase) Get(id int) (*models.Wallet, error) {
 	return resWallet, nil
 }
 
+// Update changes the wallet metadata, w.Version must be the current version.
 func (wUC *walletUseCase) Update(w *models.Wallet) error {
-	_, err := wUC.walletRepository.Get(w.ID)
+	current, err := wUC.walletRepository.Get(w.ID)
 
 	if err != nil {
 		return errors.Wrap(err, "walletUseCase.Update error: Wallet not found")
 	}
 
+	if current.Version != w.Version {
+		return errors.Wrap(models.ErrVersionMismatch, "walletUseCase.Update error")
+	}
+
 	err = wUC.walletRepository.Update(w)
 
 	if err != nil {
@@ -62,14 +99,18 @@ func (wUC *walletUseCase) Update(w *models.Wallet) error {
 	return nil
 }
 
-func (wUC *walletUseCase) Delete(id int) error {
-	_, err := wUC.walletRepository.Get(id)
+func (wUC *walletUseCase) Delete(id int, version int) error {
+	current, err := wUC.walletRepository.Get(id)
 
 	if err != nil {
 		return errors.Wrap(err, "walletUseCase.Delete error: Wallet not found")
 	}
 
-	err = wUC.walletRepository.Delete(id)
+	if current.Version != version {
+		return errors.Wrap(models.ErrVersionMismatch, "walletUseCase.Delete error")
+	}
+
+	err = wUC.walletRepository.Delete(id, version)
 
 	if err != nil {
 		return errors.Wrap(err, "walletUseCase.Delete error: Can't delete in repo")
@@ -78,8 +119,8 @@ func (wUC *walletUseCase) Delete(id int) error {
 	return nil
 }
 
-func (wUC *walletUseCase) GetAll() ([]*models.Wallet, error) {
-	wallets, err := wUC.walletRepository.GetAll()
+func (wUC *walletUseCase) GetAll(includeDeleted bool) ([]*models.Wallet, error) {
+	wallets, err := wUC.walletRepository.GetAll(includeDeleted)
 	if err != nil {
 		return nil, errors.Wrap(err, "walletUseCase.GetAll error")
 	}
@@ -96,18 +137,123 @@ func (wUC *walletUseCase) GetByUID(uid string) (*models.Wallet, error) {
 	return wallet, nil
 }
 
-func (wUC *walletUseCase) ChangeAmount(uid string, amount int) error {
+func (wUC *walletUseCase) ChangeAmount(uid string, amount int) (*models.Transaction, error) {
+	t := &models.Transaction{
+		UID:       uuid.NewString(),
+		WalletUID: uid,
+		Type:      models.TransactionTypeDeposit,
+		Amount:    amount,
+		CreatedAt: wUC.now(),
+	}
+	if amount < 0 {
+		t.Type = models.TransactionTypeWithdraw
+	}
+
+	_, err := wUC.walletRepository.ChangeAmount(t)
+	if err != nil {
+		return nil, errors.Wrap(err, "walletUseCase.ChangeAmount error")
+	}
+
+	return t, nil
+}
+
+// Adjust is a manual balance correction made by an admin. It is stored as an
+// ADJUSTMENT ledger entry together with the reason and the acting user.
+func (wUC *walletUseCase) Adjust(uid string, amount int, reasonCode string, comment string, actorID int) (*models.Transaction, error) {
+	t := &models.Transaction{
+		UID:        uuid.NewString(),
+		WalletUID:  uid,
+		Type:       models.TransactionTypeAdjustment,
+		Amount:     amount,
+		ReasonCode: reasonCode,
+		Comment:    comment,
+		ActorID:    &actorID,
+		CreatedAt:  wUC.now(),
+	}
+
+	_, err := wUC.walletRepository.ChangeAmount(t)
+	if err != nil {
+		return nil, errors.Wrap(err, "walletUseCase.Adjust error")
+	}
+
+	return t, nil
+}
+
+func (wUC *walletUseCase) ChangeStatus(uid string, status string, reason string, actorID int) (*models.Wallet, error) {
 	wallet, err := wUC.walletRepository.GetByUID(uid)
 	if err != nil {
-		return errors.Wrap(err, "walletUseCase.ChangeAmount error: Wallet not found")
+		return nil, errors.Wrap(err, "walletUseCase.ChangeStatus error: Wallet not found")
+	}
+
+	allowed := false
+	for _, to := range statusTransitions[wallet.Status] {
+		if to == status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, errors.Wrapf(models.ErrStatusTransition, "walletUseCase.ChangeStatus error: %s -> %s", wallet.Status, status)
+	}
+
+	if status == models.WalletStatusClosed && (wallet.Amount != 0 || wallet.Held != 0) {
+		return nil, errors.Wrap(models.ErrNonZeroBalance, "walletUseCase.ChangeStatus error")
+	}
+
+	wallet, err = wUC.walletRepository.ChangeStatus(&models.WalletStatusChange{
+		WalletUID:  uid,
+		FromStatus: wallet.Status,
+		ToStatus:   status,
+		ActorID:    actorID,
+		Reason:     reason,
+		CreatedAt:  wUC.now(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "walletUseCase.ChangeStatus error")
 	}
 
-	wallet.Amount += amount
+	return wallet, nil
+}
+
+func (wUC *walletUseCase) GetStatusChanges(uid string) ([]*models.WalletStatusChange, error) {
+	changes, err := wUC.walletRepository.GetStatusChanges(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "walletUseCase.GetStatusChanges error")
+	}
+
+	return changes, nil
+}
 
-	err = wUC.walletRepository.Update(wallet)
+func (wUC *walletUseCase) Restore(id int) error {
+	err := wUC.walletRepository.Restore(id)
 	if err != nil {
-		return errors.Wrap(err, "walletUseCase.ChangeAmount error: Can't update in repo")
+		return errors.Wrap(err, "walletUseCase.Restore error")
 	}
 
 	return nil
 }
+
+// Purge permanently removes wallets that were deleted more than retention ago.
+func (wUC *walletUseCase) Purge(retention time.Duration) (int, error) {
+	purged, err := wUC.walletRepository.Purge(wUC.now().Add(-retention))
+	if err != nil {
+		return 0, errors.Wrap(err, "walletUseCase.Purge error")
+	}
+
+	return purged, nil
+}
+
+// SetShards splits the balance of the wallet into the given number of shards,
+// 0 keeps it in the wallet row only.
+func (wUC *walletUseCase) SetShards(uid string, shards int) (*models.Wallet, error) {
+	if shards < 0 || shards > MaxWalletShards {
+		return nil, errors.Wrapf(models.ErrBadShards, "walletUseCase.SetShards error: %d", shards)
+	}
+
+	wallet, err := wUC.walletRepository.SetShards(uid, shards)
+	if err != nil {
+		return nil, errors.Wrap(err, "walletUseCase.SetShards error")
+	}
+
+	return wallet, nil
+}
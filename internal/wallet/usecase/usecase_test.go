@@ -8,7 +8,9 @@ import (
 	"github.com/ozontech/allure-go/pkg/framework/provider"
 	"github.com/ozontech/allure-go/pkg/framework/suite"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 type WalletTestSuite struct {
@@ -32,14 +34,19 @@ func (s *WalletTestSuite) TestCreateWallet(t provider.T) {
 	wallet := s.walletBuilder.
 		WithID(1).
 		WithUID("uid").
-		WithAmount(20).
+		WithHeld(-100).
 		Build()
+	wallet.Version = 7
 
 	s.walletRepoMock.On("Create", &wallet).Return(nil)
 	err := s.uc.Create(&wallet)
 
 	t.Assert().NoError(err)
 	t.Assert().Equal(wallet.ID, 1)
+	t.Assert().Equal(0, wallet.Amount)
+	t.Assert().Equal(0, wallet.Held)
+	t.Assert().Equal(0, wallet.Version)
+	t.Assert().Equal(models.WalletStatusActive, wallet.Status)
 }
 
 func (s *WalletTestSuite) TestUpdateWallet(t provider.T) {
@@ -69,6 +76,10 @@ func (s *WalletTestSuite) TestUpdateWallet(t provider.T) {
 			ArgData: &notFoundWallet,
 			Error:   errors.Wrap(err, "Wallet not found"),
 		},
+		"stale version": {
+			ArgData: &models.Wallet{ID: wallet.ID, Version: wallet.Version + 1},
+			Error:   models.ErrVersionMismatch,
+		},
 	}
 
 	for name, test := range cases {
@@ -104,27 +115,34 @@ func (s *WalletTestSuite) TestDeleteWallet(t provider.T) {
 	notFoundWallet := s.walletBuilder.WithID(0).Build()
 
 	s.walletRepoMock.On("Get", wallet.ID).Return(&wallet, nil)
-	s.walletRepoMock.On("Delete", wallet.ID).Return(nil)
+	s.walletRepoMock.On("Delete", wallet.ID, wallet.Version).Return(nil)
 	s.walletRepoMock.On("Get", notFoundWallet.ID).Return(&notFoundWallet, errors.Wrap(err, "Wallet not found"))
-	s.walletRepoMock.On("Delete", notFoundWallet.ID).Return(errors.Wrap(err, "Wallet not found"))
+	s.walletRepoMock.On("Delete", notFoundWallet.ID, notFoundWallet.Version).Return(errors.Wrap(err, "Wallet not found"))
 
 	cases := map[string]struct {
 		WalletID int
+		Version  int
 		Error    error
 	}{
 		"success": {
 			WalletID: wallet.ID,
+			Version:  wallet.Version,
 			Error:    nil,
 		},
 		"Wallet not found": {
 			WalletID: notFoundWallet.ID,
 			Error:    errors.Wrap(err, "Wallet not found"),
 		},
+		"stale version": {
+			WalletID: wallet.ID,
+			Version:  wallet.Version + 1,
+			Error:    models.ErrVersionMismatch,
+		},
 	}
 
 	for name, test := range cases {
 		t.Run(name, func(t provider.T) {
-			err := s.uc.Delete(test.WalletID)
+			err := s.uc.Delete(test.WalletID, test.Version)
 			t.Assert().ErrorIs(err, test.Error)
 		})
 	}
@@ -140,7 +158,7 @@ func (s *WalletTestSuite) TestGetAll(t provider.T) {
 		walletsPtr[i] = &wallet
 	}
 
-	s.walletRepoMock.On("GetAll").Return(walletsPtr, nil)
+	s.walletRepoMock.On("GetAll", false).Return(walletsPtr, nil)
 
 	cases := map[string]struct {
 		Wallets []models.Wallet
@@ -154,7 +172,7 @@ func (s *WalletTestSuite) TestGetAll(t provider.T) {
 
 	for name, test := range cases {
 		t.Run(name, func(t provider.T) {
-			resWallets, err := s.uc.GetAll()
+			resWallets, err := s.uc.GetAll(false)
 			t.Assert().ErrorIs(err, test.Error)
 			t.Assert().Equal(walletsPtr, resWallets)
 		})
@@ -182,11 +200,157 @@ func (s *WalletTestSuite) TestChangeAmount(t provider.T) {
 		WithAmount(20).
 		Build()
 
-	s.walletRepoMock.On("GetByUID", wallet.UID).Return(&wallet, nil)
-	wallet.Amount += 1000
-	s.walletRepoMock.On("Update", &wallet).Return(nil)
+	changedWallet := wallet
+	changedWallet.Amount += 1000
+
+	s.walletRepoMock.On("ChangeAmount", mock.MatchedBy(func(tr *models.Transaction) bool {
+		return tr.WalletUID == wallet.UID && tr.Amount == 1000 && tr.Type == models.TransactionTypeDeposit
+	})).Return(&changedWallet, nil)
+	s.walletRepoMock.On("ChangeAmount", mock.MatchedBy(func(tr *models.Transaction) bool {
+		return tr.WalletUID == wallet.UID && tr.Amount == -2000 && tr.Type == models.TransactionTypeWithdraw
+	})).Return(nil, errors.Wrap(models.ErrInsufficientFunds, "pgWalletRepo.ChangeAmount error"))
+
+	cases := map[string]struct {
+		Amount int
+		Error  error
+	}{
+		"success": {
+			Amount: 1000,
+			Error:  nil,
+		},
+		"insufficient funds": {
+			Amount: -2000,
+			Error:  models.ErrInsufficientFunds,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := s.uc.ChangeAmount(wallet.UID, test.Amount)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *WalletTestSuite) TestAdjust(t provider.T) {
+	wallet := s.walletBuilder.
+		WithID(1).
+		WithUID("uid").
+		WithAmount(20).
+		Build()
+
+	s.walletRepoMock.On("ChangeAmount", mock.MatchedBy(func(tr *models.Transaction) bool {
+		return tr.WalletUID == wallet.UID && tr.Amount == -15 && tr.Type == models.TransactionTypeAdjustment &&
+			tr.ReasonCode == models.AdjustmentReasonFee && tr.Comment == "monthly fee" &&
+			tr.ActorID != nil && *tr.ActorID == 7
+	})).Return(&wallet, nil)
+
+	transaction, err := s.uc.Adjust(wallet.UID, -15, models.AdjustmentReasonFee, "monthly fee", 7)
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(models.TransactionTypeAdjustment, transaction.Type)
+	t.Assert().Equal(7, *transaction.ActorID)
+}
+
+func (s *WalletTestSuite) TestChangeStatus(t provider.T) {
+	active := s.walletBuilder.
+		WithID(1).
+		WithUID("active").
+		WithAmount(20).
+		WithStatus(models.WalletStatusActive).
+		Build()
 
-	err := s.uc.ChangeAmount("uid", 1000)
+	empty := testBuilders.NewWalletBuilder().
+		WithID(2).
+		WithUID("empty").
+		WithStatus(models.WalletStatusActive).
+		Build()
+
+	closed := testBuilders.NewWalletBuilder().
+		WithID(3).
+		WithUID("closed").
+		WithStatus(models.WalletStatusClosed).
+		Build()
+
+	s.walletRepoMock.On("GetByUID", active.UID).Return(&active, nil)
+	s.walletRepoMock.On("GetByUID", empty.UID).Return(&empty, nil)
+	s.walletRepoMock.On("GetByUID", closed.UID).Return(&closed, nil)
+	s.walletRepoMock.On("ChangeStatus", mock.MatchedBy(func(c *models.WalletStatusChange) bool {
+		return c.ActorID == 7 && c.Reason == "reason" && c.FromStatus == models.WalletStatusActive
+	})).Return(&active, nil)
+
+	cases := map[string]struct {
+		UID    string
+		Status string
+		Error  error
+	}{
+		"freeze": {
+			UID:    active.UID,
+			Status: models.WalletStatusFrozen,
+			Error:  nil,
+		},
+		"close empty": {
+			UID:    empty.UID,
+			Status: models.WalletStatusClosed,
+			Error:  nil,
+		},
+		"close with balance": {
+			UID:    active.UID,
+			Status: models.WalletStatusClosed,
+			Error:  models.ErrNonZeroBalance,
+		},
+		"reopen closed": {
+			UID:    closed.UID,
+			Status: models.WalletStatusActive,
+			Error:  models.ErrStatusTransition,
+		},
+		"same status": {
+			UID:    active.UID,
+			Status: models.WalletStatusActive,
+			Error:  models.ErrStatusTransition,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t provider.T) {
+			_, err := s.uc.ChangeStatus(test.UID, test.Status, "reason", 7)
+			t.Assert().ErrorIs(err, test.Error)
+		})
+	}
+}
+
+func (s *WalletTestSuite) TestPurge(t provider.T) {
+	now := time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	uc := &walletUseCase{
+		walletRepository: s.walletRepoMock,
+		now:              func() time.Time { return now },
+	}
+
+	s.walletRepoMock.On("Purge", now.Add(-48*time.Hour)).Return(2, nil)
+
+	purged, err := uc.Purge(48 * time.Hour)
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(2, purged)
+}
 
+func (s *WalletTestSuite) TestSetShards(t provider.T) {
+	wallet := s.walletBuilder.
+		WithID(1).
+		WithUID("uid").
+		WithAmount(20).
+		Build()
+	wallet.Shards = 8
+
+	s.walletRepoMock.On("SetShards", wallet.UID, 8).Return(&wallet, nil)
+
+	resWallet, err := s.uc.SetShards(wallet.UID, 8)
 	t.Assert().NoError(err)
+	t.Assert().Equal(8, resWallet.Shards)
+
+	_, err = s.uc.SetShards(wallet.UID, -1)
+	t.Assert().ErrorIs(err, models.ErrBadShards)
+
+	_, err = s.uc.SetShards(wallet.UID, MaxWalletShards+1)
+	t.Assert().ErrorIs(err, models.ErrBadShards)
 }
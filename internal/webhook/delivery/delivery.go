@@ -0,0 +1,336 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	webhookUseCase "github.com/Davmie/javaCode/internal/webhook/usecase"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/asaskevich/govalidator"
+	"github.com/pkg/errors"
+)
+
+type UserContextManager interface {
+	UserIDFromContext(context.Context) (int, error)
+	UserRoleFromContext(context.Context) (string, error)
+}
+
+// WebhookHandler serves the webhooks of the authorized user. Webhooks of other
+// users look missing, except for admins who see all of them.
+type WebhookHandler struct {
+	WebhookUseCase webhookUseCase.WebhookUseCaseI
+	WalletUseCase  walletUseCase.WalletUseCaseI
+	Logger         logger.Logger
+	ContextManager UserContextManager
+}
+
+// CreateWebhookRequest subscribes to the wallet WalletUID, or without it to
+// the wallets of the scope: OWNER for the wallets of the user, ALL (the
+// default) for every wallet.
+type CreateWebhookRequest struct {
+	WalletUID string   `json:"walletId"`
+	Scope     string   `json:"scope"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Secret    string   `json:"secret"`
+}
+
+func (wh *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	createReq := CreateWebhookRequest{}
+	if !wh.readJSON(w, r, &createReq) {
+		return
+	}
+
+	target, err := url.Parse(createReq.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		wh.Logger.Infow("can`t create webhook",
+			"err", "url must be absolute http or https")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	if createReq.WalletUID != "" && !govalidator.IsUUID(createReq.WalletUID) {
+		wh.Logger.Infow("can`t create webhook",
+			"err", "walletId must be uuid")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	scope, ok := webhookScope(createReq)
+	if !ok {
+		wh.Logger.Infow("can`t create webhook",
+			"err", "unknown scope or scope of a single wallet without walletId")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	ownerID, err := wh.ContextManager.UserIDFromContext(r.Context())
+	if err != nil {
+		wh.Logger.Errorw("can`t get user from context",
+			"err:", err.Error())
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	if scope == models.WebhookScopeOwner && ownerID == 0 {
+		wh.Logger.Infow("can`t create webhook",
+			"err", "user without id owns no wallets")
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	if !wh.isAdmin(r) && scope != models.WebhookScopeOwner {
+		if scope == models.WebhookScopeAll {
+			wh.Logger.Infow("can`t create webhook",
+				"err", "admin role required for webhooks of every wallet")
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		wallet, err := wh.WalletUseCase.GetByUID(createReq.WalletUID)
+		if err == nil && !wallet.OwnedBy(ownerID) {
+			err = errors.Wrap(models.ErrNotFound, "wallet of another user")
+		}
+		if err != nil {
+			wh.Logger.Infow("can`t create webhook",
+				"err:", err.Error())
+			http.Error(w, "can`t create webhook", errorStatus(err))
+			return
+		}
+	}
+
+	webhook := &models.Webhook{
+		OwnerID:   ownerID,
+		Scope:     scope,
+		WalletUID: createReq.WalletUID,
+		URL:       createReq.URL,
+		Events:    createReq.Events,
+		Secret:    createReq.Secret,
+	}
+	if err = wh.WebhookUseCase.Create(webhook); err != nil {
+		wh.Logger.Infow("can`t create webhook",
+			"err:", err.Error())
+		http.Error(w, "can`t create webhook", errorStatus(err))
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/webhooks/%s", webhook.UID))
+	wh.writeJSON(w, http.StatusCreated, webhook)
+}
+
+// webhookScope returns the scope of the webhook of the request, ok is false
+// when the scope is unknown or doesn't fit walletId.
+func webhookScope(createReq CreateWebhookRequest) (scope string, ok bool) {
+	switch {
+	case createReq.WalletUID != "":
+		return models.WebhookScopeWallet, createReq.Scope == "" || createReq.Scope == models.WebhookScopeWallet
+	case createReq.Scope == "" || createReq.Scope == models.WebhookScopeAll:
+		return models.WebhookScopeAll, true
+	case createReq.Scope == models.WebhookScopeOwner:
+		return models.WebhookScopeOwner, true
+	default:
+		return "", false
+	}
+}
+
+func (wh *WebhookHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	ownerID, err := wh.ContextManager.UserIDFromContext(r.Context())
+	if err != nil {
+		wh.Logger.Errorw("can`t get user from context",
+			"err:", err.Error())
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	webhooks, err := wh.WebhookUseCase.GetByOwner(ownerID)
+	if err != nil {
+		wh.Logger.Infow("can`t get webhooks",
+			"err:", err.Error())
+		http.Error(w, "can`t get webhooks", errorStatus(err))
+		return
+	}
+
+	wh.writeJSON(w, http.StatusOK, webhooks)
+}
+
+func (wh *WebhookHandler) GetByUID(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.webhook(w, r)
+	if !ok {
+		return
+	}
+
+	wh.writeJSON(w, http.StatusOK, webhook)
+}
+
+func (wh *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.webhook(w, r)
+	if !ok {
+		return
+	}
+
+	if err := wh.WebhookUseCase.Delete(webhook.UID); err != nil {
+		wh.Logger.Infow("can`t delete webhook",
+			"err:", err.Error())
+		http.Error(w, "can`t delete webhook", errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (wh *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.webhook(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead:
+	default:
+		wh.Logger.Infow("can`t get deliveries",
+			"err", "unknown status", "status", status)
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := wh.WebhookUseCase.GetDeliveries(webhook.UID, status)
+	if err != nil {
+		wh.Logger.Infow("can`t get deliveries",
+			"err:", err.Error())
+		http.Error(w, "can`t get deliveries", errorStatus(err))
+		return
+	}
+
+	wh.writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (wh *WebhookHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := wh.webhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryUID := r.PathValue("DELIVERY_UUID")
+	if deliveryUID == "" {
+		wh.Logger.Errorw("no DELIVERY_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return
+	}
+
+	d, err := wh.WebhookUseCase.GetDelivery(deliveryUID)
+	if err == nil && d.WebhookUID != webhook.UID {
+		err = models.ErrNotFound
+	}
+	if err == nil {
+		d, err = wh.WebhookUseCase.Replay(deliveryUID)
+	}
+	if err != nil {
+		wh.Logger.Infow("can`t replay delivery",
+			"err:", err.Error())
+		http.Error(w, "can`t replay delivery", errorStatus(err))
+		return
+	}
+
+	wh.writeJSON(w, http.StatusOK, d)
+}
+
+// webhook returns the webhook of the path if the user may see it.
+func (wh *WebhookHandler) webhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	webhookUID := r.PathValue("WEBHOOK_UUID")
+	if webhookUID == "" {
+		wh.Logger.Errorw("no WEBHOOK_UUID var")
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	ownerID, err := wh.ContextManager.UserIDFromContext(r.Context())
+	if err != nil {
+		wh.Logger.Errorw("can`t get user from context",
+			"err:", err.Error())
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	webhook, err := wh.WebhookUseCase.GetByUID(webhookUID)
+	if err == nil && webhook.OwnerID != ownerID && !wh.isAdmin(r) {
+		err = models.ErrNotFound
+	}
+	if err != nil {
+		wh.Logger.Infow("can`t get webhook",
+			"err:", err.Error())
+		http.Error(w, "can`t get webhook", errorStatus(err))
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+func (wh *WebhookHandler) isAdmin(r *http.Request) bool {
+	role, err := wh.ContextManager.UserRoleFromContext(r.Context())
+	return err == nil && role == session.AdminRole
+}
+
+func (wh *WebhookHandler) readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		wh.Logger.Errorw("can`t read body of request",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return false
+	}
+
+	err = r.Body.Close()
+	if err != nil {
+		wh.Logger.Errorw("can`t close body of request", "err:", err.Error())
+		http.Error(w, "close error", http.StatusInternalServerError)
+		return false
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		wh.Logger.Infow("can`t unmarshal form",
+			"err:", err.Error())
+		http.Error(w, "bad data", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+func (wh *WebhookHandler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		wh.Logger.Errorw("can`t marshal webhook",
+			"err:", err.Error())
+		http.Error(w, "can`t make webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(resp)
+	if err != nil {
+		wh.Logger.Errorw("can`t write response",
+			"err:", err.Error())
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrWebhookTarget):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,261 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/webhook/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type memWebhookRepo struct {
+	Logger logger.Logger
+	Store  *memstore.Store
+}
+
+func New(logger logger.Logger, store *memstore.Store) repository.WebhookRepositoryI {
+	return &memWebhookRepo{
+		Logger: logger,
+		Store:  store,
+	}
+}
+
+func (mr *memWebhookRepo) Create(wh *models.Webhook) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	if mr.byUID(wh.UID) != nil {
+		return errors.Wrap(models.ErrAlreadyExists, "memWebhookRepo.Create error while inserting in repo")
+	}
+
+	wh.ID = mr.Store.NextID(wh.TableName())
+	if wh.CreatedAt.IsZero() {
+		wh.CreatedAt = time.Now()
+	}
+
+	stored := *wh
+	mr.Store.Webhooks = append(mr.Store.Webhooks, &stored)
+
+	return nil
+}
+
+func (mr *memWebhookRepo) GetByUID(uid string) (*models.Webhook, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	wh := mr.byUID(uid)
+	if wh == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWebhookRepo.GetByUID error")
+	}
+
+	res := *wh
+	return &res, nil
+}
+
+func (mr *memWebhookRepo) GetByOwner(ownerID int) ([]*models.Webhook, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var webhooks []*models.Webhook
+	for _, wh := range mr.Store.Webhooks {
+		if wh.OwnerID == ownerID {
+			res := *wh
+			webhooks = append(webhooks, &res)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (mr *memWebhookRepo) GetByWalletUID(_ context.Context, walletUID string, ownerID int) ([]*models.Webhook, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var webhooks []*models.Webhook
+	for _, wh := range mr.Store.Webhooks {
+		if wh.Covers(walletUID, ownerID) {
+			res := *wh
+			webhooks = append(webhooks, &res)
+		}
+	}
+
+	return webhooks, nil
+}
+
+func (mr *memWebhookRepo) Delete(uid string) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	if mr.byUID(uid) == nil {
+		return errors.Wrap(models.ErrNotFound, "memWebhookRepo.Delete error")
+	}
+
+	webhooks := mr.Store.Webhooks[:0]
+	for _, wh := range mr.Store.Webhooks {
+		if wh.UID != uid {
+			webhooks = append(webhooks, wh)
+		}
+	}
+	mr.Store.Webhooks = webhooks
+
+	deliveries := mr.Store.WebhookDeliveries[:0]
+	for _, d := range mr.Store.WebhookDeliveries {
+		if d.WebhookUID != uid {
+			deliveries = append(deliveries, d)
+		}
+	}
+	mr.Store.WebhookDeliveries = deliveries
+
+	return nil
+}
+
+func (mr *memWebhookRepo) Enqueue(_ context.Context, deliveries []*models.WebhookDelivery) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	for _, d := range deliveries {
+		if mr.queued(d.WebhookUID, d.EventID) {
+			continue
+		}
+
+		d.ID = mr.Store.NextID(d.TableName())
+		if d.CreatedAt.IsZero() {
+			d.CreatedAt = time.Now()
+		}
+
+		stored := *d
+		mr.Store.WebhookDeliveries = append(mr.Store.WebhookDeliveries, &stored)
+	}
+
+	return nil
+}
+
+func (mr *memWebhookRepo) Claim(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var due []*models.WebhookDelivery
+	for _, d := range mr.Store.WebhookDeliveries {
+		if d.Status == models.DeliveryStatusPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]*models.WebhookDelivery, len(due))
+	for i, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		res := *d
+		claimed[i] = &res
+	}
+
+	return claimed, nil
+}
+
+func (mr *memWebhookRepo) UpdateDelivery(d *models.WebhookDelivery) error {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	stored := mr.deliveryByUID(d.UID)
+	if stored == nil {
+		return errors.Wrap(models.ErrNotFound, "memWebhookRepo.UpdateDelivery error")
+	}
+
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.NextAttemptAt = d.NextAttemptAt
+	stored.LastError = d.LastError
+	stored.ResponseStatus = d.ResponseStatus
+	stored.DeliveredAt = d.DeliveredAt
+
+	return nil
+}
+
+func (mr *memWebhookRepo) GetDelivery(uid string) (*models.WebhookDelivery, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	d := mr.deliveryByUID(uid)
+	if d == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWebhookRepo.GetDelivery error")
+	}
+
+	res := *d
+	return &res, nil
+}
+
+func (mr *memWebhookRepo) GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	var deliveries []*models.WebhookDelivery
+	for i := len(mr.Store.WebhookDeliveries) - 1; i >= 0; i-- {
+		d := mr.Store.WebhookDeliveries[i]
+		if d.WebhookUID == webhookUID && (status == "" || d.Status == status) {
+			res := *d
+			deliveries = append(deliveries, &res)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (mr *memWebhookRepo) Replay(uid string, now time.Time) (*models.WebhookDelivery, error) {
+	mr.Store.Lock()
+	defer mr.Store.Unlock()
+
+	d := mr.deliveryByUID(uid)
+	if d == nil {
+		return nil, errors.Wrap(models.ErrNotFound, "memWebhookRepo.Replay error")
+	}
+
+	d.Status = models.DeliveryStatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.LastError = ""
+	d.ResponseStatus = 0
+	d.DeliveredAt = nil
+
+	res := *d
+	return &res, nil
+}
+
+func (mr *memWebhookRepo) byUID(uid string) *models.Webhook {
+	for _, wh := range mr.Store.Webhooks {
+		if wh.UID == uid {
+			return wh
+		}
+	}
+
+	return nil
+}
+
+func (mr *memWebhookRepo) deliveryByUID(uid string) *models.WebhookDelivery {
+	for _, d := range mr.Store.WebhookDeliveries {
+		if d.UID == uid {
+			return d
+		}
+	}
+
+	return nil
+}
+
+func (mr *memWebhookRepo) queued(webhookUID string, eventID string) bool {
+	for _, d := range mr.Store.WebhookDeliveries {
+		if d.WebhookUID == webhookUID && d.EventID == eventID {
+			return true
+		}
+	}
+
+	return false
+}
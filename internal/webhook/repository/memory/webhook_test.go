@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	"github.com/Davmie/javaCode/internal/webhook/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+)
+
+type WebhookMemRepoTestSuite struct {
+	suite.Suite
+	store *memstore.Store
+	repo  repository.WebhookRepositoryI
+	now   time.Time
+}
+
+func TestWebhookMemRepoSuite(t *testing.T) {
+	suite.RunSuite(t, new(WebhookMemRepoTestSuite))
+}
+
+func (s *WebhookMemRepoTestSuite) BeforeEach(t provider.T) {
+	var logger logger.Logger
+
+	s.store = memstore.New()
+	s.repo = New(logger, s.store)
+	s.now = time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "webhook", OwnerID: 1, Scope: models.WebhookScopeWallet, WalletUID: "wallet", URL: "https://partner.example/hook"}))
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{{
+		UID:           "delivery",
+		WebhookUID:    "webhook",
+		EventID:       "event",
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: s.now,
+	}}))
+}
+
+func (s *WebhookMemRepoTestSuite) TestGetByWalletUID(t provider.T) {
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "owner", OwnerID: 7, Scope: models.WebhookScopeOwner, URL: "https://partner.example/hook"}))
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "every", OwnerID: 2, Scope: models.WebhookScopeAll, URL: "https://admin.example/hook"}))
+
+	webhooks, err := s.repo.GetByWalletUID(context.Background(), "wallet", 7)
+	t.Require().NoError(err)
+	other, err := s.repo.GetByWalletUID(context.Background(), "other", 8)
+	t.Require().NoError(err)
+
+	t.Require().Len(webhooks, 3)
+	t.Assert().Equal("webhook", webhooks[0].UID)
+	t.Assert().Equal("owner", webhooks[1].UID)
+	t.Assert().Equal("every", webhooks[2].UID)
+	t.Require().Len(other, 1)
+	t.Assert().Equal("every", other[0].UID)
+}
+
+func (s *WebhookMemRepoTestSuite) TestEnqueueSkipsDuplicates(t provider.T) {
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{{
+		UID:        "duplicate",
+		WebhookUID: "webhook",
+		EventID:    "event",
+		Status:     models.DeliveryStatusPending,
+	}}))
+
+	deliveries, err := s.repo.GetDeliveries("webhook", "")
+
+	t.Require().NoError(err)
+	t.Require().Len(deliveries, 1)
+	t.Assert().Equal("delivery", deliveries[0].UID)
+}
+
+func (s *WebhookMemRepoTestSuite) TestClaimAndReplay(t provider.T) {
+	claimed, err := s.repo.Claim(s.now, time.Minute, 10)
+	t.Require().NoError(err)
+	t.Require().Len(claimed, 1)
+
+	claimed, err = s.repo.Claim(s.now, time.Minute, 10)
+	t.Require().NoError(err)
+	t.Assert().Empty(claimed)
+
+	d, err := s.repo.GetDelivery("delivery")
+	t.Require().NoError(err)
+	d.Status = models.DeliveryStatusDead
+	d.Attempts = 8
+	t.Require().NoError(s.repo.UpdateDelivery(d))
+
+	replayed, err := s.repo.Replay("delivery", s.now)
+
+	t.Require().NoError(err)
+	t.Assert().Equal(models.DeliveryStatusPending, replayed.Status)
+	t.Assert().Equal(0, replayed.Attempts)
+
+	claimed, err = s.repo.Claim(s.now, time.Minute, 10)
+	t.Require().NoError(err)
+	t.Assert().Len(claimed, 1)
+}
+
+func (s *WebhookMemRepoTestSuite) TestDeleteRemovesDeliveries(t provider.T) {
+	t.Require().NoError(s.repo.Delete("webhook"))
+
+	_, err := s.repo.GetDelivery("delivery")
+
+	t.Assert().True(errors.Is(err, models.ErrNotFound))
+}
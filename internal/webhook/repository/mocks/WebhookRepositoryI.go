@@ -0,0 +1,269 @@
+// Code generated by mockery v2.33.2. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/Davmie/javaCode/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// WebhookRepositoryI is an autogenerated mock type for the WebhookRepositoryI type
+type WebhookRepositoryI struct {
+	mock.Mock
+}
+
+// Claim provides a mock function with given fields: now, lease, limit
+func (_m *WebhookRepositoryI) Claim(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	ret := _m.Called(now, lease, limit)
+
+	var r0 []*models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(time.Time, time.Duration, int) ([]*models.WebhookDelivery, error)); ok {
+		return rf(now, lease, limit)
+	}
+	if rf, ok := ret.Get(0).(func(time.Time, time.Duration, int) []*models.WebhookDelivery); ok {
+		r0 = rf(now, lease, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(time.Time, time.Duration, int) error); ok {
+		r1 = rf(now, lease, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: wh
+func (_m *WebhookRepositoryI) Create(wh *models.Webhook) error {
+	ret := _m.Called(wh)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Webhook) error); ok {
+		r0 = rf(wh)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: uid
+func (_m *WebhookRepositoryI) Delete(uid string) error {
+	ret := _m.Called(uid)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(uid)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Enqueue provides a mock function with given fields: ctx, deliveries
+func (_m *WebhookRepositoryI) Enqueue(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	ret := _m.Called(ctx, deliveries)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.WebhookDelivery) error); ok {
+		r0 = rf(ctx, deliveries)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByOwner provides a mock function with given fields: ownerID
+func (_m *WebhookRepositoryI) GetByOwner(ownerID int) ([]*models.Webhook, error) {
+	ret := _m.Called(ownerID)
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(int) ([]*models.Webhook, error)); ok {
+		return rf(ownerID)
+	}
+	if rf, ok := ret.Get(0).(func(int) []*models.Webhook); ok {
+		r0 = rf(ownerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(int) error); ok {
+		r1 = rf(ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByUID provides a mock function with given fields: uid
+func (_m *WebhookRepositoryI) GetByUID(uid string) (*models.Webhook, error) {
+	ret := _m.Called(uid)
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.Webhook, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.Webhook); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByWalletUID provides a mock function with given fields: ctx, walletUID, ownerID
+func (_m *WebhookRepositoryI) GetByWalletUID(ctx context.Context, walletUID string, ownerID int) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx, walletUID, ownerID)
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*models.Webhook, error)); ok {
+		return rf(ctx, walletUID, ownerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*models.Webhook); ok {
+		r0 = rf(ctx, walletUID, ownerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, walletUID, ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDeliveries provides a mock function with given fields: webhookUID, status
+func (_m *WebhookRepositoryI) GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error) {
+	ret := _m.Called(webhookUID, status)
+
+	var r0 []*models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) ([]*models.WebhookDelivery, error)); ok {
+		return rf(webhookUID, status)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) []*models.WebhookDelivery); ok {
+		r0 = rf(webhookUID, status)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(webhookUID, status)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetDelivery provides a mock function with given fields: uid
+func (_m *WebhookRepositoryI) GetDelivery(uid string) (*models.WebhookDelivery, error) {
+	ret := _m.Called(uid)
+
+	var r0 *models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*models.WebhookDelivery, error)); ok {
+		return rf(uid)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.WebhookDelivery); ok {
+		r0 = rf(uid)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(uid)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Replay provides a mock function with given fields: uid, now
+func (_m *WebhookRepositoryI) Replay(uid string, now time.Time) (*models.WebhookDelivery, error) {
+	ret := _m.Called(uid, now)
+
+	var r0 *models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Time) (*models.WebhookDelivery, error)); ok {
+		return rf(uid, now)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Time) *models.WebhookDelivery); ok {
+		r0 = rf(uid, now)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Time) error); ok {
+		r1 = rf(uid, now)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateDelivery provides a mock function with given fields: d
+func (_m *WebhookRepositoryI) UpdateDelivery(d *models.WebhookDelivery) error {
+	ret := _m.Called(d)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.WebhookDelivery) error); ok {
+		r0 = rf(d)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewWebhookRepositoryI creates a new instance of WebhookRepositoryI. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhookRepositoryI(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhookRepositoryI {
+	mock := &WebhookRepositoryI{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
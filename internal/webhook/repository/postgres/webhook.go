@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/dbtx"
+	"github.com/Davmie/javaCode/internal/webhook/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pgWebhookRepo struct {
+	Logger logger.Logger
+	DB     *gorm.DB
+}
+
+func New(logger logger.Logger, db *gorm.DB) repository.WebhookRepositoryI {
+	return &pgWebhookRepo{
+		Logger: logger,
+		DB:     db,
+	}
+}
+
+func (pr *pgWebhookRepo) Create(wh *models.Webhook) error {
+	if err := pr.DB.Create(wh).Error; err != nil {
+		return errors.Wrap(err, "pgWebhookRepo.Create error while inserting in repo")
+	}
+
+	return nil
+}
+
+func (pr *pgWebhookRepo) GetByUID(uid string) (*models.Webhook, error) {
+	var wh models.Webhook
+	tx := pr.DB.Where("uid = ?", uid).Take(&wh)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgWebhookRepo.GetByUID error")
+	}
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgWebhookRepo.GetByUID error")
+	}
+
+	return &wh, nil
+}
+
+func (pr *pgWebhookRepo) GetByOwner(ownerID int) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	if err := pr.DB.Where("owner_id = ?", ownerID).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrap(err, "pgWebhookRepo.GetByOwner error")
+	}
+
+	return webhooks, nil
+}
+
+func (pr *pgWebhookRepo) GetByWalletUID(ctx context.Context, walletUID string, ownerID int) ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	err := dbtx.From(ctx, pr.DB).
+		Where("(scope = ? AND wallet_uid = ?) OR scope = ? OR (scope = ? AND owner_id <> 0 AND owner_id = ?)",
+			models.WebhookScopeWallet, walletUID, models.WebhookScopeAll, models.WebhookScopeOwner, ownerID).
+		Order("id").Find(&webhooks).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWebhookRepo.GetByWalletUID error")
+	}
+
+	return webhooks, nil
+}
+
+func (pr *pgWebhookRepo) Delete(uid string) error {
+	err := dbtx.Run(pr.DB, "webhook.delete", func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_uid = ?", uid).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where("uid = ?", uid).Delete(&models.Webhook{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return models.ErrNotFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "pgWebhookRepo.Delete error")
+	}
+
+	return nil
+}
+
+func (pr *pgWebhookRepo) Enqueue(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	err := dbtx.From(ctx, pr.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "webhook_uid"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&deliveries).Error
+	if err != nil {
+		return errors.Wrap(err, "pgWebhookRepo.Enqueue error")
+	}
+
+	return nil
+}
+
+func (pr *pgWebhookRepo) Claim(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := dbtx.Run(pr.DB, "webhook.claim", func(tx *gorm.DB) error {
+		deliveries = nil
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+			Order("next_attempt_at, id").Limit(limit).Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+			d.NextAttemptAt = now.Add(lease)
+		}
+
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "pgWebhookRepo.Claim error")
+	}
+
+	return deliveries, nil
+}
+
+func (pr *pgWebhookRepo) UpdateDelivery(d *models.WebhookDelivery) error {
+	err := pr.DB.Model(d).Select("status", "attempts", "next_attempt_at", "last_error", "response_status", "delivered_at").
+		Updates(d).Error
+	if err != nil {
+		return errors.Wrap(err, "pgWebhookRepo.UpdateDelivery error")
+	}
+
+	return nil
+}
+
+func (pr *pgWebhookRepo) GetDelivery(uid string) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	tx := pr.DB.Where("uid = ?", uid).Take(&d)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrap(models.ErrNotFound, "pgWebhookRepo.GetDelivery error")
+	}
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "pgWebhookRepo.GetDelivery error")
+	}
+
+	return &d, nil
+}
+
+func (pr *pgWebhookRepo) GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error) {
+	query := pr.DB.Where("webhook_uid = ?", webhookUID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := query.Order("id DESC").Find(&deliveries).Error; err != nil {
+		return nil, errors.Wrap(err, "pgWebhookRepo.GetDeliveries error")
+	}
+
+	return deliveries, nil
+}
+
+func (pr *pgWebhookRepo) Replay(uid string, now time.Time) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	res := pr.DB.Model(&d).Clauses(clause.Returning{}).Where("uid = ?", uid).
+		Updates(map[string]interface{}{
+			"status":          models.DeliveryStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"last_error":      "",
+			"response_status": 0,
+			"delivered_at":    nil,
+		})
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, "pgWebhookRepo.Replay error")
+	}
+
+	if res.RowsAffected == 0 {
+		return nil, errors.Wrap(models.ErrNotFound, "pgWebhookRepo.Replay error")
+	}
+
+	return &d, nil
+}
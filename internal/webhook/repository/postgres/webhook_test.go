@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	outboxPg "github.com/Davmie/javaCode/internal/outbox/repository/postgres"
+	"github.com/Davmie/javaCode/internal/testBuilders"
+	walletPg "github.com/Davmie/javaCode/internal/wallet/repository/postgres"
+	"github.com/Davmie/javaCode/internal/webhook/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/Davmie/javaCode/pkg/database"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type WebhookSQLiteTestSuite struct {
+	suite.Suite
+	db      *gorm.DB
+	repo    repository.WebhookRepositoryI
+	webhook *models.Webhook
+	now     time.Time
+}
+
+func TestWebhookSQLiteSuite(t *testing.T) {
+	suite.RunSuite(t, new(WebhookSQLiteTestSuite))
+}
+
+func (s *WebhookSQLiteTestSuite) BeforeEach(t provider.T) {
+	db, err := database.OpenSQLite(":memory:")
+	if err != nil {
+		t.Fatal("error while opening sqlite", err)
+	}
+
+	var logger logger.Logger
+
+	s.db = db
+	s.repo = New(logger, db)
+	s.now = time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	s.webhook = &models.Webhook{
+		UID:       "webhook",
+		OwnerID:   1,
+		Scope:     models.WebhookScopeWallet,
+		WalletUID: "wallet",
+		URL:       "https://partner.example/hook",
+		Events:    []string{"wallet.*"},
+		Secret:    "secret",
+	}
+	t.Require().NoError(s.repo.Create(s.webhook))
+}
+
+func (s *WebhookSQLiteTestSuite) delivery(uid string, eventID string) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		UID:           uid,
+		WebhookUID:    s.webhook.UID,
+		EventID:       eventID,
+		EventType:     models.EventWalletBalanceChanged,
+		Payload:       []byte(`{}`),
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: s.now,
+	}
+}
+
+func (s *WebhookSQLiteTestSuite) TestGetByWalletUID(t provider.T) {
+	every := &models.Webhook{UID: "every", OwnerID: 2, Scope: models.WebhookScopeAll, URL: "https://admin.example/hook"}
+	other := &models.Webhook{UID: "other", OwnerID: 1, Scope: models.WebhookScopeWallet, WalletUID: "other", URL: "https://partner.example/hook"}
+	t.Require().NoError(s.repo.Create(every))
+	t.Require().NoError(s.repo.Create(other))
+
+	webhooks, err := s.repo.GetByWalletUID(context.Background(), "wallet", 0)
+
+	t.Require().NoError(err)
+	t.Require().Len(webhooks, 2)
+	t.Assert().Equal("webhook", webhooks[0].UID)
+	t.Assert().Equal([]string{"wallet.*"}, webhooks[0].Events)
+	t.Assert().Equal("every", webhooks[1].UID)
+}
+
+func (s *WebhookSQLiteTestSuite) TestGetByWalletUIDOfOwner(t provider.T) {
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "owner", OwnerID: 7, Scope: models.WebhookScopeOwner, URL: "https://partner.example/hook"}))
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "nobody", Scope: models.WebhookScopeOwner, URL: "https://partner.example/hook"}))
+
+	// There are no wallet rows, like after a purge: the owner comes with the event.
+	owned, err := s.repo.GetByWalletUID(context.Background(), "purged", 7)
+	t.Require().NoError(err)
+	foreign, err := s.repo.GetByWalletUID(context.Background(), "foreign", 8)
+	t.Require().NoError(err)
+	unowned, err := s.repo.GetByWalletUID(context.Background(), "unowned", 0)
+	t.Require().NoError(err)
+
+	t.Require().Len(owned, 1)
+	t.Assert().Equal("owner", owned[0].UID)
+	t.Assert().Empty(foreign)
+	t.Assert().Empty(unowned)
+}
+
+func (s *WebhookSQLiteTestSuite) TestEnqueueSkipsDuplicates(t provider.T) {
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{s.delivery("first", "event")}))
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{s.delivery("second", "event")}))
+
+	deliveries, err := s.repo.GetDeliveries(s.webhook.UID, "")
+
+	t.Require().NoError(err)
+	t.Require().Len(deliveries, 1)
+	t.Assert().Equal("first", deliveries[0].UID)
+}
+
+func (s *WebhookSQLiteTestSuite) TestEnqueueWhileRelaying(t provider.T) {
+	var logger logger.Logger
+
+	walletRepo := walletPg.New(logger, s.db)
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("wallet").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	t.Require().NoError(walletRepo.Create(&wallet))
+
+	// SQLite has a single connection, the deliveries have to join the
+	// transaction of the relay.
+	n, err := outboxPg.New(logger, s.db).Deliver(context.Background(), 10, func(ctx context.Context, e *models.OutboxEvent) error {
+		if _, err := s.repo.GetByWalletUID(ctx, e.WalletUID, e.OwnerID); err != nil {
+			return err
+		}
+
+		return s.repo.Enqueue(ctx, []*models.WebhookDelivery{s.delivery("delivery", e.UID)})
+	})
+
+	t.Require().NoError(err)
+	t.Assert().Equal(1, n)
+
+	deliveries, err := s.repo.GetDeliveries(s.webhook.UID, models.DeliveryStatusPending)
+	t.Require().NoError(err)
+	t.Assert().Len(deliveries, 1)
+}
+
+func (s *WebhookSQLiteTestSuite) TestOwnerGetsPurge(t provider.T) {
+	var logger logger.Logger
+
+	t.Require().NoError(s.repo.Create(&models.Webhook{UID: "owner", OwnerID: 7, Scope: models.WebhookScopeOwner, URL: "https://partner.example/hook"}))
+
+	walletRepo := walletPg.New(logger, s.db)
+	wallet := testBuilders.NewWalletBuilder().
+		WithUID("purged").
+		WithStatus(models.WalletStatusActive).
+		Build()
+	wallet.OwnerID = 7
+	t.Require().NoError(walletRepo.Create(&wallet))
+	t.Require().NoError(walletRepo.Delete(wallet.ID, wallet.Version))
+	purged, err := walletRepo.Purge(time.Now().Add(time.Hour))
+	t.Require().NoError(err)
+	t.Require().Equal(1, purged)
+
+	// The wallet row is gone by the time the purge is relayed.
+	var covered []string
+	_, err = outboxPg.New(logger, s.db).Deliver(context.Background(), 10, func(ctx context.Context, e *models.OutboxEvent) error {
+		if e.Type != models.EventWalletPurged {
+			return nil
+		}
+
+		webhooks, err := s.repo.GetByWalletUID(ctx, e.WalletUID, e.OwnerID)
+		for _, wh := range webhooks {
+			covered = append(covered, wh.UID)
+		}
+		return err
+	})
+
+	t.Require().NoError(err)
+	t.Assert().Equal([]string{"owner"}, covered)
+}
+
+func (s *WebhookSQLiteTestSuite) TestClaimLeasesDeliveries(t provider.T) {
+	early := s.delivery("early", "first")
+	late := s.delivery("late", "second")
+	late.NextAttemptAt = s.now.Add(time.Hour)
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{early, late}))
+
+	claimed, err := s.repo.Claim(s.now, time.Minute, 10)
+
+	t.Require().NoError(err)
+	t.Require().Len(claimed, 1)
+	t.Assert().Equal("early", claimed[0].UID)
+
+	claimed, err = s.repo.Claim(s.now, time.Minute, 10)
+
+	t.Require().NoError(err)
+	t.Assert().Empty(claimed)
+
+	claimed, err = s.repo.Claim(s.now.Add(time.Minute), time.Minute, 10)
+
+	t.Require().NoError(err)
+	t.Assert().Len(claimed, 1)
+}
+
+func (s *WebhookSQLiteTestSuite) TestUpdateAndReplay(t provider.T) {
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{s.delivery("delivery", "event")}))
+
+	d, err := s.repo.GetDelivery("delivery")
+	t.Require().NoError(err)
+
+	d.Status = models.DeliveryStatusDead
+	d.Attempts = 8
+	d.LastError = "webhook responded with 500 Internal Server Error"
+	d.ResponseStatus = 500
+	t.Require().NoError(s.repo.UpdateDelivery(d))
+
+	dead, err := s.repo.GetDeliveries(s.webhook.UID, models.DeliveryStatusDead)
+	t.Require().NoError(err)
+	t.Require().Len(dead, 1)
+	t.Assert().Equal(8, dead[0].Attempts)
+
+	replayed, err := s.repo.Replay("delivery", s.now.Add(time.Hour))
+
+	t.Require().NoError(err)
+	t.Assert().Equal(models.DeliveryStatusPending, replayed.Status)
+	t.Assert().Equal(0, replayed.Attempts)
+	t.Assert().Empty(replayed.LastError)
+	t.Assert().True(replayed.NextAttemptAt.Equal(s.now.Add(time.Hour)))
+}
+
+func (s *WebhookSQLiteTestSuite) TestReplayNotFound(t provider.T) {
+	_, err := s.repo.Replay("missing", s.now)
+
+	t.Assert().True(errors.Is(err, models.ErrNotFound))
+}
+
+func (s *WebhookSQLiteTestSuite) TestDeleteRemovesDeliveries(t provider.T) {
+	t.Require().NoError(s.repo.Enqueue(context.Background(), []*models.WebhookDelivery{s.delivery("delivery", "event")}))
+
+	t.Require().NoError(s.repo.Delete(s.webhook.UID))
+
+	_, err := s.repo.GetByUID(s.webhook.UID)
+	t.Assert().True(errors.Is(err, models.ErrNotFound))
+
+	_, err = s.repo.GetDelivery("delivery")
+	t.Assert().True(errors.Is(err, models.ErrNotFound))
+
+	t.Assert().True(errors.Is(s.repo.Delete(s.webhook.UID), models.ErrNotFound))
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+)
+
+type WebhookRepositoryI interface {
+	Create(wh *models.Webhook) error
+	GetByUID(uid string) (*models.Webhook, error)
+	GetByOwner(ownerID int) ([]*models.Webhook, error)
+	// GetByWalletUID returns the webhooks covering the wallet of the owner:
+	// those of the wallet, of the owner and of every wallet, within the
+	// transaction of ctx if it carries one. The owner comes with the event, the
+	// wallet itself may be deleted or purged already.
+	GetByWalletUID(ctx context.Context, walletUID string, ownerID int) ([]*models.Webhook, error)
+	// Delete removes the webhook together with its deliveries.
+	Delete(uid string) error
+
+	// Enqueue stores the deliveries, skipping the events already queued for
+	// their webhooks, within the transaction of ctx if it carries one.
+	Enqueue(ctx context.Context, deliveries []*models.WebhookDelivery) error
+	// Claim returns at most limit pending deliveries due at now and postpones
+	// them by lease, so other dispatchers skip them while they are sent.
+	Claim(now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+	// UpdateDelivery saves the outcome of an attempt.
+	UpdateDelivery(d *models.WebhookDelivery) error
+	GetDelivery(uid string) (*models.WebhookDelivery, error)
+	// GetDeliveries returns the deliveries of the webhook, newest first, only
+	// those with the given status unless it is empty.
+	GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error)
+	// Replay makes the delivery pending again with a fresh set of attempts.
+	Replay(uid string, now time.Time) (*models.WebhookDelivery, error)
+}
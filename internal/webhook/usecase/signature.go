@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	HeaderSignature = "X-Wallets-Signature"
+	HeaderTimestamp = "X-Wallets-Timestamp"
+	HeaderEvent     = "X-Wallets-Event"
+	HeaderDelivery  = "X-Wallets-Delivery"
+)
+
+// Sign returns the signature of a delivery body sent at timestamp, the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed by the secret of the webhook. The
+// receiver recomputes it and rejects old timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
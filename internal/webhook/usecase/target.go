@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/Davmie/javaCode/models"
+	"github.com/pkg/errors"
+)
+
+// sharedAddrSpace is the shared address space of carrier-grade NAT (RFC 6598),
+// clouds use it for internal networks as well.
+var sharedAddrSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr tells whether the service may send deliveries to addr. Loopback,
+// private, shared, link-local and unspecified addresses reach the service
+// itself, its network or the metadata of the cloud it runs in, and are refused.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!sharedAddrSpace.Contains(addr) &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// CheckTarget resolves the host of the webhook url and fails with
+// models.ErrWebhookTarget unless all of its addresses are public. The client
+// checks the address again when it connects, a host may resolve differently
+// by then.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(models.ErrWebhookTarget, err.Error())
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", target.Hostname())
+	if err != nil {
+		return errors.Wrap(models.ErrWebhookTarget, err.Error())
+	}
+
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return errors.Wrapf(models.ErrWebhookTarget, "%s resolves to %s", target.Hostname(), addr)
+		}
+	}
+
+	return nil
+}
+
+// NewClient returns the client sending the deliveries. It connects to public
+// addresses only, ignores the proxy settings of the environment and doesn't
+// follow redirects, a redirected delivery fails with its 3xx status.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, publicAddr)
+}
+
+func newClient(timeout time.Duration, allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return errors.Wrap(models.ErrWebhookTarget, err.Error())
+			}
+			if !allowed(addrPort.Addr()) {
+				return errors.Wrapf(models.ErrWebhookTarget, "connection to %s refused", addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
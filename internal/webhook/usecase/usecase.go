@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/outbox"
+	webhookRep "github.com/Davmie/javaCode/internal/webhook/repository"
+	"github.com/Davmie/javaCode/models"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// dispatchBatch is how many deliveries a Dispatch sends at once.
+	dispatchBatch = 20
+	// claimLease keeps the claimed deliveries from other dispatchers, it has
+	// to outlast the request timeout.
+	claimLease = time.Minute
+	// maxBackoff caps the pause between the attempts of a delivery.
+	maxBackoff = 6 * time.Hour
+)
+
+type WebhookUseCaseI interface {
+	Create(wh *models.Webhook) error
+	GetByUID(uid string) (*models.Webhook, error)
+	GetByOwner(ownerID int) ([]*models.Webhook, error)
+	Delete(uid string) error
+	GetDelivery(uid string) (*models.WebhookDelivery, error)
+	GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error)
+	Replay(deliveryUID string) (*models.WebhookDelivery, error)
+	// Enqueue queues the event for every webhook subscribed to it. Relayed
+	// events are queued in the transaction marking them published.
+	Enqueue(ctx context.Context, e *models.OutboxEvent) error
+	// Dispatch sends the due deliveries and returns how many succeeded.
+	Dispatch(ctx context.Context) (int, error)
+}
+
+type webhookUseCase struct {
+	webhookRepository webhookRep.WebhookRepositoryI
+	client            *http.Client
+	maxAttempts       int
+	backoff           time.Duration
+	now               func() time.Time
+	checkTarget       func(ctx context.Context, rawURL string) error
+}
+
+// New returns the use case sending the deliveries with client, see NewClient.
+// A failed delivery is tried again after backoff, doubled with every attempt,
+// and is dead after maxAttempts attempts.
+func New(whRep webhookRep.WebhookRepositoryI, client *http.Client, maxAttempts int, backoff time.Duration) WebhookUseCaseI {
+	return &webhookUseCase{
+		webhookRepository: whRep,
+		client:            client,
+		maxAttempts:       maxAttempts,
+		backoff:           backoff,
+		now:               time.Now,
+		checkTarget:       CheckTarget,
+	}
+}
+
+// Create stores the webhook, with a generated secret unless it has one. Urls
+// of private addresses are rejected with models.ErrWebhookTarget.
+func (wUC *webhookUseCase) Create(wh *models.Webhook) error {
+	if err := wUC.checkTarget(context.Background(), wh.URL); err != nil {
+		return errors.Wrap(err, "webhookUseCase.Create error")
+	}
+
+	wh.UID = uuid.NewString()
+	wh.CreatedAt = wUC.now()
+
+	if wh.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return errors.Wrap(err, "webhookUseCase.Create error")
+		}
+		wh.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := wUC.webhookRepository.Create(wh); err != nil {
+		return errors.Wrap(err, "webhookUseCase.Create error")
+	}
+
+	return nil
+}
+
+// GetByUID returns the webhook without its secret.
+func (wUC *webhookUseCase) GetByUID(uid string) (*models.Webhook, error) {
+	wh, err := wUC.webhookRepository.GetByUID(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookUseCase.GetByUID error")
+	}
+
+	wh.Secret = ""
+	return wh, nil
+}
+
+// GetByOwner returns the webhooks of the owner without their secrets.
+func (wUC *webhookUseCase) GetByOwner(ownerID int) ([]*models.Webhook, error) {
+	webhooks, err := wUC.webhookRepository.GetByOwner(ownerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookUseCase.GetByOwner error")
+	}
+
+	for _, wh := range webhooks {
+		wh.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+func (wUC *webhookUseCase) Delete(uid string) error {
+	if err := wUC.webhookRepository.Delete(uid); err != nil {
+		return errors.Wrap(err, "webhookUseCase.Delete error")
+	}
+
+	return nil
+}
+
+func (wUC *webhookUseCase) GetDelivery(uid string) (*models.WebhookDelivery, error) {
+	d, err := wUC.webhookRepository.GetDelivery(uid)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookUseCase.GetDelivery error")
+	}
+
+	return d, nil
+}
+
+func (wUC *webhookUseCase) GetDeliveries(webhookUID string, status string) ([]*models.WebhookDelivery, error) {
+	deliveries, err := wUC.webhookRepository.GetDeliveries(webhookUID, status)
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookUseCase.GetDeliveries error")
+	}
+
+	return deliveries, nil
+}
+
+// Replay sends the delivery again with the next Dispatch, whatever happened to
+// it before.
+func (wUC *webhookUseCase) Replay(deliveryUID string) (*models.WebhookDelivery, error) {
+	d, err := wUC.webhookRepository.Replay(deliveryUID, wUC.now())
+	if err != nil {
+		return nil, errors.Wrap(err, "webhookUseCase.Replay error")
+	}
+
+	return d, nil
+}
+
+func (wUC *webhookUseCase) Enqueue(ctx context.Context, e *models.OutboxEvent) error {
+	webhooks, err := wUC.webhookRepository.GetByWalletUID(ctx, e.WalletUID, e.OwnerID)
+	if err != nil {
+		return errors.Wrap(err, "webhookUseCase.Enqueue error")
+	}
+
+	var deliveries []*models.WebhookDelivery
+	for _, wh := range webhooks {
+		if !wh.Matches(e.Type) {
+			continue
+		}
+
+		payload, err := outbox.Encode(e)
+		if err != nil {
+			return errors.Wrap(err, "webhookUseCase.Enqueue error")
+		}
+
+		now := wUC.now()
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			UID:           uuid.NewString(),
+			WebhookUID:    wh.UID,
+			EventID:       e.UID,
+			EventType:     e.Type,
+			Payload:       payload,
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	if err = wUC.webhookRepository.Enqueue(ctx, deliveries); err != nil {
+		return errors.Wrap(err, "webhookUseCase.Enqueue error")
+	}
+
+	return nil
+}
+
+func (wUC *webhookUseCase) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := wUC.webhookRepository.Claim(wUC.now(), claimLease, dispatchBatch)
+	if err != nil {
+		return 0, errors.Wrap(err, "webhookUseCase.Dispatch error")
+	}
+
+	webhooks := make(map[string]*models.Webhook)
+	for _, d := range deliveries {
+		if _, ok := webhooks[d.WebhookUID]; ok {
+			continue
+		}
+
+		wh, err := wUC.webhookRepository.GetByUID(d.WebhookUID)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			return 0, errors.Wrap(err, "webhookUseCase.Dispatch error")
+		}
+		webhooks[d.WebhookUID] = wh
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sent int
+		errs []error
+	)
+	for _, d := range deliveries {
+		// The deliveries of a webhook deleted meanwhile are gone with it.
+		wh := webhooks[d.WebhookUID]
+		if wh == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(wh *models.Webhook, d *models.WebhookDelivery) {
+			defer wg.Done()
+
+			ok, err := wUC.attempt(ctx, wh, d)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				sent++
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}(wh, d)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return sent, errors.Wrap(errs[0], "webhookUseCase.Dispatch error")
+	}
+
+	return sent, nil
+}
+
+// attempt sends the delivery once and saves the outcome: delivered, pending
+// for another attempt or dead.
+func (wUC *webhookUseCase) attempt(ctx context.Context, wh *models.Webhook, d *models.WebhookDelivery) (bool, error) {
+	status, sendErr := wUC.send(ctx, wh, d)
+
+	now := wUC.now()
+	d.Attempts++
+	d.ResponseStatus = status
+
+	switch {
+	case sendErr == nil:
+		d.Status = models.DeliveryStatusDelivered
+		d.DeliveredAt = &now
+		d.LastError = ""
+	case d.Attempts >= wUC.maxAttempts:
+		d.Status = models.DeliveryStatusDead
+		d.LastError = sendErr.Error()
+	default:
+		d.NextAttemptAt = now.Add(min(wUC.backoff<<(d.Attempts-1), maxBackoff))
+		d.LastError = sendErr.Error()
+	}
+
+	if err := wUC.webhookRepository.UpdateDelivery(d); err != nil {
+		return false, err
+	}
+
+	return sendErr == nil, nil
+}
+
+// send posts the payload of the delivery to the webhook and returns the
+// response status. Only 2xx responses count as delivered.
+func (wUC *webhookUseCase) send(ctx context.Context, wh *models.Webhook, d *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := wUC.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.UID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, timestamp, d.Payload))
+
+	resp, err := wUC.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drained bodies let the client reuse the connection.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/outbox"
+	webhookMocks "github.com/Davmie/javaCode/internal/webhook/repository/mocks"
+	"github.com/Davmie/javaCode/models"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/stretchr/testify/mock"
+)
+
+type WebhookTestSuite struct {
+	suite.Suite
+	uc              *webhookUseCase
+	webhookRepoMock *webhookMocks.WebhookRepositoryI
+	server          *httptest.Server
+	status          int
+	requests        []*http.Request
+	bodies          [][]byte
+	now             time.Time
+}
+
+func TestWebhookTestSuite(t *testing.T) {
+	suite.RunSuite(t, new(WebhookTestSuite))
+}
+
+func (s *WebhookTestSuite) BeforeEach(t provider.T) {
+	s.status = http.StatusOK
+	s.requests = nil
+	s.bodies = nil
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		w.WriteHeader(s.status)
+	}))
+
+	s.webhookRepoMock = webhookMocks.NewWebhookRepositoryI(t)
+	s.now = time.Date(2024, 11, 9, 12, 0, 0, 0, time.UTC)
+	s.uc = &webhookUseCase{
+		webhookRepository: s.webhookRepoMock,
+		client:            s.server.Client(),
+		maxAttempts:       3,
+		backoff:           30 * time.Second,
+		now:               func() time.Time { return s.now },
+		// The urls of the tests don't resolve or point to loopback.
+		checkTarget: func(context.Context, string) error { return nil },
+	}
+}
+
+func (s *WebhookTestSuite) AfterEach(t provider.T) {
+	s.server.Close()
+}
+
+func (s *WebhookTestSuite) webhook() *models.Webhook {
+	return &models.Webhook{UID: "webhook", OwnerID: 1, Scope: models.WebhookScopeWallet, WalletUID: "wallet", URL: s.server.URL, Secret: "secret"}
+}
+
+func (s *WebhookTestSuite) delivery(attempts int) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		UID:        "delivery",
+		WebhookUID: "webhook",
+		EventID:    "event",
+		EventType:  models.EventWalletBalanceChanged,
+		Payload:    []byte(`{"schemaVersion":1}`),
+		Status:     models.DeliveryStatusPending,
+		Attempts:   attempts,
+	}
+}
+
+func (s *WebhookTestSuite) TestCreateGeneratesSecret(t provider.T) {
+	s.webhookRepoMock.On("Create", mock.MatchedBy(func(wh *models.Webhook) bool {
+		return wh.UID != "" && len(wh.Secret) == 64 && wh.CreatedAt.Equal(s.now)
+	})).Return(nil)
+
+	wh := &models.Webhook{OwnerID: 1, URL: "https://partner.example/hook"}
+
+	t.Assert().NoError(s.uc.Create(wh))
+	t.Assert().NotEmpty(wh.Secret)
+}
+
+func (s *WebhookTestSuite) TestGetByOwnerHidesSecrets(t provider.T) {
+	s.webhookRepoMock.On("GetByOwner", 1).Return([]*models.Webhook{s.webhook()}, nil)
+
+	webhooks, err := s.uc.GetByOwner(1)
+
+	t.Require().NoError(err)
+	t.Assert().Empty(webhooks[0].Secret)
+}
+
+func (s *WebhookTestSuite) TestEnqueueMatchingWebhooks(t provider.T) {
+	balance := s.webhook()
+	balance.Events = []string{models.EventWalletBalanceChanged}
+	holds := &models.Webhook{UID: "holds", Scope: models.WebhookScopeWallet, WalletUID: "wallet", Events: []string{"hold.*"}}
+
+	s.webhookRepoMock.On("GetByWalletUID", mock.Anything, "wallet", 1).Return([]*models.Webhook{balance, holds}, nil)
+	s.webhookRepoMock.On("Enqueue", mock.Anything, mock.MatchedBy(func(deliveries []*models.WebhookDelivery) bool {
+		if len(deliveries) != 1 {
+			return false
+		}
+
+		d := deliveries[0]
+		envelope, err := outbox.Decode(d.Payload)
+		return err == nil && d.WebhookUID == "webhook" && d.EventID == "event" &&
+			d.Status == models.DeliveryStatusPending && d.NextAttemptAt.Equal(s.now) && envelope.GlobalSequence == 7
+	})).Return(nil)
+
+	err := s.uc.Enqueue(context.Background(), &models.OutboxEvent{
+		ID:        7,
+		UID:       "event",
+		Type:      models.EventWalletBalanceChanged,
+		WalletUID: "wallet",
+		OwnerID:   1,
+		Payload:   []byte(`{}`),
+	})
+
+	t.Assert().NoError(err)
+}
+
+func (s *WebhookTestSuite) TestDispatchSignsDelivery(t provider.T) {
+	s.webhookRepoMock.On("Claim", s.now, claimLease, dispatchBatch).Return([]*models.WebhookDelivery{s.delivery(0)}, nil)
+	s.webhookRepoMock.On("GetByUID", "webhook").Return(s.webhook(), nil)
+	s.webhookRepoMock.On("UpdateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusDelivered && d.Attempts == 1 && d.ResponseStatus == http.StatusOK &&
+			d.DeliveredAt != nil && d.DeliveredAt.Equal(s.now)
+	})).Return(nil)
+
+	n, err := s.uc.Dispatch(context.Background())
+
+	t.Require().NoError(err)
+	t.Assert().Equal(1, n)
+	t.Require().Len(s.requests, 1)
+
+	r := s.requests[0]
+	timestamp := strconv.FormatInt(s.now.Unix(), 10)
+	t.Assert().Equal(timestamp, r.Header.Get(HeaderTimestamp))
+	t.Assert().Equal(models.EventWalletBalanceChanged, r.Header.Get(HeaderEvent))
+	t.Assert().Equal("delivery", r.Header.Get(HeaderDelivery))
+	t.Assert().Equal(Sign("secret", s.now.Unix(), s.bodies[0]), r.Header.Get(HeaderSignature))
+	t.Assert().Equal(`{"schemaVersion":1}`, string(s.bodies[0]))
+}
+
+func (s *WebhookTestSuite) TestDispatchBacksOff(t provider.T) {
+	s.status = http.StatusInternalServerError
+
+	s.webhookRepoMock.On("Claim", s.now, claimLease, dispatchBatch).Return([]*models.WebhookDelivery{s.delivery(1)}, nil)
+	s.webhookRepoMock.On("GetByUID", "webhook").Return(s.webhook(), nil)
+	s.webhookRepoMock.On("UpdateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusPending && d.Attempts == 2 &&
+			d.ResponseStatus == http.StatusInternalServerError && d.LastError != "" &&
+			d.NextAttemptAt.Equal(s.now.Add(time.Minute))
+	})).Return(nil)
+
+	n, err := s.uc.Dispatch(context.Background())
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, n)
+}
+
+func (s *WebhookTestSuite) TestDispatchDeadLetters(t provider.T) {
+	s.status = http.StatusGone
+
+	s.webhookRepoMock.On("Claim", s.now, claimLease, dispatchBatch).Return([]*models.WebhookDelivery{s.delivery(2)}, nil)
+	s.webhookRepoMock.On("GetByUID", "webhook").Return(s.webhook(), nil)
+	s.webhookRepoMock.On("UpdateDelivery", mock.MatchedBy(func(d *models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusDead && d.Attempts == 3 && d.ResponseStatus == http.StatusGone
+	})).Return(nil)
+
+	_, err := s.uc.Dispatch(context.Background())
+
+	t.Assert().NoError(err)
+}
+
+func (s *WebhookTestSuite) TestDispatchSkipsDeletedWebhook(t provider.T) {
+	s.webhookRepoMock.On("Claim", s.now, claimLease, dispatchBatch).Return([]*models.WebhookDelivery{s.delivery(0)}, nil)
+	s.webhookRepoMock.On("GetByUID", "webhook").Return(nil, models.ErrNotFound)
+
+	n, err := s.uc.Dispatch(context.Background())
+
+	t.Assert().NoError(err)
+	t.Assert().Equal(0, n)
+	t.Assert().Empty(s.requests)
+}
+
+func (s *WebhookTestSuite) TestSign(t provider.T) {
+	signature := Sign("secret", 1731153600, []byte(`{"schemaVersion":1}`))
+
+	t.Assert().Equal("sha256=20853bb94b08476cf9776a1f03118de720420b78316bda504b34f18124680ef7", signature)
+}
+
+func (s *WebhookTestSuite) TestCreateRejectsPrivateTarget(t provider.T) {
+	s.uc.checkTarget = CheckTarget
+
+	for _, target := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.1/hook",
+		"http://100.100.100.200/hook",
+		"http://[::ffff:100.64.0.1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		err := s.uc.Create(&models.Webhook{OwnerID: 1, URL: target})
+		t.Assert().ErrorIs(err, models.ErrWebhookTarget, target)
+	}
+}
+
+func (s *WebhookTestSuite) TestCheckTargetPublicAddress(t provider.T) {
+	t.Assert().NoError(CheckTarget(context.Background(), "https://93.184.215.14/hook"))
+}
+
+func (s *WebhookTestSuite) TestClientRefusesPrivateAddress(t provider.T) {
+	_, err := NewClient(time.Second).Get(s.server.URL)
+
+	t.Assert().ErrorIs(err, models.ErrWebhookTarget)
+	t.Assert().Empty(s.requests)
+}
+
+func (s *WebhookTestSuite) TestClientDoesNotFollowRedirects(t provider.T) {
+	redirect := httptest.NewServer(http.RedirectHandler(s.server.URL, http.StatusFound))
+	defer redirect.Close()
+
+	client := newClient(time.Second, func(netip.Addr) bool { return true })
+	resp, err := client.Get(redirect.URL)
+	t.Require().NoError(err)
+	resp.Body.Close()
+
+	t.Assert().Equal(http.StatusFound, resp.StatusCode)
+	t.Assert().Empty(s.requests)
+}
@@ -0,0 +1,21 @@
+package models
+
+import "github.com/pkg/errors"
+
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrHoldNotActive      = errors.New("hold is not active")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds hold amount")
+	ErrNotReversible      = errors.New("transaction can`t be reversed")
+	ErrAlreadyReversed    = errors.New("transaction is already reversed")
+	ErrReversalExceeds    = errors.New("reversal amount exceeds transaction amount")
+	ErrWalletFrozen       = errors.New("wallet is frozen")
+	ErrWalletClosed       = errors.New("wallet is closed")
+	ErrStatusTransition   = errors.New("wallet status transition is not allowed")
+	ErrNonZeroBalance     = errors.New("wallet balance is not zero")
+	ErrVersionMismatch    = errors.New("wallet version mismatch")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrBadShards          = errors.New("bad number of wallet shards")
+	ErrWebhookTarget      = errors.New("webhook url must point to a public address")
+)
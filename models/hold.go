@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	HoldStatusActive   = "ACTIVE"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusVoided   = "VOIDED"
+	HoldStatusExpired  = "EXPIRED"
+)
+
+func (Hold) TableName() string {
+	return "hold"
+}
+
+type Hold struct {
+	ID        int       `json:"id" db:"id" gorm:"size:32"`
+	UID       string    `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:hold_uid_key"`
+	WalletUID string    `json:"walletId" db:"wallet_uid" gorm:"type:uuid;not null"`
+	Amount    int       `json:"amount" db:"amount" gorm:"size:32;not null"`
+	Captured  int       `json:"captured" db:"captured" gorm:"size:32;not null;default:0"`
+	Status    string    `json:"status" db:"status" gorm:"size:16;not null"`
+	ExpiresAt time.Time `json:"expiresAt" db:"expires_at" gorm:"not null;index:hold_active_expires_at_idx,where:status = 'ACTIVE'"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"not null"`
+
+	Wallet *Wallet `json:"-" gorm:"foreignKey:WalletUID;references:UID"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventWalletCreated        = "wallet.created"
+	EventWalletUpdated        = "wallet.updated"
+	EventWalletDeleted        = "wallet.deleted"
+	EventWalletRestored       = "wallet.restored"
+	EventWalletPurged         = "wallet.purged"
+	EventWalletBalanceChanged = "wallet.balance_changed"
+	EventWalletStatusChanged  = "wallet.status_changed"
+	EventWalletShardsChanged  = "wallet.shards_changed"
+	EventHoldCreated          = "hold.created"
+	EventHoldCaptured         = "hold.captured"
+	EventHoldVoided           = "hold.voided"
+	EventHoldExpired          = "hold.expired"
+)
+
+func (OutboxEvent) TableName() string {
+	return "outbox_event"
+}
+
+// OutboxEvent is written in the same transaction as the change it describes and
+// is delivered to other services afterwards. Events of a wallet are delivered
+// in the order of their ids. OwnerID is the owner of the wallet at the change,
+// the wallet may be gone by the time the event is delivered.
+type OutboxEvent struct {
+	ID          int             `json:"id" db:"id" gorm:"index:outbox_event_unpublished_idx,where:published_at IS NULL"`
+	UID         string          `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:outbox_event_uid_key"`
+	Type        string          `json:"type" db:"type" gorm:"size:64;not null"`
+	WalletUID   string          `json:"walletId" db:"wallet_uid" gorm:"type:uuid;not null;index:outbox_event_wallet_uid_idx"`
+	OwnerID     int             `json:"ownerId" db:"owner_id" gorm:"size:32;not null;default:0"`
+	Payload     json.RawMessage `json:"payload" db:"payload" gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time       `json:"createdAt" db:"created_at" gorm:"not null"`
+	PublishedAt *time.Time      `json:"-" db:"published_at"`
+}
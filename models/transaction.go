@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	TransactionTypeDeposit    = "DEPOSIT"
+	TransactionTypeWithdraw   = "WITHDRAW"
+	TransactionTypeCapture    = "CAPTURE"
+	TransactionTypeReversal   = "REVERSAL"
+	TransactionTypeAdjustment = "ADJUSTMENT"
+)
+
+// Reason codes of manual balance adjustments.
+const (
+	AdjustmentReasonCorrection = "CORRECTION"
+	AdjustmentReasonChargeback = "CHARGEBACK"
+	AdjustmentReasonFee        = "FEE"
+	AdjustmentReasonGoodwill   = "GOODWILL"
+	AdjustmentReasonMigration  = "MIGRATION"
+)
+
+func (Transaction) TableName() string {
+	return "transaction"
+}
+
+// Transaction is a ledger entry. Amount is the signed change of the wallet balance,
+// Reversed is the absolute amount already compensated by reversal entries.
+// ReasonCode, Comment and ActorID are only set for manual adjustments.
+type Transaction struct {
+	ID         int       `json:"id" db:"id" gorm:"size:32;index:transaction_wallet_uid_idx,priority:2"`
+	UID        string    `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:transaction_uid_key"`
+	WalletUID  string    `json:"walletId" db:"wallet_uid" gorm:"type:uuid;not null;index:transaction_wallet_uid_idx,priority:1"`
+	Type       string    `json:"operationType" db:"type" gorm:"size:16;not null"`
+	Amount     int       `json:"amount" db:"amount" gorm:"size:32;not null"`
+	Reversed   int       `json:"reversed" db:"reversed" gorm:"size:32;not null;default:0"`
+	ReversalOf *string   `json:"reversalOf,omitempty" db:"reversal_of" gorm:"type:uuid"`
+	ReasonCode string    `json:"reasonCode,omitempty" db:"reason_code" gorm:"size:32;not null;default:''"`
+	Comment    string    `json:"comment,omitempty" db:"comment" gorm:"not null;default:''"`
+	ActorID    *int      `json:"actorId,omitempty" db:"actor_id" gorm:"size:32"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at" gorm:"not null"`
+
+	Wallet   *Wallet      `json:"-" gorm:"foreignKey:WalletUID;references:UID"`
+	Original *Transaction `json:"-" gorm:"foreignKey:ReversalOf;references:UID"`
+}
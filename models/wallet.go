@@ -1,5 +1,18 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WalletStatusActive = "ACTIVE"
+	WalletStatusFrozen = "FROZEN"
+	WalletStatusClosed = "CLOSED"
+)
+
 type Tabler interface {
 	TableName() string
 }
@@ -9,7 +22,74 @@ func (Wallet) TableName() string {
 }
 
 type Wallet struct {
-	ID     int    `json:"id" db:"id"`
-	UID    string `json:"uid" db:"uid"` // a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11
-	Amount int    `json:"amount" db:"amount"`
+	ID     int    `json:"id" db:"id" gorm:"size:32"`
+	UID    string `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:wallet_uid_key"` // a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11
+	Name   string `json:"name" db:"name" gorm:"size:255;not null;default:''"`
+	Amount int    `json:"amount" db:"amount" gorm:"size:32;not null"`
+	// Held is never negative and never above Amount of the wallet row.
+	Held   int    `json:"held" db:"held" gorm:"size:32;not null;default:0;check:held >= 0 AND held <= amount"`
+	Status string `json:"status" db:"status" gorm:"size:16;not null;default:ACTIVE"`
+	// Version is bumped by every change of the wallet row and is used as its ETag.
+	Version int `json:"version" db:"version" gorm:"size:32;not null;default:0"`
+	// Shards is the number of WalletShard rows deposits are spread over, 0 for
+	// a wallet kept in its own row only. Amount of a sharded wallet is the sum
+	// of its row and its shards.
+	Shards int `json:"shards" db:"shards" gorm:"size:32;not null;default:0"`
+	// OwnerID is the user who created the wallet, 0 for wallets created without
+	// a session, which only admins may follow.
+	OwnerID int `json:"ownerId" db:"owner_id" gorm:"size:32;not null;default:0;index:wallet_owner_id_idx"`
+
+	DeletedAt gorm.DeletedAt `json:"deletedAt" db:"deleted_at" gorm:"index:wallet_deleted_at_idx,where:deleted_at IS NOT NULL"`
+}
+
+// OwnedBy tells whether the user created the wallet.
+func (w Wallet) OwnedBy(userID int) bool {
+	return w.OwnerID != 0 && w.OwnerID == userID
+}
+
+// Available is the part of the ledger balance that is not reserved by active holds.
+func (w Wallet) Available() int {
+	return w.Amount - w.Held
+}
+
+func (w Wallet) MarshalJSON() ([]byte, error) {
+	type wallet Wallet
+	return json.Marshal(struct {
+		wallet
+		Available int `json:"available"`
+	}{
+		wallet:    wallet(w),
+		Available: w.Available(),
+	})
+}
+
+func (WalletShard) TableName() string {
+	return "wallet_shard"
+}
+
+// WalletShard holds a part of the balance of a sharded wallet. Deposits change
+// a single shard, so they don't wait for each other on the wallet row.
+type WalletShard struct {
+	ID        int    `json:"id" db:"id" gorm:"size:32"`
+	WalletUID string `json:"walletId" db:"wallet_uid" gorm:"type:uuid;not null;uniqueIndex:wallet_shard_wallet_uid_shard_key"`
+	Shard     int    `json:"shard" db:"shard" gorm:"size:32;not null;uniqueIndex:wallet_shard_wallet_uid_shard_key"`
+	Amount    int    `json:"amount" db:"amount" gorm:"size:32;not null;default:0"`
+
+	Wallet *Wallet `json:"-" gorm:"foreignKey:WalletUID;references:UID"`
+}
+
+func (WalletStatusChange) TableName() string {
+	return "wallet_status_change"
+}
+
+type WalletStatusChange struct {
+	ID         int       `json:"id" db:"id" gorm:"size:32"`
+	WalletUID  string    `json:"walletId" db:"wallet_uid" gorm:"type:uuid;not null;index"`
+	FromStatus string    `json:"fromStatus" db:"from_status" gorm:"size:16;not null"`
+	ToStatus   string    `json:"toStatus" db:"to_status" gorm:"size:16;not null"`
+	ActorID    int       `json:"actorId" db:"actor_id" gorm:"size:32;not null"`
+	Reason     string    `json:"reason" db:"reason" gorm:"not null"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at" gorm:"not null"`
+
+	Wallet *Wallet `json:"-" gorm:"foreignKey:WalletUID;references:UID"`
 }
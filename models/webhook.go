@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Scopes of webhooks: a single wallet, every wallet of the owner of the webhook,
+// or every wallet.
+const (
+	WebhookScopeWallet = "WALLET"
+	WebhookScopeOwner  = "OWNER"
+	WebhookScopeAll    = "ALL"
+)
+
+const (
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusDelivered = "DELIVERED"
+	DeliveryStatusDead      = "DEAD"
+)
+
+func (Webhook) TableName() string {
+	return "webhook"
+}
+
+// Webhook subscribes its owner to the events of the wallets of its Scope:
+// WalletUID, the wallets the owner owns or every wallet. Events lists the event
+// types to deliver, a type ending with ".*" matches the whole group, an empty
+// list matches everything. Secret signs the deliveries and is only shown when
+// the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id" db:"id" gorm:"size:32"`
+	UID       string    `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:webhook_uid_key"`
+	OwnerID   int       `json:"ownerId" db:"owner_id" gorm:"size:32;not null;index:webhook_owner_id_idx"`
+	Scope     string    `json:"scope" db:"scope" gorm:"size:16;not null;default:''"`
+	WalletUID string    `json:"walletId,omitempty" db:"wallet_uid" gorm:"size:36;not null;default:'';index:webhook_wallet_uid_idx"`
+	URL       string    `json:"url" db:"url" gorm:"not null"`
+	Events    []string  `json:"events" db:"events" gorm:"serializer:json;not null;default:'[]'"`
+	Secret    string    `json:"secret,omitempty" db:"secret" gorm:"not null"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at" gorm:"not null"`
+}
+
+// Covers tells whether the wallet is in the scope of the webhook, walletOwnerID
+// is the owner of the wallet.
+func (wh Webhook) Covers(walletUID string, walletOwnerID int) bool {
+	switch wh.Scope {
+	case WebhookScopeWallet:
+		return wh.WalletUID == walletUID
+	case WebhookScopeOwner:
+		return wh.OwnerID != 0 && wh.OwnerID == walletOwnerID
+	case WebhookScopeAll:
+		return true
+	default:
+		return false
+	}
+}
+
+// Matches tells whether events of the type are delivered to the webhook.
+func (wh Webhook) Matches(eventType string) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+
+	for _, filter := range wh.Events {
+		if filter == eventType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(filter, "*"); ok && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+// WebhookDelivery is an event on its way to a webhook. A failed delivery is
+// tried again at NextAttemptAt, after the last attempt it is DEAD until it is
+// replayed.
+type WebhookDelivery struct {
+	ID             int             `json:"id" db:"id"`
+	UID            string          `json:"uid" db:"uid" gorm:"type:uuid;not null;uniqueIndex:webhook_delivery_uid_key"`
+	WebhookUID     string          `json:"webhookId" db:"webhook_uid" gorm:"type:uuid;not null;uniqueIndex:webhook_delivery_webhook_uid_event_id_key"`
+	EventID        string          `json:"eventId" db:"event_id" gorm:"type:uuid;not null;uniqueIndex:webhook_delivery_webhook_uid_event_id_key"`
+	EventType      string          `json:"eventType" db:"event_type" gorm:"size:64;not null"`
+	Payload        json.RawMessage `json:"payload" db:"payload" gorm:"type:jsonb;not null"`
+	Status         string          `json:"status" db:"status" gorm:"size:16;not null"`
+	Attempts       int             `json:"attempts" db:"attempts" gorm:"size:32;not null;default:0"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt" db:"next_attempt_at" gorm:"not null;index:webhook_delivery_pending_idx,where:status = 'PENDING'"`
+	LastError      string          `json:"lastError,omitempty" db:"last_error" gorm:"not null;default:''"`
+	ResponseStatus int             `json:"responseStatus,omitempty" db:"response_status" gorm:"size:32;not null;default:0"`
+	CreatedAt      time.Time       `json:"createdAt" db:"created_at" gorm:"not null"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty" db:"delivered_at"`
+
+	Webhook *Webhook `json:"-" gorm:"foreignKey:WebhookUID;references:UID;constraint:OnDelete:CASCADE"`
+}
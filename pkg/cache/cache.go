@@ -0,0 +1,15 @@
+package cache
+
+// Cache stores serialized values for a limited time. The time to live is set
+// when a cache is constructed.
+type Cache interface {
+	// Get returns the value stored under key, ok is false on a miss.
+	Get(key string) (value []byte, ok bool, err error)
+	Set(key string, value []byte) error
+	// Add stores value under key unless key holds a value already, added
+	// tells whether it did. Of concurrent calls for a key only one adds.
+	Add(key string, value []byte) (added bool, err error)
+	Delete(keys ...string) error
+	// Clear drops every value of the cache.
+	Clear() error
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix namespaces the wallet cache in a Redis shared with other services.
+const redisPrefix = "wallets:"
+
+// redisIdempotencyPrefix keeps the idempotency store apart from the wallet
+// cache, so clearing the cache doesn't forget the responses.
+const redisIdempotencyPrefix = "wallets-idempotency:"
+
+// Open builds the cache chosen in cfg. It returns nil when caching is disabled.
+func Open(cfg *config.Config) (Cache, error) {
+	switch cfg.Cache {
+	case config.CacheNone:
+		return nil, nil
+	case config.CacheLRU:
+		return NewLRU(cfg.CacheSize, cfg.CacheTTL), nil
+	case config.CacheRedis:
+		client, err := openRedis(cfg)
+		if err != nil {
+			return nil, errors.Wrap(err, "cache.Open error")
+		}
+
+		return NewRedis(client, redisPrefix, cfg.CacheTTL), nil
+	default:
+		return nil, errors.Errorf("cache.Open error: unknown cache %q", cfg.Cache)
+	}
+}
+
+// OpenIdempotency builds the store of the responses to requests with an
+// idempotency key. It shares Redis with the wallet cache when there is one, so
+// a retry may reach any instance, and is an in-process LRU otherwise.
+func OpenIdempotency(cfg *config.Config) (Cache, error) {
+	if cfg.Cache != config.CacheRedis {
+		return NewLRU(cfg.IdempotencySize, cfg.IdempotencyTTL), nil
+	}
+
+	client, err := openRedis(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cache.OpenIdempotency error")
+	}
+
+	return NewRedis(client, redisIdempotencyPrefix, cfg.IdempotencyTTL), nil
+}
+
+func openRedis(cfg *config.Config) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+// pinnedValue is a value stored by Add, it is not evicted for room.
+type pinnedValue struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type lruCache struct {
+	// mu makes Add atomic, the changes of the values are serialized with it.
+	mu  sync.Mutex
+	lru *expirable.LRU[string, []byte]
+	// pinned keeps the values stored by Add until they are set, deleted or
+	// expire. A reservation evicted by other values would let its request run
+	// again.
+	pinned map[string]pinnedValue
+	ttl    time.Duration
+}
+
+// NewLRU returns an in-process cache keeping at most size values for ttl each.
+// Values stored by Add don't count towards size until they are set.
+func NewLRU(size int, ttl time.Duration) Cache {
+	return &lruCache{
+		lru:    expirable.NewLRU[string, []byte](size, nil, ttl),
+		pinned: make(map[string]pinnedValue),
+		ttl:    ttl,
+	}
+}
+
+func (c *lruCache) Get(key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	pinned, ok := c.pin(key)
+	c.mu.Unlock()
+	if ok {
+		return pinned, true, nil
+	}
+
+	value, ok := c.lru.Get(key)
+	return value, ok, nil
+}
+
+func (c *lruCache) Set(key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pinned, key)
+	c.lru.Add(key, value)
+	return nil
+}
+
+func (c *lruCache) Add(key string, value []byte) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pin(key); ok {
+		return false, nil
+	}
+	// Contains would count expired values that are not evicted yet.
+	if _, ok := c.lru.Peek(key); ok {
+		return false, nil
+	}
+	c.pinned[key] = pinnedValue{value: value, expiresAt: time.Now().Add(c.ttl)}
+
+	return true, nil
+}
+
+func (c *lruCache) Delete(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		delete(c.pinned, key)
+		c.lru.Remove(key)
+	}
+
+	return nil
+}
+
+func (c *lruCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.pinned)
+	c.lru.Purge()
+	return nil
+}
+
+// pin returns the pinned value of key and drops it once it expires. It must be
+// called with mu held.
+func (c *lruCache) pin(key string) ([]byte, bool) {
+	pinned, ok := c.pinned[key]
+	if !ok {
+		return nil, false
+	}
+
+	if c.ttl > 0 && !time.Now().Before(pinned.expiresAt) {
+		delete(c.pinned, key)
+		return nil, false
+	}
+
+	return pinned.value, true
+}
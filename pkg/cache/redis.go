@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+type redisCache struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedis returns a cache shared by every instance connected to the same Redis.
+// Keys are namespaced with prefix, so Clear only drops keys of this cache.
+func NewRedis(client *redis.Client, prefix string, ttl time.Duration) Cache {
+	return &redisCache{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (c *redisCache) Get(key string) ([]byte, bool, error) {
+	value, err := c.client.Get(context.Background(), c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "redisCache.Get error")
+	}
+
+	return value, true, nil
+}
+
+func (c *redisCache) Set(key string, value []byte) error {
+	err := c.client.Set(context.Background(), c.prefix+key, value, c.ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "redisCache.Set error")
+	}
+
+	return nil
+}
+
+func (c *redisCache) Add(key string, value []byte) (bool, error) {
+	added, err := c.client.SetNX(context.Background(), c.prefix+key, value, c.ttl).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "redisCache.Add error")
+	}
+
+	return added, nil
+}
+
+func (c *redisCache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+
+	if err := c.client.Del(context.Background(), prefixed...).Err(); err != nil {
+		return errors.Wrap(err, "redisCache.Delete error")
+	}
+
+	return nil
+}
+
+func (c *redisCache) Clear() error {
+	ctx := context.Background()
+	iter := c.client.Scan(ctx, 0, c.prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := c.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return errors.Wrap(err, "redisCache.Clear error")
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return errors.Wrap(err, "redisCache.Clear error")
+	}
+
+	return nil
+}
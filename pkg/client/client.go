@@ -0,0 +1,213 @@
+// Package client is a Go client of the REST API of the wallets service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultMaxRetries = 3
+	defaultBackoff    = 200 * time.Millisecond
+	maxBackoff        = 10 * time.Second
+)
+
+// Client calls the API at its base URL. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	token      string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+type Option func(*Client)
+
+// WithToken sends the session token in the Authorization header.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient replaces the default client with a 30 seconds timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed call is retried and the pause
+// before the first retry, every next pause is doubled. 0 retries disables them.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client of the API at baseURL, such as http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, errors.Wrap(err, "client.New error")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("client.New error: base URL %q is not http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// call is a request of the API and where its response goes.
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   interface{}
+	// out receives the decoded JSON response, nil when the body is ignored.
+	out interface{}
+}
+
+// do sends c and retries it on network errors, on 429, 502, 503 and 504
+// responses and on 409 with Retry-After, the service is still running the
+// request with the same Idempotency-Key. Changing requests carry an
+// Idempotency-Key kept over the retries, so the service applies them once.
+func (c *Client) do(ctx context.Context, cl call) error {
+	var body []byte
+	if cl.body != nil {
+		var err error
+		body, err = json.Marshal(cl.body)
+		if err != nil {
+			return errors.Wrap(err, "can`t marshal request")
+		}
+	}
+
+	u := c.baseURL.JoinPath(cl.path)
+	u.RawQuery = cl.query.Encode()
+
+	header := http.Header{}
+	for name, values := range cl.header {
+		header[name] = values
+	}
+	header.Set("Accept", "application/json, application/problem+json")
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		header.Set("Authorization", c.token)
+	}
+	if cl.method != http.MethodGet && header.Get("Idempotency-Key") == "" {
+		header.Set("Idempotency-Key", uuid.NewString())
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, cl.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return errors.Wrap(err, "can`t make request")
+		}
+		req.Header = header.Clone()
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && !retryable(resp) {
+			return c.decode(resp, cl.out)
+		}
+
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			if err != nil {
+				return errors.Wrapf(err, "%s %s", cl.method, cl.path)
+			}
+			return c.decode(resp, cl.out)
+		}
+
+		pause := backoff / 2
+		if backoff > 0 {
+			pause += rand.N(backoff)
+		}
+		if err == nil {
+			if after := retryAfter(resp); after > 0 {
+				pause = after
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "%s %s", cl.method, cl.path)
+		case <-time.After(pause):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// decode reads a successful response into out and turns the others into *Error.
+func (c *Client) decode(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "can`t read response")
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newError(resp, data)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if err = json.Unmarshal(data, out); err != nil {
+		return errors.Wrap(err, "can`t unmarshal response")
+	}
+
+	return nil
+}
+
+func retryable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// Conflicts of the wallets are final, only the one of a request in
+		// progress comes with Retry-After.
+		return resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// retryAfter reads the Retry-After seconds, 0 when there are none.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+
+	return min(time.Duration(seconds)*time.Second, maxBackoff)
+}
@@ -0,0 +1,363 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/internal/memstore"
+	transactionDel "github.com/Davmie/javaCode/internal/transaction/delivery"
+	memTransaction "github.com/Davmie/javaCode/internal/transaction/repository/memory"
+	transactionUseCase "github.com/Davmie/javaCode/internal/transaction/usecase"
+	walletDel "github.com/Davmie/javaCode/internal/wallet/delivery"
+	memWallet "github.com/Davmie/javaCode/internal/wallet/repository/memory"
+	walletUseCase "github.com/Davmie/javaCode/internal/wallet/usecase"
+	"github.com/Davmie/javaCode/pkg/cache"
+	ctxManager "github.com/Davmie/javaCode/pkg/context"
+	"github.com/Davmie/javaCode/pkg/middleware"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	testUserID  = 7
+	testAdminID = 1
+)
+
+type ClientTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	// fault, when set, answers the requests before the service does.
+	fault    func(w http.ResponseWriter, r *http.Request, service http.Handler) bool
+	attempts atomic.Int32
+	user     *Client
+	admin    *Client
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.RunSuite(t, new(ClientTestSuite))
+}
+
+func (s *ClientTestSuite) BeforeEach(t provider.T) {
+	logger := zap.NewNop().Sugar()
+	store := memstore.New()
+
+	walletHandler := walletDel.WalletHandler{
+		WalletUseCase:  walletUseCase.New(memWallet.New(logger, store)),
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+	transactionHandler := transactionDel.TransactionHandler{
+		TransactionUseCase: transactionUseCase.New(memTransaction.New(logger, store)),
+		Logger:             logger,
+	}
+	authManager := middleware.AuthManager{
+		SessionManager: session.JWTSessionsManager{},
+		Logger:         logger,
+		ContextManager: ctxManager.Manager{},
+	}
+
+	r := http.NewServeMux()
+	r.Handle("POST /api/v1/wallets", authManager.OptionalAuth(http.HandlerFunc(walletHandler.Create)))
+	r.Handle("GET /api/v1/wallets", authManager.OptionalAuth(http.HandlerFunc(walletHandler.GetAll)))
+	r.Handle("GET /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.GetByUID))
+	r.Handle("PATCH /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.Update))
+	r.Handle("DELETE /api/v1/wallets/{walletId}", http.HandlerFunc(walletHandler.Delete))
+	r.Handle("POST /api/v1/wallet", http.HandlerFunc(walletHandler.ChangeAmount))
+	r.Handle("POST /api/v1/wallets/{WALLET_UUID}/adjustments", authManager.Auth(http.HandlerFunc(walletHandler.Adjust), session.AdminRole))
+	r.Handle("POST /api/v1/wallets/{WALLET_UUID}/status", authManager.Auth(http.HandlerFunc(walletHandler.ChangeStatus), session.AdminRole))
+	r.Handle("GET /api/v1/wallets/{WALLET_UUID}/transactions", http.HandlerFunc(transactionHandler.GetByWalletUID))
+	r.Handle("GET /api/v1/transactions/{TRANSACTION_UUID}", http.HandlerFunc(transactionHandler.GetByUID))
+
+	service := middleware.Problems(middleware.Idempotency(logger, cache.NewLRU(100, time.Hour), r))
+
+	s.fault = nil
+	s.attempts.Store(0)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.attempts.Add(1)
+		if s.fault != nil && s.fault(w, r, service) {
+			return
+		}
+		service.ServeHTTP(w, r)
+	}))
+
+	var err error
+	s.user, err = New(s.server.URL, WithToken(s.token(t, testUserID, "user")), WithRetries(3, time.Millisecond))
+	t.Require().NoError(err)
+	s.admin, err = New(s.server.URL, WithToken(s.token(t, testAdminID, session.AdminRole)), WithRetries(3, time.Millisecond))
+	t.Require().NoError(err)
+}
+
+func (s *ClientTestSuite) AfterEach(t provider.T) {
+	s.server.Close()
+}
+
+func (s *ClientTestSuite) token(t provider.T, userID int, role string) string {
+	token, err := session.JWTSessionsManager{}.CreateSession(userID, role)
+	t.Require().NoError(err)
+
+	return token
+}
+
+func (s *ClientTestSuite) TestWallets(t provider.T) {
+	ctx := context.Background()
+
+	created, err := s.user.CreateWallet(ctx, CreateWalletRequest{Name: "savings"})
+	t.Require().NoError(err)
+	t.Assert().NotEmpty(created.UID)
+	t.Assert().Equal("savings", created.Name)
+	t.Assert().Equal(WalletStatusActive, created.Status)
+	t.Assert().Equal(testUserID, created.OwnerID)
+
+	updated, err := s.user.UpdateWallet(ctx, created.ID, created.Version, "checking")
+	t.Require().NoError(err)
+	t.Assert().Equal("checking", updated.Name)
+
+	wallets, err := s.user.ListWallets(ctx, false)
+	t.Require().NoError(err)
+	t.Require().Len(wallets, 1)
+	t.Assert().Equal(created.UID, wallets[0].UID)
+
+	_, err = s.user.UpdateWallet(ctx, created.ID, created.Version, "stale")
+	t.Assert().ErrorIs(err, ErrPreconditionFailed)
+
+	got, err := s.user.GetWallet(ctx, created.UID)
+	t.Require().NoError(err)
+	t.Require().NoError(s.user.DeleteWallet(ctx, got.ID, got.Version))
+
+	_, err = s.user.GetWallet(ctx, created.UID)
+	t.Assert().ErrorIs(err, ErrNotFound)
+
+	_, err = s.user.ListWallets(ctx, true)
+	t.Assert().ErrorIs(err, ErrForbidden)
+
+	wallets, err = s.admin.ListWallets(ctx, true)
+	t.Require().NoError(err)
+	t.Require().Len(wallets, 1)
+	t.Assert().NotNil(wallets[0].DeletedAt)
+}
+
+func (s *ClientTestSuite) TestChangeAmountAndHistory(t provider.T) {
+	ctx := context.Background()
+
+	w, err := s.user.CreateWallet(ctx, CreateWalletRequest{})
+	t.Require().NoError(err)
+
+	deposit, err := s.user.Deposit(ctx, w.UID, 100)
+	t.Require().NoError(err)
+	t.Assert().Equal(100, deposit.Amount)
+	t.Assert().Equal(OperationDeposit, deposit.Type)
+
+	withdrawal, err := s.user.Withdraw(ctx, w.UID, 30)
+	t.Require().NoError(err)
+	t.Assert().Equal(-30, withdrawal.Amount)
+
+	adjustment, err := s.admin.Adjust(ctx, w.UID, AdjustRequest{Amount: 5, ReasonCode: "GOODWILL", Comment: "sorry"})
+	t.Require().NoError(err)
+	t.Assert().Equal(5, adjustment.Amount)
+
+	history, err := s.user.GetHistory(ctx, w.UID)
+	t.Require().NoError(err)
+	t.Require().Len(history, 3)
+	t.Assert().Equal(deposit.UID, history[0].UID)
+	t.Assert().Equal(withdrawal.UID, history[1].UID)
+	t.Assert().Equal(adjustment.UID, history[2].UID)
+
+	got, err := s.user.GetTransaction(ctx, withdrawal.UID)
+	t.Require().NoError(err)
+	t.Assert().Equal(w.UID, got.WalletUID)
+
+	w, err = s.user.GetWallet(ctx, w.UID)
+	t.Require().NoError(err)
+	t.Assert().Equal(75, w.Amount)
+}
+
+func (s *ClientTestSuite) TestErrors(t provider.T) {
+	ctx := context.Background()
+
+	w, err := s.user.CreateWallet(ctx, CreateWalletRequest{})
+	t.Require().NoError(err)
+
+	_, err = s.user.Withdraw(ctx, w.UID, 10)
+	t.Assert().ErrorIs(err, ErrInsufficientFunds)
+	t.Assert().ErrorIs(err, ErrConflict)
+
+	var apiErr *Error
+	t.Require().True(errors.As(err, &apiErr))
+	t.Assert().Equal(http.StatusConflict, apiErr.StatusCode)
+	t.Assert().Equal("insufficient funds", apiErr.Detail)
+	t.Assert().Equal("/api/v1/wallet", apiErr.Instance)
+
+	_, err = s.admin.ChangeStatus(ctx, w.UID, WalletStatusFrozen, "fraud check")
+	t.Require().NoError(err)
+
+	_, err = s.user.Deposit(ctx, w.UID, 10)
+	t.Assert().ErrorIs(err, ErrWalletFrozen)
+	t.Assert().False(errors.Is(err, ErrInsufficientFunds))
+
+	_, err = s.user.ChangeStatus(ctx, w.UID, WalletStatusActive, "")
+	t.Assert().ErrorIs(err, ErrForbidden)
+
+	_, err = s.user.Deposit(ctx, w.UID, 0)
+	t.Assert().ErrorIs(err, ErrBadRequest)
+}
+
+func (s *ClientTestSuite) TestPlainTextError(t provider.T) {
+	s.fault = func(w http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		http.Error(w, "gone fishing", http.StatusTeapot)
+		return true
+	}
+
+	_, err := s.user.GetWallet(context.Background(), "uid")
+
+	var apiErr *Error
+	t.Require().True(errors.As(err, &apiErr))
+	t.Assert().Equal(http.StatusTeapot, apiErr.StatusCode)
+	t.Assert().Equal("gone fishing", apiErr.Detail)
+	t.Assert().Equal("418 I'm a teapot: gone fishing", apiErr.Error())
+}
+
+func (s *ClientTestSuite) TestRetryLostResponse(t provider.T) {
+	ctx := context.Background()
+
+	w, err := s.user.CreateWallet(ctx, CreateWalletRequest{})
+	t.Require().NoError(err)
+
+	// The first deposit is applied, but its response never reaches the client.
+	var keys []string
+	s.attempts.Store(0)
+	s.fault = func(rw http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if s.attempts.Load() > 1 {
+			return false
+		}
+
+		service.ServeHTTP(httptest.NewRecorder(), r)
+		rw.WriteHeader(http.StatusBadGateway)
+		return true
+	}
+
+	deposit, err := s.user.Deposit(ctx, w.UID, 100)
+	t.Require().NoError(err)
+	t.Assert().Equal(100, deposit.Amount)
+	t.Assert().EqualValues(2, s.attempts.Load())
+	t.Require().Len(keys, 2)
+	t.Assert().NotEmpty(keys[0])
+	t.Assert().Equal(keys[0], keys[1])
+
+	s.fault = nil
+	history, err := s.user.GetHistory(ctx, w.UID)
+	t.Require().NoError(err)
+	t.Require().Len(history, 1)
+	t.Assert().Equal(deposit.UID, history[0].UID)
+
+	// Every call gets its own key.
+	_, err = s.user.Deposit(ctx, w.UID, 100)
+	t.Require().NoError(err)
+
+	history, err = s.user.GetHistory(ctx, w.UID)
+	t.Require().NoError(err)
+	t.Assert().Len(history, 2)
+}
+
+func (s *ClientTestSuite) TestRetryUnavailable(t provider.T) {
+	s.fault = func(w http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		if s.attempts.Load() > 2 {
+			return false
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	}
+
+	wallets, err := s.user.ListWallets(context.Background(), false)
+	t.Require().NoError(err)
+	t.Assert().Empty(wallets)
+	t.Assert().EqualValues(3, s.attempts.Load())
+}
+
+func (s *ClientTestSuite) TestRetryInProgress(t provider.T) {
+	ctx := context.Background()
+
+	w, err := s.user.CreateWallet(ctx, CreateWalletRequest{})
+	t.Require().NoError(err)
+
+	// The first attempt finds the key taken by a request still running, as the
+	// service answers then.
+	s.attempts.Store(0)
+	s.fault = func(rw http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		if s.attempts.Load() > 1 {
+			return false
+		}
+
+		rw.Header().Set("Retry-After", "1")
+		http.Error(rw, "request with this Idempotency-Key is in progress", http.StatusConflict)
+		return true
+	}
+
+	deposit, err := s.user.Deposit(ctx, w.UID, 100)
+	t.Require().NoError(err)
+	t.Assert().Equal(100, deposit.Amount)
+	t.Assert().EqualValues(2, s.attempts.Load())
+
+	// Other conflicts are not retried.
+	s.fault = nil
+	s.attempts.Store(0)
+	_, err = s.user.Withdraw(ctx, w.UID, 1000)
+	t.Assert().ErrorIs(err, ErrInsufficientFunds)
+	t.Assert().EqualValues(1, s.attempts.Load())
+}
+
+func (s *ClientTestSuite) TestRetriesExhausted(t provider.T) {
+	s.fault = func(w http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	}
+
+	_, err := s.user.ListWallets(context.Background(), false)
+
+	var apiErr *Error
+	t.Require().True(errors.As(err, &apiErr))
+	t.Assert().Equal(http.StatusServiceUnavailable, apiErr.StatusCode)
+	t.Assert().EqualValues(4, s.attempts.Load())
+}
+
+func (s *ClientTestSuite) TestNoRetryOnClientErrors(t provider.T) {
+	_, err := s.user.GetWallet(context.Background(), "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")
+	t.Assert().ErrorIs(err, ErrNotFound)
+	t.Assert().EqualValues(1, s.attempts.Load())
+}
+
+func (s *ClientTestSuite) TestContextCanceled(t provider.T) {
+	s.fault = func(w http.ResponseWriter, r *http.Request, service http.Handler) bool {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return true
+	}
+
+	c, err := New(s.server.URL, WithRetries(10, time.Hour))
+	t.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = c.ListWallets(ctx, false)
+	t.Assert().ErrorIs(err, context.DeadlineExceeded)
+	t.Assert().Less(time.Since(start), 5*time.Second)
+	t.Assert().EqualValues(1, s.attempts.Load())
+}
+
+func (s *ClientTestSuite) TestNew(t provider.T) {
+	_, err := New("localhost:8080")
+	t.Assert().Error(err)
+
+	c, err := New("http://localhost:8080/")
+	t.Require().NoError(err)
+	t.Assert().Equal("http://localhost:8080", c.baseURL.String())
+}
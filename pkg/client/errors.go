@@ -0,0 +1,79 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Errors matched by errors.Is against the *Error of a failed call.
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotFound           = errors.New("not found")
+	ErrConflict           = errors.New("conflict")
+	ErrPreconditionFailed = errors.New("wallet was changed")
+
+	// The conflicts below are told apart by the message of the service.
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrWalletFrozen      = errors.New("wallet is frozen")
+	ErrWalletClosed      = errors.New("wallet is closed")
+)
+
+// Error is a response of the API with an error status. It is decoded from
+// problem details, Detail is the message of the service.
+type Error struct {
+	StatusCode int    `json:"status"`
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+	Instance   string `json:"instance"`
+}
+
+func newError(resp *http.Response, body []byte) *Error {
+	e := &Error{}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/problem+json") || json.Unmarshal(body, e) != nil {
+		// Proxies and older versions of the service answer with plain text.
+		e = &Error{Detail: strings.TrimSpace(string(body))}
+	}
+
+	e.StatusCode = resp.StatusCode
+	if e.Title == "" {
+		e.Title = http.StatusText(resp.StatusCode)
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Title)
+	}
+
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Title, e.Detail)
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrInsufficientFunds, ErrWalletFrozen, ErrWalletClosed:
+		return e.StatusCode == http.StatusConflict && e.Detail == target.Error()
+	default:
+		return false
+	}
+}
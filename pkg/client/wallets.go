@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	OperationDeposit  = "DEPOSIT"
+	OperationWithdraw = "WITHDRAW"
+)
+
+const (
+	WalletStatusActive = "ACTIVE"
+	WalletStatusFrozen = "FROZEN"
+	WalletStatusClosed = "CLOSED"
+)
+
+type Wallet struct {
+	ID        int        `json:"id"`
+	UID       string     `json:"uid"`
+	Name      string     `json:"name"`
+	Amount    int        `json:"amount"`
+	Held      int        `json:"held"`
+	Available int        `json:"available"`
+	Status    string     `json:"status"`
+	Version   int        `json:"version"`
+	Shards    int        `json:"shards"`
+	OwnerID   int        `json:"ownerId"`
+	DeletedAt *time.Time `json:"deletedAt"`
+}
+
+// Transaction is a ledger entry, Amount is the signed change of the balance.
+type Transaction struct {
+	ID         int       `json:"id"`
+	UID        string    `json:"uid"`
+	WalletUID  string    `json:"walletId"`
+	Type       string    `json:"operationType"`
+	Amount     int       `json:"amount"`
+	Reversed   int       `json:"reversed"`
+	ReversalOf *string   `json:"reversalOf,omitempty"`
+	ReasonCode string    `json:"reasonCode,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	ActorID    *int      `json:"actorId,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type CreateWalletRequest struct {
+	// UID of the new wallet, generated when empty.
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+type ChangeAmountRequest struct {
+	WalletUID     string `json:"walletId"`
+	OperationType string `json:"operationType"`
+	// Amount is positive, OperationType gives the direction.
+	Amount int `json:"amount"`
+}
+
+type AdjustRequest struct {
+	// Amount is signed, a negative one takes money out.
+	Amount     int    `json:"amount"`
+	ReasonCode string `json:"reasonCode"`
+	Comment    string `json:"comment"`
+}
+
+type changeStatusRequest struct {
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type updateWalletRequest struct {
+	Name string `json:"name"`
+}
+
+// ifMatch is the If-Match header expecting the wallet version.
+func ifMatch(version int) http.Header {
+	return http.Header{"If-Match": {`"` + strconv.Itoa(version) + `"`}}
+}
+
+// CreateWallet creates a wallet owned by the user of the token and returns it.
+func (c *Client) CreateWallet(ctx context.Context, req CreateWalletRequest) (*Wallet, error) {
+	if req.UID == "" {
+		req.UID = uuid.NewString()
+	}
+
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/wallets", body: req})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.CreateWallet error")
+	}
+
+	w, err := c.GetWallet(ctx, req.UID)
+	if err != nil {
+		return nil, errors.Wrap(err, "client.CreateWallet error")
+	}
+
+	return w, nil
+}
+
+func (c *Client) GetWallet(ctx context.Context, uid string) (*Wallet, error) {
+	var w Wallet
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/wallets/" + url.PathEscape(uid), out: &w})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.GetWallet error")
+	}
+
+	return &w, nil
+}
+
+// ListWallets returns every wallet, includeDeleted needs an admin token.
+func (c *Client) ListWallets(ctx context.Context, includeDeleted bool) ([]*Wallet, error) {
+	query := url.Values{}
+	if includeDeleted {
+		query.Set("include_deleted", "true")
+	}
+
+	var wallets []*Wallet
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/wallets", query: query, out: &wallets})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.ListWallets error")
+	}
+
+	return wallets, nil
+}
+
+// UpdateWallet renames the wallet with the given numeric id. It fails with
+// ErrPreconditionFailed when the wallet is no longer at version.
+func (c *Client) UpdateWallet(ctx context.Context, id int, version int, name string) (*Wallet, error) {
+	var w Wallet
+	err := c.do(ctx, call{
+		method: http.MethodPatch,
+		path:   "/api/v1/wallets/" + strconv.Itoa(id),
+		header: ifMatch(version),
+		body:   updateWalletRequest{Name: name},
+		out:    &w,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.UpdateWallet error")
+	}
+
+	return &w, nil
+}
+
+// DeleteWallet soft deletes the wallet with the given numeric id at version.
+func (c *Client) DeleteWallet(ctx context.Context, id int, version int) error {
+	err := c.do(ctx, call{method: http.MethodDelete, path: "/api/v1/wallets/" + strconv.Itoa(id), header: ifMatch(version)})
+	if err != nil {
+		return errors.Wrap(err, "client.DeleteWallet error")
+	}
+
+	return nil
+}
+
+// ChangeAmount deposits to or withdraws from the wallet and returns the ledger
+// entry of the change.
+func (c *Client) ChangeAmount(ctx context.Context, req ChangeAmountRequest) (*Transaction, error) {
+	var t Transaction
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/wallet", body: req, out: &t})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.ChangeAmount error")
+	}
+
+	return &t, nil
+}
+
+func (c *Client) Deposit(ctx context.Context, walletUID string, amount int) (*Transaction, error) {
+	return c.ChangeAmount(ctx, ChangeAmountRequest{WalletUID: walletUID, OperationType: OperationDeposit, Amount: amount})
+}
+
+func (c *Client) Withdraw(ctx context.Context, walletUID string, amount int) (*Transaction, error) {
+	return c.ChangeAmount(ctx, ChangeAmountRequest{WalletUID: walletUID, OperationType: OperationWithdraw, Amount: amount})
+}
+
+// Adjust changes the balance by hand, it needs an admin token.
+func (c *Client) Adjust(ctx context.Context, walletUID string, req AdjustRequest) (*Transaction, error) {
+	var t Transaction
+	err := c.do(ctx, call{method: http.MethodPost, path: "/api/v1/wallets/" + url.PathEscape(walletUID) + "/adjustments", body: req, out: &t})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.Adjust error")
+	}
+
+	return &t, nil
+}
+
+// ChangeStatus freezes, unfreezes or closes the wallet, it needs an admin token.
+func (c *Client) ChangeStatus(ctx context.Context, walletUID string, status string, reason string) (*Wallet, error) {
+	var w Wallet
+	err := c.do(ctx, call{
+		method: http.MethodPost,
+		path:   "/api/v1/wallets/" + url.PathEscape(walletUID) + "/status",
+		body:   changeStatusRequest{Status: status, Reason: reason},
+		out:    &w,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.ChangeStatus error")
+	}
+
+	return &w, nil
+}
+
+// GetHistory returns the ledger of the wallet, oldest entries first.
+func (c *Client) GetHistory(ctx context.Context, walletUID string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/wallets/" + url.PathEscape(walletUID) + "/transactions", out: &transactions})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.GetHistory error")
+	}
+
+	return transactions, nil
+}
+
+func (c *Client) GetTransaction(ctx context.Context, uid string) (*Transaction, error) {
+	var t Transaction
+	err := c.do(ctx, call{method: http.MethodGet, path: "/api/v1/transactions/" + url.PathEscape(uid), out: &t})
+	if err != nil {
+		return nil, errors.Wrap(err, "client.GetTransaction error")
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,435 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+const (
+	CacheNone  = "none"
+	CacheLRU   = "lru"
+	CacheRedis = "redis"
+)
+
+const (
+	EventsLog   = "log"
+	EventsKafka = "kafka"
+	EventsNATS  = "nats"
+	EventsFile  = "file"
+)
+
+const (
+	defaultStorage         = StoragePostgres
+	defaultPostgresDSN     = "host=postgres user=program password=test dbname=wallets port=5432"
+	defaultSQLitePath      = "wallets.db"
+	defaultWalletRetention = 30 * 24 * time.Hour
+	defaultCache           = CacheNone
+	defaultCacheTTL        = 5 * time.Second
+	defaultCacheSize       = 10000
+	defaultRedisAddr       = "redis:6379"
+	defaultReadYourWrites  = 5 * time.Second
+	defaultReplicaCheck    = 5 * time.Second
+	defaultDBMaxOpenConns  = 20
+	defaultDBMaxIdleConns  = 10
+	defaultDBConnLifetime  = 30 * time.Minute
+	defaultDBConnIdleTime  = 5 * time.Minute
+	defaultDBStatement     = 5 * time.Second
+	defaultDBLock          = 2 * time.Second
+	defaultDBConnect       = time.Minute
+	defaultBatchSize       = 100
+	defaultOutboxInterval  = time.Second
+	defaultOutboxBatch     = 100
+	defaultEvents          = EventsLog
+	defaultKafkaBroker     = "kafka:9092"
+	defaultKafkaTopic      = "wallet-events"
+	defaultNATSURL         = "nats://nats:4222"
+	defaultNATSStream      = "WALLET_EVENTS"
+	defaultNATSSubject     = "wallets.events"
+	defaultEventsFile      = "events.ndjson"
+	defaultWebhookInterval = time.Second
+	defaultWebhookTimeout  = 10 * time.Second
+	defaultWebhookAttempts = 8
+	defaultWebhookBackoff  = 30 * time.Second
+	defaultStreamHeartbeat = 15 * time.Second
+	defaultGRPCAddr        = ":9090"
+	defaultIdempotencyTTL  = 24 * time.Hour
+	defaultIdempotencySize = 100000
+)
+
+// Config of the service. Every field can be overridden with an environment
+// variable, defaults match docker-compose.yml.
+type Config struct {
+	Storage         string        // WALLETS_STORAGE, one of postgres, sqlite or memory
+	PostgresDSN     string        // WALLETS_POSTGRES_DSN
+	SQLitePath      string        // WALLETS_SQLITE_PATH
+	WalletRetention time.Duration // WALLETS_RETENTION, how long soft deleted wallets are kept
+	Cache           string        // WALLETS_CACHE, wallet lookup cache: none, lru or redis
+	CacheTTL        time.Duration // WALLETS_CACHE_TTL
+	CacheSize       int           // WALLETS_CACHE_SIZE, max wallets kept by the lru cache
+	RedisAddr       string        // WALLETS_REDIS_ADDR
+	ReplicaDSNs     []string      // WALLETS_REPLICA_DSNS, comma separated, reads go to the primary when empty
+	ReadYourWrites  time.Duration // WALLETS_READ_YOUR_WRITES, how long a client reads from the primary after a write
+	ReplicaCheck    time.Duration // WALLETS_REPLICA_CHECK, interval of the replica health checks
+
+	// Postgres pool of the primary and of every replica, zero durations disable the limit.
+	DBMaxOpenConns     int           // WALLETS_DB_MAX_OPEN_CONNS
+	DBMaxIdleConns     int           // WALLETS_DB_MAX_IDLE_CONNS
+	DBConnMaxLifetime  time.Duration // WALLETS_DB_CONN_MAX_LIFETIME
+	DBConnMaxIdleTime  time.Duration // WALLETS_DB_CONN_MAX_IDLE_TIME
+	DBStatementTimeout time.Duration // WALLETS_DB_STATEMENT_TIMEOUT, Postgres statement_timeout
+	DBLockTimeout      time.Duration // WALLETS_DB_LOCK_TIMEOUT, Postgres lock_timeout
+	DBConnectTimeout   time.Duration // WALLETS_DB_CONNECT_TIMEOUT, how long the primary is waited for at startup
+
+	BatchChanges bool // WALLETS_BATCH_CHANGES, apply concurrent balance changes of a wallet in batches
+	BatchSize    int  // WALLETS_BATCH_SIZE, max balance changes per batch
+
+	OutboxInterval time.Duration // WALLETS_OUTBOX_INTERVAL, how often the outbox relay looks for new events
+	OutboxBatch    int           // WALLETS_OUTBOX_BATCH, max events published per outbox transaction
+
+	Events       string   // WALLETS_EVENTS, where outbox events are published: log, kafka, nats or file
+	KafkaBrokers []string // WALLETS_KAFKA_BROKERS, comma separated
+	KafkaTopic   string   // WALLETS_KAFKA_TOPIC
+	NATSURL      string   // WALLETS_NATS_URL
+	NATSStream   string   // WALLETS_NATS_STREAM, JetStream stream keeping the events
+	NATSSubject  string   // WALLETS_NATS_SUBJECT, prefix of the event subjects
+	EventsFile   string   // WALLETS_EVENTS_FILE, NDJSON file the events are appended to
+
+	WebhookInterval    time.Duration // WALLETS_WEBHOOK_INTERVAL, how often due webhook deliveries are sent
+	WebhookTimeout     time.Duration // WALLETS_WEBHOOK_TIMEOUT, timeout of a webhook request
+	WebhookMaxAttempts int           // WALLETS_WEBHOOK_MAX_ATTEMPTS, attempts before a delivery is dead
+	WebhookBackoff     time.Duration // WALLETS_WEBHOOK_BACKOFF, pause after the first failed attempt, doubled after each next one
+
+	StreamHeartbeat time.Duration // WALLETS_STREAM_HEARTBEAT, longest silence of a wallet event stream
+
+	GRPCAddr string // WALLETS_GRPC_ADDR, address of the gRPC server, empty disables it
+
+	IdempotencyTTL  time.Duration // WALLETS_IDEMPOTENCY_TTL, how long responses to requests with an Idempotency-Key are replayed
+	IdempotencySize int           // WALLETS_IDEMPOTENCY_SIZE, max responses kept without Redis
+}
+
+func Load() (*Config, error) {
+	cfg := &Config{
+		Storage:         defaultStorage,
+		PostgresDSN:     defaultPostgresDSN,
+		SQLitePath:      defaultSQLitePath,
+		WalletRetention: defaultWalletRetention,
+		Cache:           defaultCache,
+		CacheTTL:        defaultCacheTTL,
+		CacheSize:       defaultCacheSize,
+		RedisAddr:       defaultRedisAddr,
+		ReadYourWrites:  defaultReadYourWrites,
+		ReplicaCheck:    defaultReplicaCheck,
+
+		DBMaxOpenConns:     defaultDBMaxOpenConns,
+		DBMaxIdleConns:     defaultDBMaxIdleConns,
+		DBConnMaxLifetime:  defaultDBConnLifetime,
+		DBConnMaxIdleTime:  defaultDBConnIdleTime,
+		DBStatementTimeout: defaultDBStatement,
+		DBLockTimeout:      defaultDBLock,
+		DBConnectTimeout:   defaultDBConnect,
+
+		BatchSize: defaultBatchSize,
+
+		OutboxInterval: defaultOutboxInterval,
+		OutboxBatch:    defaultOutboxBatch,
+
+		Events:       defaultEvents,
+		KafkaBrokers: []string{defaultKafkaBroker},
+		KafkaTopic:   defaultKafkaTopic,
+		NATSURL:      defaultNATSURL,
+		NATSStream:   defaultNATSStream,
+		NATSSubject:  defaultNATSSubject,
+		EventsFile:   defaultEventsFile,
+
+		WebhookInterval:    defaultWebhookInterval,
+		WebhookTimeout:     defaultWebhookTimeout,
+		WebhookMaxAttempts: defaultWebhookAttempts,
+		WebhookBackoff:     defaultWebhookBackoff,
+
+		StreamHeartbeat: defaultStreamHeartbeat,
+
+		GRPCAddr: defaultGRPCAddr,
+
+		IdempotencyTTL:  defaultIdempotencyTTL,
+		IdempotencySize: defaultIdempotencySize,
+	}
+
+	if storage := os.Getenv("WALLETS_STORAGE"); storage != "" {
+		cfg.Storage = storage
+	}
+
+	if dsn := os.Getenv("WALLETS_POSTGRES_DSN"); dsn != "" {
+		cfg.PostgresDSN = dsn
+	}
+
+	if path := os.Getenv("WALLETS_SQLITE_PATH"); path != "" {
+		cfg.SQLitePath = path
+	}
+
+	if retention := os.Getenv("WALLETS_RETENTION"); retention != "" {
+		d, err := time.ParseDuration(retention)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_RETENTION")
+		}
+		cfg.WalletRetention = d
+	}
+
+	if cache := os.Getenv("WALLETS_CACHE"); cache != "" {
+		cfg.Cache = cache
+	}
+
+	if ttl := os.Getenv("WALLETS_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_CACHE_TTL")
+		}
+		cfg.CacheTTL = d
+	}
+
+	if size := os.Getenv("WALLETS_CACHE_SIZE"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_CACHE_SIZE")
+		}
+		cfg.CacheSize = n
+	}
+
+	if addr := os.Getenv("WALLETS_REDIS_ADDR"); addr != "" {
+		cfg.RedisAddr = addr
+	}
+
+	if dsns := os.Getenv("WALLETS_REPLICA_DSNS"); dsns != "" {
+		for _, dsn := range strings.Split(dsns, ",") {
+			if dsn = strings.TrimSpace(dsn); dsn != "" {
+				cfg.ReplicaDSNs = append(cfg.ReplicaDSNs, dsn)
+			}
+		}
+	}
+
+	if window := os.Getenv("WALLETS_READ_YOUR_WRITES"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_READ_YOUR_WRITES")
+		}
+		cfg.ReadYourWrites = d
+	}
+
+	if interval := os.Getenv("WALLETS_REPLICA_CHECK"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_REPLICA_CHECK")
+		}
+		if d <= 0 {
+			return nil, errors.New("config.Load error: WALLETS_REPLICA_CHECK must be positive")
+		}
+		cfg.ReplicaCheck = d
+	}
+
+	if n := os.Getenv("WALLETS_DB_MAX_OPEN_CONNS"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_MAX_OPEN_CONNS")
+		}
+		cfg.DBMaxOpenConns = v
+	}
+
+	if n := os.Getenv("WALLETS_DB_MAX_IDLE_CONNS"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_MAX_IDLE_CONNS")
+		}
+		cfg.DBMaxIdleConns = v
+	}
+
+	if d := os.Getenv("WALLETS_DB_CONN_MAX_LIFETIME"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_CONN_MAX_LIFETIME")
+		}
+		cfg.DBConnMaxLifetime = v
+	}
+
+	if d := os.Getenv("WALLETS_DB_CONN_MAX_IDLE_TIME"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_CONN_MAX_IDLE_TIME")
+		}
+		cfg.DBConnMaxIdleTime = v
+	}
+
+	if d := os.Getenv("WALLETS_DB_STATEMENT_TIMEOUT"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_STATEMENT_TIMEOUT")
+		}
+		cfg.DBStatementTimeout = v
+	}
+
+	if d := os.Getenv("WALLETS_DB_LOCK_TIMEOUT"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_LOCK_TIMEOUT")
+		}
+		cfg.DBLockTimeout = v
+	}
+
+	if d := os.Getenv("WALLETS_DB_CONNECT_TIMEOUT"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_DB_CONNECT_TIMEOUT")
+		}
+		cfg.DBConnectTimeout = v
+	}
+
+	if batch := os.Getenv("WALLETS_BATCH_CHANGES"); batch != "" {
+		v, err := strconv.ParseBool(batch)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_BATCH_CHANGES")
+		}
+		cfg.BatchChanges = v
+	}
+
+	if size := os.Getenv("WALLETS_BATCH_SIZE"); size != "" {
+		v, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_BATCH_SIZE")
+		}
+		if v < 1 {
+			return nil, errors.New("config.Load error: WALLETS_BATCH_SIZE must be at least 1")
+		}
+		cfg.BatchSize = v
+	}
+
+	if d := os.Getenv("WALLETS_OUTBOX_INTERVAL"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_OUTBOX_INTERVAL")
+		}
+		if v <= 0 {
+			return nil, errors.New("config.Load error: WALLETS_OUTBOX_INTERVAL must be positive")
+		}
+		cfg.OutboxInterval = v
+	}
+
+	if size := os.Getenv("WALLETS_OUTBOX_BATCH"); size != "" {
+		v, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_OUTBOX_BATCH")
+		}
+		if v < 1 {
+			return nil, errors.New("config.Load error: WALLETS_OUTBOX_BATCH must be at least 1")
+		}
+		cfg.OutboxBatch = v
+	}
+
+	if events := os.Getenv("WALLETS_EVENTS"); events != "" {
+		cfg.Events = events
+	}
+
+	if brokers := os.Getenv("WALLETS_KAFKA_BROKERS"); brokers != "" {
+		cfg.KafkaBrokers = nil
+		for _, broker := range strings.Split(brokers, ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				cfg.KafkaBrokers = append(cfg.KafkaBrokers, broker)
+			}
+		}
+	}
+
+	if topic := os.Getenv("WALLETS_KAFKA_TOPIC"); topic != "" {
+		cfg.KafkaTopic = topic
+	}
+
+	if url := os.Getenv("WALLETS_NATS_URL"); url != "" {
+		cfg.NATSURL = url
+	}
+
+	if stream := os.Getenv("WALLETS_NATS_STREAM"); stream != "" {
+		cfg.NATSStream = stream
+	}
+
+	if subject := os.Getenv("WALLETS_NATS_SUBJECT"); subject != "" {
+		cfg.NATSSubject = subject
+	}
+
+	if path := os.Getenv("WALLETS_EVENTS_FILE"); path != "" {
+		cfg.EventsFile = path
+	}
+
+	if d := os.Getenv("WALLETS_WEBHOOK_INTERVAL"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_WEBHOOK_INTERVAL")
+		}
+		if v <= 0 {
+			return nil, errors.New("config.Load error: WALLETS_WEBHOOK_INTERVAL must be positive")
+		}
+		cfg.WebhookInterval = v
+	}
+
+	if d := os.Getenv("WALLETS_WEBHOOK_TIMEOUT"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_WEBHOOK_TIMEOUT")
+		}
+		cfg.WebhookTimeout = v
+	}
+
+	if attempts := os.Getenv("WALLETS_WEBHOOK_MAX_ATTEMPTS"); attempts != "" {
+		v, err := strconv.Atoi(attempts)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_WEBHOOK_MAX_ATTEMPTS")
+		}
+		cfg.WebhookMaxAttempts = v
+	}
+
+	if d := os.Getenv("WALLETS_WEBHOOK_BACKOFF"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_WEBHOOK_BACKOFF")
+		}
+		cfg.WebhookBackoff = v
+	}
+
+	if d := os.Getenv("WALLETS_STREAM_HEARTBEAT"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_STREAM_HEARTBEAT")
+		}
+		if v <= 0 {
+			return nil, errors.New("config.Load error: WALLETS_STREAM_HEARTBEAT must be positive")
+		}
+		cfg.StreamHeartbeat = v
+	}
+
+	if addr, ok := os.LookupEnv("WALLETS_GRPC_ADDR"); ok {
+		cfg.GRPCAddr = addr
+	}
+
+	if d := os.Getenv("WALLETS_IDEMPOTENCY_TTL"); d != "" {
+		v, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_IDEMPOTENCY_TTL")
+		}
+		cfg.IdempotencyTTL = v
+	}
+
+	if n := os.Getenv("WALLETS_IDEMPOTENCY_SIZE"); n != "" {
+		v, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, errors.Wrap(err, "config.Load error: bad WALLETS_IDEMPOTENCY_SIZE")
+		}
+		cfg.IdempotencySize = v
+	}
+
+	return cfg, nil
+}
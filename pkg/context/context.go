@@ -8,7 +8,11 @@ import (
 
 type contextKeyType string
 
-const contextUserKey contextKeyType = "contextUserKey"
+const (
+	contextUserKey     contextKeyType = "contextUserKey"
+	contextUserRoleKey contextKeyType = "contextUserRoleKey"
+	contextPrimaryKey  contextKeyType = "contextPrimaryKey"
+)
 
 type Manager struct{}
 
@@ -24,3 +28,26 @@ func (cu Manager) UserIDFromContext(ctx context.Context) (int, error) {
 
 	return user, nil
 }
+
+func (cu Manager) ContextWithUserRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, contextUserRoleKey, role)
+}
+
+func (cu Manager) UserRoleFromContext(ctx context.Context) (string, error) {
+	role, ok := ctx.Value(contextUserRoleKey).(string)
+	if !ok {
+		return "", errors.Errorf("can`t get user role from context")
+	}
+
+	return role, nil
+}
+
+// ContextWithPrimaryRead marks the request to read from the primary database.
+func (cu Manager) ContextWithPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextPrimaryKey, true)
+}
+
+func (cu Manager) PrimaryReadFromContext(ctx context.Context) bool {
+	primary, _ := ctx.Value(contextPrimaryKey).(bool)
+	return primary
+}
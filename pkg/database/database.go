@@ -0,0 +1,67 @@
+package database
+
+import (
+	"github.com/Davmie/javaCode/internal/migrations"
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Open connects to the SQL storage chosen in cfg and migrates it to the current
+// schema. Postgres is waited for up to cfg.DBConnectTimeout.
+func Open(cfg *config.Config, logger logger.Logger) (*gorm.DB, error) {
+	switch cfg.Storage {
+	case config.StoragePostgres:
+		sqlDB, err := openPostgres(logger, cfg, cfg.PostgresDSN, "primary")
+		if err != nil {
+			return nil, errors.Wrap(err, "database.Open error")
+		}
+
+		if err = waitForDB(logger, sqlDB, cfg.DBConnectTimeout); err != nil {
+			return nil, errors.Wrap(err, "database.Open error")
+		}
+
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+		if err != nil {
+			return nil, errors.Wrap(err, "database.Open error")
+		}
+
+		if err = migrations.Migrate(db); err != nil {
+			return nil, errors.Wrap(err, "database.Open error")
+		}
+
+		return db, nil
+	case config.StorageSQLite:
+		return OpenSQLite(cfg.SQLitePath)
+	default:
+		return nil, errors.Errorf("database.Open error: unknown storage %q", cfg.Storage)
+	}
+}
+
+// OpenSQLite opens the database file at path, ":memory:" gives a private
+// in-memory database, and migrates it to the current schema.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"), &gorm.Config{})
+	if err != nil {
+		return nil, errors.Wrap(err, "database.OpenSQLite error")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "database.OpenSQLite error")
+	}
+
+	// SQLite allows a single writer anyway. One connection serializes the
+	// conditional updates the repositories rely on for atomic balance changes,
+	// and keeps ":memory:" databases from being opened once per connection.
+	sqlDB.SetMaxOpenConns(1)
+
+	if err = migrations.Migrate(db); err != nil {
+		return nil, errors.Wrap(err, "database.OpenSQLite error")
+	}
+
+	return db, nil
+}
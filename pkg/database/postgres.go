@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+)
+
+// openPostgres opens a connection pool sized by cfg. Every connection of the
+// pool is started with the statement and lock timeouts of cfg, so they apply
+// to each query. Stats of the pool are published as go_sql_* metrics labelled
+// with name.
+func openPostgres(logger logger.Logger, cfg *config.Config, dsn string, name string) (*sql.DB, error) {
+	pgCfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "database.openPostgres error")
+	}
+
+	if cfg.DBStatementTimeout > 0 {
+		pgCfg.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+	}
+	if cfg.DBLockTimeout > 0 {
+		pgCfg.RuntimeParams["lock_timeout"] = strconv.FormatInt(cfg.DBLockTimeout.Milliseconds(), 10)
+	}
+
+	db := stdlib.OpenDB(*pgCfg)
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
+
+	if err = prometheus.Register(collectors.NewDBStatsCollector(db, name)); err != nil {
+		logger.Errorw("can`t register database stats", "db", name, "err:", err.Error())
+	}
+
+	return db, nil
+}
+
+// waitForDB pings db with exponential backoff until it answers or timeout
+// passes, so the service can start before the database does.
+func waitForDB(logger logger.Logger, db *sql.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := initialConnectBackoff
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), maxConnectBackoff)
+		err := db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			return errors.Wrap(err, "database.waitForDB error")
+		}
+
+		logger.Infow("database is not ready, retrying", "in", backoff.String(), "err:", err.Error())
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
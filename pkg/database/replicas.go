@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/config"
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const replicaPingTimeout = 2 * time.Second
+
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaPool sends SELECT queries round robin to the healthy replicas and
+// everything else, locking reads included, to the primary. Without a healthy
+// replica it fails over to the primary.
+type replicaPool struct {
+	logger   logger.Logger
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint32
+}
+
+// OpenReplicas returns a database for the read-only repositories, spread over
+// the replicas of cfg.ReplicaDSNs. The replicas are pinged every
+// cfg.ReplicaCheck until ctx is done, a replica failing the ping gets no
+// queries until it passes again.
+func OpenReplicas(ctx context.Context, logger logger.Logger, cfg *config.Config, primary *gorm.DB) (*gorm.DB, error) {
+	primaryDB, err := primary.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "database.OpenReplicas error")
+	}
+
+	pool := &replicaPool{
+		logger:  logger,
+		primary: primaryDB,
+	}
+	for i, dsn := range cfg.ReplicaDSNs {
+		db, err := openPostgres(logger, cfg, dsn, fmt.Sprintf("replica_%d", i))
+		if err != nil {
+			return nil, errors.Wrap(err, "database.OpenReplicas error")
+		}
+		pool.replicas = append(pool.replicas, &replica{db: db})
+	}
+
+	pool.check(ctx)
+	go func() {
+		ticker := time.NewTicker(cfg.ReplicaCheck)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pool.check(ctx)
+			}
+		}
+	}()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: pool}), &gorm.Config{})
+	if err != nil {
+		return nil, errors.Wrap(err, "database.OpenReplicas error")
+	}
+
+	return db, nil
+}
+
+// check pings every replica and logs the ones changing their state.
+func (p *replicaPool) check(ctx context.Context) {
+	for i, r := range p.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		if healthy {
+			p.logger.Infow("replica is up", "replica", i)
+		} else {
+			p.logger.Errorw("replica is down", "replica", i, "err:", err.Error())
+		}
+	}
+}
+
+func (p *replicaPool) pick(query string) *sql.DB {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(query, "SELECT") || strings.Contains(query, " FOR UPDATE") || strings.Contains(query, " FOR SHARE") {
+		return p.primary
+	}
+
+	start := int(p.next.Add(1))
+	for i := range p.replicas {
+		r := p.replicas[(start+i)%len(p.replicas)]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+
+	return p.primary
+}
+
+func (p *replicaPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pick(query).PrepareContext(ctx, query)
+}
+
+func (p *replicaPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.primary.ExecContext(ctx, query, args...)
+}
+
+func (p *replicaPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.pick(query).QueryContext(ctx, query, args...)
+}
+
+func (p *replicaPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.pick(query).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts transactions on the primary, statements of a transaction must
+// see its own writes.
+func (p *replicaPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.primary.BeginTx(ctx, opts)
+}
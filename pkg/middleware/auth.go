@@ -15,6 +15,7 @@ type AuthSessionsManager interface {
 
 type AuthContextManager interface {
 	ContextWithUserID(context.Context, int) context.Context
+	ContextWithUserRole(context.Context, string) context.Context
 }
 
 type AuthManager struct {
@@ -80,6 +81,22 @@ func (am *AuthManager) Auth(next http.Handler, roles ...string) http.Handler {
 			"userRole", userRole)
 
 		ctx := am.ContextManager.ContextWithUserID(r.Context(), userID)
+		ctx = am.ContextManager.ContextWithUserRole(ctx, userRole)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuth lets anonymous requests through unchanged, requests with a
+// session header are authorized the same way as in Auth.
+func (am *AuthManager) OptionalAuth(next http.Handler) http.Handler {
+	auth := am.Auth(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(sessionHeader) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		auth.ServeHTTP(w, r)
+	})
+}
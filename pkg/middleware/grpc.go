@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// sessionMetadata is the gRPC counterpart of the session header, metadata
+// keys are lower case.
+const sessionMetadata = "authorization"
+
+var (
+	grpcHandled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "gRPC calls completed by the server.",
+	}, []string{"method", "code"})
+	grpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "Time the server spent on a gRPC call.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// UnaryAccessLog is AccessLog for gRPC calls.
+func UnaryAccessLog(logger logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		logger.Infow("New call",
+			"method", info.FullMethod,
+			"remote_addr", remoteAddr(ctx),
+			"code", status.Code(err).String(),
+			"time", time.Since(start),
+		)
+
+		return resp, err
+	}
+}
+
+// UnaryPanic is Panic for gRPC calls, the caller gets codes.Internal.
+func UnaryPanic(logger logger.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorw("Server paniced",
+					"method", info.FullMethod,
+					"remote_addr", remoteAddr(ctx),
+					"error", r,
+				)
+
+				resp, err = nil, status.Error(codes.Internal, "Internal server error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// UnaryMetrics counts the calls by method and status code and observes their
+// duration.
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+
+		grpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		grpcHandled.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+
+		return resp, err
+	}
+}
+
+// UnaryAuth authorizes gRPC calls the way Auth and OptionalAuth authorize
+// requests. The methods listed in rules require a session with one of the
+// listed roles, any role when the list is empty. The rest are called
+// anonymously when there is no session.
+func (am *AuthManager) UnaryAuth(rules map[string][]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		roles, required := rules[info.FullMethod]
+
+		token := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(sessionMetadata); len(values) > 0 {
+				token = values[0]
+			}
+		}
+
+		if token == "" {
+			if !required {
+				return handler(ctx, req)
+			}
+
+			am.Logger.Infow("authorization",
+				"method", info.FullMethod,
+				"remote_addr", remoteAddr(ctx),
+				"auth result", "session metadata not found")
+
+			return nil, status.Error(codes.Unauthenticated, "no auth")
+		}
+
+		userID, userRole, err := am.SessionManager.GetUser(token)
+		if err != nil {
+			am.Logger.Infow("authorization",
+				"method", info.FullMethod,
+				"remote_addr", remoteAddr(ctx),
+				"auth result", "user not found",
+				"GetUser error", err)
+
+			return nil, status.Error(codes.Unauthenticated, "no auth")
+		}
+
+		if len(roles) > 0 {
+			roleMatch := false
+			for _, role := range roles {
+				if userRole == role {
+					roleMatch = true
+					break
+				}
+			}
+			if !roleMatch {
+				am.Logger.Infow("authorization",
+					"method", info.FullMethod,
+					"remote_addr", remoteAddr(ctx),
+					"auth result", "user role doesn`t match",
+					"userID", userID,
+					"userRole", userRole)
+
+				return nil, status.Error(codes.PermissionDenied, "Forbidden")
+			}
+		}
+
+		am.Logger.Infow("authorization",
+			"method", info.FullMethod,
+			"remote_addr", remoteAddr(ctx),
+			"auth result", "success",
+			"userID", userID,
+			"userRole", userRole)
+
+		ctx = am.ContextManager.ContextWithUserID(ctx, userID)
+		ctx = am.ContextManager.ContextWithUserRole(ctx, userRole)
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ctxManager "github.com/Davmie/javaCode/pkg/context"
+	"github.com/Davmie/javaCode/pkg/session"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	checkMethod = "/grpc.health.v1.Health/Check"
+	testUserID  = 7
+)
+
+// caller is who a call reached the service as.
+type caller struct {
+	userID int
+	role   string
+	known  bool
+}
+
+// GRPCAuthTestSuite calls the health service over an in-memory connection
+// through UnaryAuth. The rules of a test are set before its first call.
+type GRPCAuthTestSuite struct {
+	suite.Suite
+	rules  map[string][]string
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client healthpb.HealthClient
+	caller caller
+}
+
+func TestGRPCAuthSuite(t *testing.T) {
+	suite.RunSuite(t, new(GRPCAuthTestSuite))
+}
+
+func (s *GRPCAuthTestSuite) BeforeEach(t provider.T) {
+	s.rules = nil
+	s.caller = caller{}
+}
+
+func (s *GRPCAuthTestSuite) AfterEach(t provider.T) {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.server.Stop()
+		s.conn = nil
+	}
+}
+
+// start serves the health service with the rules of the test.
+func (s *GRPCAuthTestSuite) start(t provider.T) {
+	contextManager := ctxManager.Manager{}
+	authManager := AuthManager{
+		SessionManager: session.JWTSessionsManager{},
+		Logger:         zap.NewNop().Sugar(),
+		ContextManager: contextManager,
+	}
+
+	// Records the user UnaryAuth put in the context.
+	record := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		userID, err := contextManager.UserIDFromContext(ctx)
+		role, _ := contextManager.UserRoleFromContext(ctx)
+		s.caller = caller{userID: userID, role: role, known: err == nil}
+
+		return handler(ctx, req)
+	}
+
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(authManager.UnaryAuth(s.rules), record))
+	healthpb.RegisterHealthServer(s.server, health.NewServer())
+
+	lis := bufconn.Listen(1 << 20)
+	go func() {
+		_ = s.server.Serve(lis)
+	}()
+
+	var err error
+	s.conn, err = grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	t.Require().NoError(err)
+	s.client = healthpb.NewHealthClient(s.conn)
+}
+
+func (s *GRPCAuthTestSuite) check(ctx context.Context) error {
+	_, err := s.client.Check(ctx, &healthpb.HealthCheckRequest{})
+	return err
+}
+
+func (s *GRPCAuthTestSuite) as(t provider.T, userID int, role string) context.Context {
+	token, err := session.JWTSessionsManager{}.CreateSession(userID, role)
+	t.Require().NoError(err)
+
+	return metadata.AppendToOutgoingContext(context.Background(), sessionMetadata, token)
+}
+
+func (s *GRPCAuthTestSuite) TestAnonymousCall(t provider.T) {
+	s.start(t)
+
+	t.Require().NoError(s.check(context.Background()))
+	t.Assert().False(s.caller.known)
+}
+
+func (s *GRPCAuthTestSuite) TestSessionOfOptionalMethod(t provider.T) {
+	s.start(t)
+
+	t.Require().NoError(s.check(s.as(t, testUserID, "user")))
+	t.Assert().Equal(caller{userID: testUserID, role: "user", known: true}, s.caller)
+}
+
+func (s *GRPCAuthTestSuite) TestBadSession(t provider.T) {
+	s.start(t)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), sessionMetadata, "token")
+
+	t.Assert().Equal(codes.Unauthenticated, status.Code(s.check(ctx)))
+	t.Assert().False(s.caller.known)
+}
+
+func (s *GRPCAuthTestSuite) TestRequiredSession(t provider.T) {
+	s.rules = map[string][]string{checkMethod: nil}
+	s.start(t)
+
+	t.Assert().Equal(codes.Unauthenticated, status.Code(s.check(context.Background())))
+	t.Assert().False(s.caller.known)
+
+	t.Require().NoError(s.check(s.as(t, testUserID, "user")))
+	t.Assert().Equal(testUserID, s.caller.userID)
+}
+
+func (s *GRPCAuthTestSuite) TestRequiredRole(t provider.T) {
+	s.rules = map[string][]string{checkMethod: {session.AdminRole}}
+	s.start(t)
+
+	t.Assert().Equal(codes.PermissionDenied, status.Code(s.check(s.as(t, testUserID, "user"))))
+	t.Assert().False(s.caller.known)
+
+	t.Require().NoError(s.check(s.as(t, 1, session.AdminRole)))
+	t.Assert().Equal(caller{userID: 1, role: session.AdminRole, known: true}, s.caller)
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/Davmie/javaCode/pkg/cache"
+	"github.com/Davmie/javaCode/pkg/logger"
+)
+
+const (
+	idempotencyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a repeated key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKey        = 255
+)
+
+// idempotentResponse is a response kept for the retries of its request. Its
+// Status is 0 while the request runs.
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// recordingWriter copies the response on its way to the client.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Idempotency replays the response to a changing request sent again with the
+// same Idempotency-Key, so clients may retry it after losing the response.
+// Keys are scoped by the session header. The key is reserved in the store
+// before the request runs, so with a shared store a retry reaching another
+// instance doesn't run it again either. A key reused for another request is
+// answered with 422, a key whose request is still running with 409 and
+// Retry-After. Server errors are not kept, their requests run again on retry.
+// A key stays reserved until the store expires it if the instance running its
+// request stops before it completes.
+func Idempotency(logger logger.Logger, store cache.Cache, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if len(key) > maxIdempotencyKey {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad data", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := sha256.New()
+		fingerprint.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+		fingerprint.Write(body)
+		requestFingerprint := hex.EncodeToString(fingerprint.Sum(nil))
+
+		scope := sha256.Sum256([]byte(r.Header.Get(sessionHeader)))
+		storeKey := hex.EncodeToString(scope[:]) + ":" + key
+
+		pending, err := json.Marshal(idempotentResponse{Fingerprint: requestFingerprint})
+		if err != nil {
+			logger.Errorw("can`t marshal idempotent response",
+				"err:", err.Error())
+			http.Error(w, "unknown error", http.StatusInternalServerError)
+			return
+		}
+
+		reserved, err := store.Add(storeKey, pending)
+		if err != nil {
+			// Running the request without knowing whether it already ran could
+			// apply it twice.
+			logger.Errorw("can`t reserve idempotency key",
+				"err:", err.Error())
+			http.Error(w, "idempotency store is unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		if !reserved {
+			replay(logger, store, storeKey, requestFingerprint, w)
+			return
+		}
+
+		kept := false
+		defer func() {
+			// Releases the key of a request that failed, panicked included, so
+			// its retry runs it again.
+			if kept {
+				return
+			}
+			if err := store.Delete(storeKey); err != nil {
+				logger.Errorw("can`t release idempotency key",
+					"err:", err.Error())
+			}
+		}()
+
+		rw := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r)
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+
+		header := w.Header().Clone()
+		// Cookies belong to the response of the first attempt only.
+		header.Del("Set-Cookie")
+
+		resp, err := json.Marshal(idempotentResponse{
+			Fingerprint: requestFingerprint,
+			Status:      rw.status,
+			Header:      header,
+			Body:        rw.body.Bytes(),
+		})
+		if err == nil {
+			err = store.Set(storeKey, resp)
+		}
+		if err != nil {
+			logger.Errorw("can`t keep idempotent response",
+				"err:", err.Error())
+			return
+		}
+		kept = true
+	})
+}
+
+// replay answers a request whose key is reserved already with the response
+// kept for it.
+func replay(logger logger.Logger, store cache.Cache, storeKey string, requestFingerprint string, w http.ResponseWriter) {
+	stored, ok, err := store.Get(storeKey)
+	if err != nil {
+		logger.Errorw("can`t read idempotency store",
+			"err:", err.Error())
+		http.Error(w, "idempotency store is unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var resp idempotentResponse
+	if ok {
+		if err = json.Unmarshal(stored, &resp); err != nil {
+			logger.Errorw("can`t unmarshal idempotent response",
+				"err:", err.Error())
+			http.Error(w, "unknown error", http.StatusInternalServerError)
+			return
+		}
+
+		if resp.Fingerprint != requestFingerprint {
+			http.Error(w, "Idempotency-Key is reused with another request", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// A key released after it was found reserved is answered the same way,
+	// the retry runs the request again.
+	if !ok || resp.Status == 0 {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Davmie/javaCode/pkg/cache"
+	"github.com/ozontech/allure-go/pkg/framework/provider"
+	"github.com/ozontech/allure-go/pkg/framework/suite"
+	"go.uber.org/zap"
+)
+
+const idempotencyStoreSize = 100
+
+// IdempotencyTestSuite runs two instances of the service sharing the store of
+// the responses, like instances sharing Redis.
+type IdempotencyTestSuite struct {
+	suite.Suite
+	first  http.Handler
+	second http.Handler
+	// handle answers the requests reaching the service.
+	handle func(w http.ResponseWriter, r *http.Request)
+	calls  atomic.Int32
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.RunSuite(t, new(IdempotencyTestSuite))
+}
+
+func (s *IdempotencyTestSuite) BeforeEach(t provider.T) {
+	logger := zap.NewNop().Sugar()
+	store := cache.NewLRU(idempotencyStoreSize, time.Hour)
+
+	s.calls.Store(0)
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}
+	service := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		s.handle(w, r)
+	})
+
+	s.first = Panic(logger, Idempotency(logger, store, service))
+	s.second = Panic(logger, Idempotency(logger, store, service))
+}
+
+func (s *IdempotencyTestSuite) send(handler http.Handler, key string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", strings.NewReader(body))
+	req.Header.Set(idempotencyHeader, key)
+	req.Header.Set(sessionHeader, "session")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func (s *IdempotencyTestSuite) TestReplay(t provider.T) {
+	first := s.send(s.first, "key", "{}")
+	t.Require().Equal(http.StatusCreated, first.Code)
+	t.Assert().Empty(first.Header().Get(IdempotentReplayedHeader))
+
+	replayed := s.send(s.second, "key", "{}")
+
+	t.Assert().Equal(http.StatusCreated, replayed.Code)
+	t.Assert().Equal("created", replayed.Body.String())
+	t.Assert().Equal("true", replayed.Header().Get(IdempotentReplayedHeader))
+	t.Assert().Equal(int32(1), s.calls.Load())
+}
+
+func (s *IdempotencyTestSuite) TestRetryWhileRunning(t provider.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- s.send(s.first, "key", "{}")
+	}()
+	<-started
+
+	// The retry reaches the other instance while the request runs.
+	running := s.send(s.second, "key", "{}")
+	t.Assert().Equal(http.StatusConflict, running.Code)
+	t.Assert().Equal("1", running.Header().Get("Retry-After"))
+
+	close(release)
+	t.Require().Equal(http.StatusCreated, (<-done).Code)
+
+	replayed := s.send(s.second, "key", "{}")
+	t.Assert().Equal(http.StatusCreated, replayed.Code)
+	t.Assert().Equal("true", replayed.Header().Get(IdempotentReplayedHeader))
+	t.Assert().Equal(int32(1), s.calls.Load())
+}
+
+func (s *IdempotencyTestSuite) TestRetryWhileRunningAfterStoreFilledUp(t provider.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(idempotencyHeader) == "key" {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- s.send(s.first, "key", "{}")
+	}()
+	<-started
+
+	// More responses are kept than the store has room for while the request runs.
+	for i := 0; i < 2*idempotencyStoreSize; i++ {
+		t.Require().Equal(http.StatusCreated, s.send(s.second, strconv.Itoa(i), "{}").Code)
+	}
+
+	running := s.send(s.second, "key", "{}")
+	t.Assert().Equal(http.StatusConflict, running.Code)
+
+	close(release)
+	t.Require().Equal(http.StatusCreated, (<-done).Code)
+
+	replayed := s.send(s.second, "key", "{}")
+	t.Assert().Equal("true", replayed.Header().Get(IdempotentReplayedHeader))
+	t.Assert().Equal(int32(2*idempotencyStoreSize+1), s.calls.Load())
+}
+
+func (s *IdempotencyTestSuite) TestKeyReusedWithAnotherRequest(t provider.T) {
+	t.Require().Equal(http.StatusCreated, s.send(s.first, "key", `{"amount":1}`).Code)
+
+	reused := s.send(s.second, "key", `{"amount":2}`)
+
+	t.Assert().Equal(http.StatusUnprocessableEntity, reused.Code)
+	t.Assert().Equal(int32(1), s.calls.Load())
+}
+
+func (s *IdempotencyTestSuite) TestServerErrorIsNotKept(t provider.T) {
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unknown error", http.StatusInternalServerError)
+	}
+	t.Require().Equal(http.StatusInternalServerError, s.send(s.first, "key", "{}").Code)
+
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		panic("broken")
+	}
+	t.Require().Equal(http.StatusInternalServerError, s.send(s.first, "key", "{}").Code)
+
+	s.handle = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}
+	retried := s.send(s.second, "key", "{}")
+
+	t.Assert().Equal(http.StatusCreated, retried.Code)
+	t.Assert().Empty(retried.Header().Get(IdempotentReplayedHeader))
+	t.Assert().Equal(int32(3), s.calls.Load())
+}
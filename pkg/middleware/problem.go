@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details document.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// problemWriter holds back plain text error responses so they can be written
+// as problem details.
+type problemWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (pw *problemWriter) WriteHeader(status int) {
+	if pw.status != 0 {
+		return
+	}
+
+	if status >= http.StatusBadRequest && strings.HasPrefix(pw.Header().Get("Content-Type"), "text/plain") {
+		pw.status = status
+		return
+	}
+
+	pw.status = -1
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+func (pw *problemWriter) Write(b []byte) (int, error) {
+	if pw.status == 0 {
+		pw.WriteHeader(http.StatusOK)
+	}
+
+	if pw.status > 0 {
+		return pw.body.Write(b)
+	}
+
+	return pw.ResponseWriter.Write(b)
+}
+
+func (pw *problemWriter) Flush() {
+	if f, ok := pw.ResponseWriter.(http.Flusher); ok && pw.status <= 0 {
+		f.Flush()
+	}
+}
+
+func (pw *problemWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// Problems answers the clients accepting application/problem+json with problem
+// details instead of the plain text error messages of the handlers. The message
+// becomes the detail. Other clients get the responses unchanged.
+func Problems(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), problemContentType) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		pw := &problemWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+
+		if pw.status <= 0 {
+			return
+		}
+
+		resp, err := json.Marshal(Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(pw.status),
+			Status:   pw.status,
+			Detail:   strings.TrimSpace(pw.body.String()),
+			Instance: r.URL.Path,
+		})
+		if err != nil {
+			http.Error(w, pw.body.String(), pw.status)
+			return
+		}
+
+		w.Header().Set("Content-Type", problemContentType)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(pw.status)
+		_, _ = w.Write(resp)
+	})
+}
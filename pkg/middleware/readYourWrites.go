@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const readPrimaryCookie = "wallets_read_primary"
+
+type ReadYourWritesContextManager interface {
+	ContextWithPrimaryRead(context.Context) context.Context
+}
+
+// ReadYourWrites sends the reads of a client to the primary database for the
+// window after its last write. The write is remembered in a cookie holding the
+// time the window ends.
+func ReadYourWrites(cm ReadYourWritesContextManager, window time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if cookie, err := r.Cookie(readPrimaryCookie); err == nil {
+				until, err := strconv.ParseInt(cookie.Value, 10, 64)
+				if err == nil && time.Now().Unix() < until {
+					r = r.WithContext(cm.ContextWithPrimaryRead(r.Context()))
+				}
+			}
+		default:
+			http.SetCookie(w, &http.Cookie{
+				Name:     readPrimaryCookie,
+				Value:    strconv.FormatInt(time.Now().Add(window).Unix(), 10),
+				Path:     "/",
+				MaxAge:   int(window.Seconds()),
+				HttpOnly: true,
+			})
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -9,6 +9,8 @@ import (
 
 var tokenKey = []byte("fvoNImvpdms023sv0s9vs")
 
+const AdminRole = "admin"
+
 type UserClaims struct {
 	ID   int    `json:"id"`
 	Role string `json:"role"`
@@ -26,10 +28,14 @@ func (jsm JWTSessionsManager) GetUser(inToken string) (int, string, error) {
 		return tokenKey, nil
 	})
 
+	if err != nil {
+		return -1, "", errors.Wrapf(err, "can`t parse or validate session token \"%s\"", inToken)
+	}
+
 	claims, ok := token.Claims.(*Claims)
 
 	if !ok || !token.Valid {
-		return -1, "", errors.Wrapf(err, "can`t parse or validate session token \"%s\"", inToken)
+		return -1, "", errors.Errorf("can`t parse or validate session token \"%s\"", inToken)
 	}
 
 	return claims.User.ID, claims.User.Role, nil
@@ -0,0 +1,5 @@
+// Package walletpb holds the gRPC client and server code generated from
+// api/proto/wallet/v1/wallet.proto.
+package walletpb
+
+//go:generate protoc -I ../../api/proto --go_out=../.. --go_opt=module=github.com/Davmie/javaCode --go-grpc_out=../.. --go-grpc_opt=module=github.com/Davmie/javaCode wallet/v1/wallet.proto
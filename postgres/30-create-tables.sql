@@ -1,8 +1,4 @@
 \connect wallets program
 
-CREATE TABLE IF NOT EXISTS wallet
-(
-    id            SERIAL PRIMARY KEY,
-    uid    uuid UNIQUE NOT NULL,
-    amount         INT         NOT NULL
-);
\ No newline at end of file
+-- The tables are created and updated by the service on start, from the models
+-- in models/ and internal/migrations. Nothing has to be added here.